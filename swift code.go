@@ -3,18 +3,134 @@
 swift-code-service/
 ├── src/
 │   ├── controllers/
+│   │   ├── adminController.js
+│   │   ├── apiKeyController.js
+│   │   ├── bankController.js
+│   │   ├── editLockController.js
+│   │   ├── eventController.js
+│   │   ├── ibanController.js
+│   │   ├── metaController.js
+│   │   ├── statsController.js
 │   │   └── swiftCodeController.js
 │   ├── models/
-│   │   └── swiftCode.js
+│   │   ├── apiKey.js
+│   │   ├── auditLog.js
+│   │   ├── bank.js
+│   │   ├── bicAssignment.js
+│   │   ├── country.js
+│   │   ├── editLock.js
+│   │   ├── importReject.js
+│   │   ├── importRun.js
+│   │   ├── lease.js
+│   │   ├── mergerEvent.js
+│   │   ├── swiftCode.js
+│   │   ├── swiftCodeLookup.js
+│   │   └── swiftCodeRevision.js
 │   ├── routes/
-│   │   └── swiftCodeRoutes.js
+│   │   ├── adminRoutes.js
+│   │   ├── apiKeyRoutes.js
+│   │   ├── bankRoutes.js
+│   │   ├── eventRoutes.js
+│   │   ├── ibanRoutes.js
+│   │   ├── metaRoutes.js
+│   │   ├── statsRoutes.js
+│   │   ├── swiftCodeRoutes.js
+│   │   └── swiftCodeRoutesV2.js
 │   ├── services/
+│   │   ├── apiKeyService.js
+│   │   ├── assignmentService.js
+│   │   ├── auditService.js
+│   │   ├── bankService.js
+│   │   ├── countryService.js
+│   │   ├── dashboardService.js
+│   │   ├── editLockService.js
+│   │   ├── exportService.js
+│   │   ├── growthMonitor.js
+│   │   ├── historyService.js
+│   │   ├── ibanService.js
+│   │   ├── importRejectService.js
+│   │   ├── importRunService.js
+│   │   ├── integrityCheck.js
+│   │   ├── leaseService.js
+│   │   ├── lookupService.js
+│   │   ├── mergerEventService.js
+│   │   ├── metricsService.js
+│   │   ├── objectStorage.js
+│   │   ├── reportService.js
+│   │   ├── siemService.js
+│   │   ├── statsService.js
 │   │   └── swiftCodeService.js
 │   ├── utils/
-│   │   └── dataParser.js
+│   │   ├── actor.js
+│   │   ├── addressNormalizer.js
+│   │   ├── backfillBicFields.js
+│   │   ├── bankNames.js
+│   │   ├── bic.js
+│   │   ├── branchCache.js
+│   │   ├── branchDescriptionParser.js
+│   │   ├── clientIp.js
+│   │   ├── countries.js
+│   │   ├── dataParser.js
+│   │   ├── dataQuality.js
+│   │   ├── fields.js
+│   │   ├── geo.js
+│   │   ├── iban.js
+│   │   ├── importDedupe.js
+│   │   ├── importSource.js
+│   │   ├── inputNormalizer.js
+│   │   ├── lei.js
+│   │   ├── logLevel.js
+│   │   ├── nationalIds.js
+│   │   ├── pagination.js
+│   │   ├── regex.js
+│   │   ├── replayMutations.js
+│   │   ├── stageTimer.js
+│   │   ├── swiftCodeValidator.js
+│   │   └── workbookReader.js
 │   ├── config/
-│   │   └── database.js
+│   │   ├── app.js
+│   │   ├── database.js
+│   │   └── reload.js
+│   ├── middleware/
+│   │   ├── auditLogger.js
+│   │   ├── authenticate.js
+│   │   ├── editLockGuard.js
+│   │   ├── fileUpload.js
+│   │   ├── httpMetrics.js
+│   │   ├── ipAllowlist.js
+│   │   ├── mutationRecorder.js
+│   │   ├── normalizeInput.js
+│   │   ├── rateLimiter.js
+│   │   ├── recordCeiling.js
+│   │   ├── redaction.js
+│   │   ├── requireRole.js
+│   │   ├── resolveBic8.js
+│   │   ├── responseValidator.js
+│   │   ├── stageTimings.js
+│   │   └── validateRequest.js
+│   ├── auth/
+│   │   ├── apiKeyProvider.js
+│   │   ├── index.js
+│   │   ├── jwtProvider.js
+│   │   └── mtlsProvider.js
+│   ├── docs/
+│   │   └── openapi.js
+│   ├── serializers/
+│   │   └── swiftCodeSerializer.js
+│   ├── errors/
+│   │   └── errorCodes.js
+│   ├── validation/
+│   │   └── requestSchemas.js
+│   ├── jobs/
+│   │   ├── normalizeRecords.js
+│   │   ├── scheduledImport.js
+│   │   ├── smokeTest.js
+│   │   ├── snapshotPublisher.js
+│   │   └── temporaryCodeExpiry.js
 │   └── app.js
+├── tests/
+│   └── integration/
+│       └── swiftCodes.test.js
 ├── package.json
 └── server.js
 */
@@ -28,34 +144,101 @@ swift-code-service/
   "scripts": {
     "start": "node server.js",
     "dev": "nodemon server.js",
-    "parse": "node src/utils/dataParser.js"
+    "parse": "node src/utils/dataParser.js data/swift_codes.csv",
+    "parse:descriptions": "node src/utils/branchDescriptionParser.js",
+    "replay": "node src/utils/replayMutations.js",
+    "backfill:bic": "node src/utils/backfillBicFields.js",
+    "smoke": "node src/jobs/smokeTest.js",
+    "test": "jest --runInBand"
   },
   "dependencies": {
+    "ajv": "^8.12.0",
+    "archiver": "^6.0.1",
     "cors": "^2.8.5",
     "csv-parser": "^3.0.0",
     "dotenv": "^16.0.3",
+    "exceljs": "^4.4.0",
     "express": "^4.18.2",
-    "mongoose": "^7.1.0"
+    "joi": "^17.11.0",
+    "jsonwebtoken": "^9.0.2",
+    "jwks-rsa": "^3.1.0",
+    "mongoose": "^7.1.0",
+    "multer": "^1.4.5-lts.1",
+    "node-cron": "^3.0.3",
+    "prom-client": "^15.1.0"
+  },
+  "optionalDependencies": {
+    "@aws-sdk/client-s3": "^3.470.0",
+    "@aws-sdk/lib-storage": "^3.470.0",
+    "@google-cloud/storage": "^7.7.0"
   },
   "devDependencies": {
-    "nodemon": "^2.0.22"
+    "adm-zip": "^0.5.10",
+    "jest": "^29.7.0",
+    "mongodb-memory-server": "^9.1.1",
+    "nodemon": "^2.0.22",
+    "supertest": "^6.3.3"
+  },
+  "jest": {
+    "testEnvironment": "node"
   }
 }
 
 // server.js
+const fs = require('fs');
+const https = require('https');
 const app = require('./src/app');
 const mongoose = require('mongoose');
 const config = require('./src/config/database');
+const appConfig = require('./src/config/app');
+const growthMonitor = require('./src/services/growthMonitor');
+const temporaryCodeExpiry = require('./src/jobs/temporaryCodeExpiry');
+const snapshotPublisher = require('./src/jobs/snapshotPublisher');
+const scheduledImport = require('./src/jobs/scheduledImport');
+const integrityCheck = require('./src/services/integrityCheck');
+const countryService = require('./src/services/countryService');
+const configReload = require('./src/config/reload');
+const { applyLogLevel } = require('./src/utils/logLevel');
 
 const PORT = process.env.PORT || 3000;
 
+// The config file, when one is set, only ever changes the reloadable settings, at startup as on SIGHUP
+configReload.reload();
+applyLogLevel(appConfig.logLevel);
+configReload.watchSignals();
+
+// Serve HTTPS when a certificate is configured; client certificates are requested but verified by the mtls
+// auth provider, so untrusted ones get a JSON 401 instead of a failed handshake
+const listen = (callback) => {
+  if (!appConfig.tlsCertFile) {
+    return app.listen(PORT, callback);
+  }
+  
+  return https.createServer({
+    cert: fs.readFileSync(appConfig.tlsCertFile),
+    key: fs.readFileSync(appConfig.tlsKeyFile),
+    ca: appConfig.tlsCaFile ? fs.readFileSync(appConfig.tlsCaFile) : undefined,
+    requestCert: Boolean(appConfig.tlsCaFile),
+    rejectUnauthorized: false
+  }, app).listen(PORT, callback);
+};
+
 // Connect to MongoDB
 mongoose.connect(config.mongoURI)
   .then(() => {
     console.log('Connected to MongoDB');
-    app.listen(PORT, () => {
+    countryService.start();
+    growthMonitor.start();
+    temporaryCodeExpiry.start();
+    snapshotPublisher.start();
+    scheduledImport.start();
+    if (appConfig.integrityCheck) {
+      integrityCheck.run();
+    }
+    const server = listen(() => {
       console.log(`Server running on port ${PORT}`);
     });
+    server.requestTimeout = appConfig.requestTimeoutMs;
   })
   .catch(err => {
     console.error('Failed to connect to MongoDB', err);
@@ -65,16 +248,78 @@ mongoose.connect(config.mongoURI)
 // src/app.js
 const express = require('express');
 const cors = require('cors');
+const mongoose = require('mongoose');
 const swiftCodeRoutes = require('./routes/swiftCodeRoutes');
+const swiftCodeRoutesV2 = require('./routes/swiftCodeRoutesV2');
+const statsRoutes = require('./routes/statsRoutes');
+const bankRoutes = require('./routes/bankRoutes');
+const adminRoutes = require('./routes/adminRoutes');
+const metaRoutes = require('./routes/metaRoutes');
+const apiKeyRoutes = require('./routes/apiKeyRoutes');
+const ibanRoutes = require('./routes/ibanRoutes');
+const eventRoutes = require('./routes/eventRoutes');
+const appConfig = require('./config/app');
+const authenticate = require('./middleware/authenticate');
+const httpMetrics = require('./middleware/httpMetrics');
+const stageTimings = require('./middleware/stageTimings');
+const rateLimiter = require('./middleware/rateLimiter');
+const metricsService = require('./services/metricsService');
+const integrityCheck = require('./services/integrityCheck');
+const redaction = require('./middleware/redaction');
+const responseValidator = require('./middleware/responseValidator');
+const mutationRecorder = require('./middleware/mutationRecorder');
+const auditLogger = require('./middleware/auditLogger');
+const normalizeInput = require('./middleware/normalizeInput');
 
 const app = express();
 
+// Derive req.ip from X-Forwarded-For only when it was set by a trusted proxy
+app.set('trust proxy', appConfig.trustProxy);
+
 // Middleware
 app.use(cors());
-app.use(express.json());
+app.use(httpMetrics);
+app.use(rateLimiter);
+app.use(express.json({ limit: appConfig.jsonBodyLimit }));
+// After body parsing, whose stream callbacks would run outside the request's timing context
+app.use(stageTimings);
+app.use(normalizeInput);
+app.use(authenticate);
+app.use(redaction);
+app.use(responseValidator);
+app.use(mutationRecorder);
+app.use(auditLogger);
+
+// Prometheus scrape endpoint
+app.get('/metrics', async (req, res, next) => {
+  try {
+    res.type(metricsService.register.contentType);
+    res.send(await metricsService.register.metrics());
+  } catch (error) {
+    next(error);
+  }
+});
+
+// Readiness probe: connected to MongoDB and no critical integrity violation
+app.get('/ready', (req, res) => {
+  const integrity = integrityCheck.getState();
+  
+  if (mongoose.connection.readyState !== 1 || !integrityCheck.isReady()) {
+    return res.status(503).json({ status: 'not ready', integrity });
+  }
+  res.status(200).json({ status: 'ready', integrity });
+});
 
 // Routes
 app.use('/v1/swift-codes', swiftCodeRoutes);
+app.use('/v1/stats', statsRoutes);
+app.use('/v1/banks', bankRoutes);
+app.use('/v2/swift-codes', swiftCodeRoutesV2);
+app.use('/v1/admin', adminRoutes);
+app.use('/v1/meta', metaRoutes);
+app.use('/v1/keys', apiKeyRoutes);
+app.use('/v1/iban', ibanRoutes);
+app.use('/v1/events', eventRoutes);
 
 // Error handling middleware
 app.use((err, req, res, next) => {
@@ -84,298 +329,14413 @@ app.use((err, req, res, next) => {
 
 module.exports = app;
 
-// src/config/database.js
-module.exports = {
-  mongoURI: process.env.MONGODB_URI || 'mongodb://localhost:27017/swift-codes'
+// src/config/app.js
+// Parse a JSON environment variable, falling back when it is missing or malformed
+const parseJson = (value, fallback) => {
+  if (!value) {
+    return fallback;
+  }
+  try {
+    return JSON.parse(value);
+  } catch (error) {
+    console.error('Ignoring malformed JSON configuration value', error.message);
+    return fallback;
+  }
 };
 
-// src/models/swiftCode.js
-const mongoose = require('mongoose');
-
-const swiftCodeSchema = new mongoose.Schema({
-  swiftCode: {
-    type: String,
-    required: true,
-    unique: true,
-    trim: true,
-    uppercase: true
-  },
-  bankName: {
-    type: String,
-    required: true,
-    trim: true
-  },
-  address: {
-    type: String,
-    required: true,
-    trim: true
-  },
-  countryISO2: {
-    type: String,
-    required: true,
-    trim: true,
-    uppercase: true
-  },
-  countryName: {
-    type: String, 
-    required: true,
-    trim: true,
-    uppercase: true
-  },
-  isHeadquarter: {
-    type: Boolean,
-    required: true
+// TRUST_PROXY: 'true', a hop count, or a comma-separated list of trusted proxy addresses/subnets
+const parseTrustProxy = (value) => {
+  if (!value || value === 'false') {
+    return false;
   }
-});
+  if (value === 'true') {
+    return true;
+  }
+  return /^\d+$/.test(value) ? parseInt(value, 10) : value;
+};
 
-// Index for faster querying
-swiftCodeSchema.index({ swiftCode: 1 });
-swiftCodeSchema.index({ countryISO2: 1 });
-swiftCodeSchema.index({ swiftCode: 1, isHeadquarter: 1 });
+const parseList = (value) => (value || '').split(',').map(item => item.trim()).filter(Boolean);
 
-const SwiftCode = mongoose.model('SwiftCode', swiftCodeSchema);
+// PAGE_SIZE_<FAMILY>_DEFAULT / PAGE_SIZE_<FAMILY>_MAX, or PAGE_SIZE_DEFAULT / PAGE_SIZE_MAX for the rest
+const parsePageSize = (value, fallback) => {
+  const size = parseInt(value, 10);
+  return Number.isNaN(size) || size < 0 ? fallback : size;
+};
 
-module.exports = SwiftCode;
+// 0 is a valid setting (no default page, no maximum), and a default beyond the maximum, 0 included, is
+// brought down to it
+const parsePageSizes = (env, family, defaultLimit, maxLimit) => {
+  const prefix = family ? `PAGE_SIZE_${family}_` : 'PAGE_SIZE_';
+  const max = parsePageSize(env[`${prefix}MAX`], maxLimit);
+  const fallback = parsePageSize(env[`${prefix}DEFAULT`], defaultLimit);
+  
+  return {
+    defaultLimit: max && (fallback === 0 || fallback > max) ? max : fallback,
+    maxLimit: max
+  };
+};
 
-// src/routes/swiftCodeRoutes.js
-const express = require('express');
-const swiftCodeController = require('../controllers/swiftCodeController');
+// Settings from an environment; built from process.env at startup and again on every reload
+const buildConfig = (env) => ({
+  // Behind the load balancer, client IPs come from X-Forwarded-For set by trusted proxies
+  trustProxy: parseTrustProxy(env.TRUST_PROXY),
+  // Requests per client IP per minute (0 disables rate limiting)
+  rateLimitPerMinute: parseInt(env.RATE_LIMIT_PER_MINUTE, 10) || 0,
+  // Client IPs allowed on /v1/admin; empty allows any
+  adminIpAllowlist: parseList(env.ADMIN_IP_ALLOWLIST),
+  // Request body size limit, raised for bulk endpoints
+  jsonBodyLimit: env.JSON_BODY_LIMIT || '1mb',
+  // Upper bound on receiving a whole request (0 disables it, Node's own default is 5 minutes); off by
+  // default so the streaming validation channel stays open, slow headers are still cut by headersTimeout
+  requestTimeoutMs: parseInt(env.REQUEST_TIMEOUT_MS, 10) || 0,
+  // Page size used without ?limit= and the largest one accepted, per endpoint family
+  pageSizes: {
+    default: parsePageSizes(env, '', 50, 500),
+    country: parsePageSizes(env, 'COUNTRY', 50, 500),
+    search: parsePageSizes(env, 'SEARCH', 50, 500),
+    branches: parsePageSizes(env, 'BRANCHES', 100, 500),
+    // 0 keeps exports whole unless the client pages them
+    export: parsePageSizes(env, 'EXPORT', 0, 0),
+    dashboard: parsePageSizes(env, 'DASHBOARD', 10, 100)
+  },
+  // Maximum number of records accepted by a single bulk request
+  bulkMaxItems: parseInt(env.BULK_MAX_ITEMS, 10) || 1000,
+  // Screening streams (POST /v1/swift-codes/validate/stream): lines accepted per request, and how long
+  // the client may go without sending one
+  validationStreamMaxLines: parseInt(env.VALIDATION_STREAM_MAX_LINES, 10) || 100000,
+  validationStreamIdleTimeoutMs: parseInt(env.VALIDATION_STREAM_IDLE_TIMEOUT_MS, 10) || 60 * 1000,
+  // Behaviour for unknown BIC11 codes: 'strict' (404) or 'headquarter' (fall back to BIC8 + XXX)
+  unknownCodeFallback: env.UNKNOWN_CODE_FALLBACK || 'strict',
+  // Response schema validation: 'off', 'log' or 'fail' - never enabled in production
+  responseValidation: env.NODE_ENV === 'production'
+    ? 'off'
+    : env.RESPONSE_VALIDATION || 'log',
+  // Record sanitized mutating requests so they can be replayed against staging
+  mutationCapture: env.MUTATION_CAPTURE === 'true',
+  mutationCaptureFile: env.MUTATION_CAPTURE_FILE || 'mutations.ndjson',
+  // Collection growth monitoring: sampling cadence and how many standard deviations count as abnormal
+  growthSampleIntervalMs: parseInt(env.GROWTH_SAMPLE_INTERVAL_MS, 10) || 5 * 60 * 1000,
+  growthAlertThreshold: parseFloat(env.GROWTH_ALERT_THRESHOLD) || 3,
+  // Block API writes once the collection holds this many records (0 disables the ceiling)
+  recordCeiling: parseInt(env.RECORD_CEILING, 10) || 0,
+  // API keys as JSON: { "<key>": { "name": "partner-x", "role": "consumer", "redactionProfile": "external", "strictness": "strict" } }
+  apiKeys: parseJson(env.API_KEYS, {}),
+  // Auth providers tried in order, see src/auth: 'api-key', 'jwt', 'mtls'
+  authProviders: (env.AUTH_PROVIDERS || 'api-key').split(',').map(name => name.trim()).filter(Boolean),
+  // Refuse anonymous requests (REQUIRE_API_KEY is the older name)
+  requireAuth: env.REQUIRE_AUTH === 'true' || env.REQUIRE_API_KEY === 'true',
+  // JWT/OIDC bearer tokens: a shared secret, a PEM public key or the issuer's JWKS endpoint
+  jwtSecret: env.JWT_SECRET || '',
+  jwtPublicKey: env.JWT_PUBLIC_KEY || '',
+  jwtJwksUri: env.JWT_JWKS_URI || '',
+  jwtIssuer: env.JWT_ISSUER || '',
+  jwtAudience: env.JWT_AUDIENCE || '',
+  // Claims holding the consumer name and role
+  jwtNameClaim: env.JWT_NAME_CLAIM || 'sub',
+  jwtRoleClaim: env.JWT_ROLE_CLAIM || 'role',
+  // HTTPS with client certificates signed by TLS_CA_FILE, for the mtls auth provider
+  tlsCertFile: env.TLS_CERT_FILE || '',
+  tlsKeyFile: env.TLS_KEY_FILE || '',
+  tlsCaFile: env.TLS_CA_FILE || '',
+  // Certificate subjects mapped to consumers: { "CN=gateway.bank-x.com,O=Bank X": { "name": "bank-x", "role": "consumer" } }
+  mtlsConsumers: parseJson(env.MTLS_CONSUMERS, {}),
+  // Header carrying the verified subject when TLS ends at a proxy; the proxy must overwrite it on every request
+  mtlsSubjectHeader: env.MTLS_SUBJECT_HEADER || '',
+  // Self-service keys: live keys per consumer (API_KEYS entries may set "keyLimit") and how long a rotated key keeps working
+  apiKeyLimitPerConsumer: parseInt(env.API_KEY_LIMIT_PER_CONSUMER, 10) || 3,
+  apiKeyRotationGraceMs: parseInt(env.API_KEY_ROTATION_GRACE_MS, 10) || 24 * 60 * 60 * 1000,
+  // Data-quality strictness for writes: 'lenient', 'standard' or 'strict' (API keys may override it)
+  validationStrictness: env.VALIDATION_STRICTNESS || 'standard',
+  // How long a steward's edit lock lasts without a heartbeat
+  editLockTtlMs: parseInt(env.EDIT_LOCK_TTL_MS, 10) || 2 * 60 * 1000,
+  // Record successful writes in the audit log collection
+  auditLog: env.AUDIT_LOG !== 'false',
+  // Source name stamped on every event of the SIEM feed, to tell instances or environments apart
+  siemSource: env.SIEM_SOURCE || 'swift-code-api',
+  // Fields hidden from consumers whose key carries the profile
+  redactionProfiles: parseJson(env.REDACTION_PROFILES, {
+    external: ['address', 'originalAddress']
+  }),
+  // Largest file accepted by the admin import endpoints
+  uploadMaxBytes: parseInt(env.UPLOAD_MAX_BYTES, 10) || 50 * 1024 * 1024,
+  // Address normalization steps run on write and import, in order ('none' disables it)
+  addressNormalizationSteps: (env.ADDRESS_NORMALIZATION || 'whitespace,uppercase,abbreviations,countryFormat')
+    .split(',')
+    .map(step => step.trim())
+    .filter(Boolean),
+  // Collapse imported rows sharing a BIC whose normalized addresses are at least this similar
+  importDedupe: env.IMPORT_DEDUPE === 'true',
+  importDedupeThreshold: parseFloat(env.IMPORT_DEDUPE_THRESHOLD) || 0.9,
+  // Verify data invariants on boot; readiness is refused while a critical one fails
+  integrityCheck: env.INTEGRITY_CHECK === 'true',
+  // How often expired temporary codes are deprecated
+  temporaryExpiryIntervalMs: parseInt(env.TEMPORARY_EXPIRY_INTERVAL_MS, 10) || 60 * 60 * 1000,
+  // How often each instance reloads the country registry to pick up changes made through another one
+  countryRefreshIntervalMs: parseInt(env.COUNTRY_REFRESH_INTERVAL_MS, 10) || 60 * 1000,
+  // Bucket the daily dataset snapshot is published to, e.g. s3://bucket/swift or gs://bucket/swift (unset disables it)
+  snapshotTarget: env.SNAPSHOT_TARGET || '',
+  snapshotIntervalMs: parseInt(env.SNAPSHOT_INTERVAL_MS, 10) || 24 * 60 * 60 * 1000,
+  // Worksheet (name or 1-based position) and header row of .xlsx imports; unset means detect them
+  importSheet: env.IMPORT_SHEET || '',
+  importHeaderRow: parseInt(env.IMPORT_HEADER_ROW, 10) || 0,
+  // 'replace' rebuilds the collection from the import file, 'upsert' merges the file into it,
+  // 'delta' writes only the differences and removes codes the file no longer lists
+  importMode: env.IMPORT_MODE || 'replace',
+  // Upsert imports set codes missing from the file to inactive
+  importDeactivateMissing: env.IMPORT_DEACTIVATE_MISSING === 'true',
+  // CSV column separator and file encoding of import files; workbooks carry their own
+  importDelimiter: env.IMPORT_DELIMITER || ',',
+  importEncoding: env.IMPORT_ENCODING || 'utf8',
+  // Rows written per insertMany while an import streams through its file
+  importBatchSize: parseInt(env.IMPORT_BATCH_SIZE, 10) || 1000,
+  // Lifetime of the lease a running import holds, renewed while it runs; a crashed import blocks
+  // the next one for at most this long
+  leaseTtlMs: parseInt(env.LEASE_TTL_MS, 10) || 60 * 1000,
+  // NDJSON file every import also writes its rejected rows to (unset keeps them in the collection only)
+  importRejectsFile: env.IMPORT_REJECTS_FILE || '',
+  // File or URL re-imported on a schedule (unset disables it), when as a cron expression in the given
+  // time zone, and in which mode (default importMode)
+  importScheduleSource: env.IMPORT_SCHEDULE_SOURCE || '',
+  importScheduleCron: env.IMPORT_SCHEDULE_CRON || '0 2 * * *',
+  importScheduleTimezone: env.IMPORT_SCHEDULE_TIMEZONE || 'UTC',
+  importScheduleMode: env.IMPORT_SCHEDULE_MODE || '',
+  // How long an https:// import source may go without sending anything, while connecting or mid-download
+  importDownloadTimeoutMs: parseInt(env.IMPORT_DOWNLOAD_TIMEOUT_MS, 10) || 30 * 1000,
+  // Records sampled by the post-deployment smoke test
+  smokeTestSamples: parseInt(env.SMOKE_TEST_SAMPLES, 10) || 20,
+  // In-process cache of HQ branch lists; writes elsewhere only show up here after the TTL
+  branchCache: env.BRANCH_CACHE !== 'false',
+  branchCacheTtlMs: parseInt(env.BRANCH_CACHE_TTL_MS, 10) || 5 * 60 * 1000,
+  branchCacheMaxEntries: parseInt(env.BRANCH_CACHE_MAX_ENTRIES, 10) || 10000,
+  // Serve plain detail and existence lookups from the denormalized lookup collection
+  lookupReadModel: env.LOOKUP_READ_MODEL !== 'false',
+  // Lifetime of a lookup entry; bounds how long a write that missed its invalidation can be served stale
+  lookupEntryTtlMs: parseInt(env.LOOKUP_ENTRY_TTL_MS, 10) || 10 * 60 * 1000,
+  // Send per-stage timings (validation, cache, db, serialization) in a Server-Timing header; they
+  // reach the metrics either way
+  stageTimingHeaders: env.STAGE_TIMING_HEADERS === 'true',
+  // Console output below this level is dropped: 'error', 'warn', 'info' or 'debug'
+  logLevel: env.LOG_LEVEL || 'info',
+  // KEY=value file re-read on SIGHUP or POST /v1/admin/config/reload; its entries override the environment
+  configFile: env.CONFIG_FILE || ''
+});
 
-const router = express.Router();
+module.exports = buildConfig(process.env);
+module.exports.buildConfig = buildConfig;
 
-// GET routes
-router.get('/:swiftCode', swiftCodeController.getSwiftCodeDetails);
-router.get('/country/:countryISO2', swiftCodeController.getSwiftCodesByCountry);
+// src/config/database.js
+module.exports = {
+  mongoURI: process.env.MONGODB_URI || 'mongodb://localhost:27017/swift-codes'
+};
 
-// POST route
-router.post('/', swiftCodeController.addSwiftCode);
+// src/config/reload.js
+const fs = require('fs');
+const dotenv = require('dotenv');
+const appConfig = require('./app');
+const branchCache = require('../utils/branchCache');
+const { applyLogLevel } = require('../utils/logLevel');
 
-// DELETE route
-router.delete('/:swiftCode', swiftCodeController.deleteSwiftCode);
+// Environment the process started with; the config file is layered over it for reloadable settings only
+const startupEnv = { ...process.env };
 
-module.exports = router;
+let watching = false;
 
-// src/controllers/swiftCodeController.js
-const swiftCodeService = require('../services/swiftCodeService');
+// Settings read on every use, so they can change without a restart; the rest (port, TLS, auth
+// providers, MongoDB URI, job intervals) are read once and still need one
+const RELOADABLE_SETTINGS = [
+  'rateLimitPerMinute',
+  'adminIpAllowlist',
+  'pageSizes',
+  'bulkMaxItems',
+  'validationStreamMaxLines',
+  'validationStreamIdleTimeoutMs',
+  'recordCeiling',
+  'unknownCodeFallback',
+  'validationStrictness',
+  'redactionProfiles',
+  'mutationCapture',
+  'auditLog',
+  'importDedupe',
+  'importDedupeThreshold',
+  'editLockTtlMs',
+  'branchCache',
+  'branchCacheTtlMs',
+  'branchCacheMaxEntries',
+  'stageTimingHeaders',
+  'logLevel'
+];
 
-exports.getSwiftCodeDetails = async (req, res, next) => {
+const readConfigFile = (file) => {
+  if (!file) {
+    return {};
+  }
+  
   try {
-    const { swiftCode } = req.params;
-    const result = await swiftCodeService.getSwiftCodeDetails(swiftCode);
-    
-    if (!result) {
-      return res.status(404).json({ message: 'SWIFT code not found' });
-    }
-    
-    res.status(200).json(result);
+    return dotenv.parse(fs.readFileSync(file));
   } catch (error) {
-    next(error);
+    if (error.code === 'ENOENT') {
+      return {};
+    }
+    throw error;
   }
 };
 
-exports.getSwiftCodesByCountry = async (req, res, next) => {
-  try {
-    const { countryISO2 } = req.params;
-    const result = await swiftCodeService.getSwiftCodesByCountry(countryISO2.toUpperCase());
-    
-    if (!result) {
-      return res.status(404).json({ message: 'Country not found' });
-    }
-    
-    res.status(200).json(result);
-  } catch (error) {
-    next(error);
+// Re-read the config file (CONFIG_FILE, none by default) and apply the reloadable settings that
+// changed; everything else keeps the value the environment gave it
+exports.reload = () => {
+  const next = appConfig.buildConfig({ ...startupEnv, ...readConfigFile(appConfig.configFile) });
+  const changed = RELOADABLE_SETTINGS.filter(key => JSON.stringify(next[key]) !== JSON.stringify(appConfig[key]));
+  
+  changed.forEach((key) => {
+    appConfig[key] = next[key];
+  });
+  
+  // Entries cached under the old TTL or while caching was off would outlive the change
+  if (changed.some(key => key.startsWith('branchCache'))) {
+    branchCache.clear();
+  }
+  if (changed.includes('logLevel')) {
+    applyLogLevel(appConfig.logLevel);
+  }
+  if (changed.length > 0) {
+    console.log(`Reloaded configuration: ${changed.join(', ')}`);
   }
+  
+  return { reloadedAt: new Date(), changed };
 };
 
-exports.addSwiftCode = async (req, res, next) => {
-  try {
-    const swiftCodeData = req.body;
-    
-    // Validate required fields
-    const requiredFields = ['swiftCode', 'bankName', 'address', 'countryISO2', 'countryName', 'isHeadquarter'];
-    for (const field of requiredFields) {
-      if (!swiftCodeData[field] && swiftCodeData[field] !== false) {
-        return res.status(400).json({ message: `Missing required field: ${field}` });
-      }
-    }
-    
-    // Ensure uppercase for country fields
-    swiftCodeData.countryISO2 = swiftCodeData.countryISO2.toUpperCase();
-    swiftCodeData.countryName = swiftCodeData.countryName.toUpperCase();
-    
-    const result = await swiftCodeService.addSwiftCode(swiftCodeData);
-    res.status(201).json({ message: 'SWIFT code added successfully' });
-  } catch (error) {
-    if (error.code === 11000) { // MongoDB duplicate key error
-      res.status(409).json({ message: 'SWIFT code already exists' });
-    } else {
-      next(error);
-    }
+exports.watchSignals = () => {
+  if (watching) {
+    return;
   }
+  
+  watching = true;
+  process.on('SIGHUP', () => {
+    try {
+      exports.reload();
+    } catch (error) {
+      console.error('Failed to reload configuration', error);
+    }
+  });
 };
 
-exports.deleteSwiftCode = async (req, res, next) => {
+exports.RELOADABLE_SETTINGS = RELOADABLE_SETTINGS;
+
+// src/middleware/authenticate.js
+const appConfig = require('../config/app');
+const { resolveProviders } = require('../auth');
+
+// Identify the consumer with the first configured provider that finds credentials on the request
+module.exports = async (req, res, next) => {
   try {
-    const { swiftCode } = req.params;
-    const result = await swiftCodeService.deleteSwiftCode(swiftCode);
-    
-    if (result.deletedCount === 0) {
-      return res.status(404).json({ message: 'SWIFT code not found' });
+    for (const provider of resolveProviders(appConfig.authProviders)) {
+      const result = await provider.authenticate(req);
+      
+      if (result && result.error) {
+        return res.status(401).json({ message: result.error });
+      }
+      if (result) {
+        req.consumer = { ...result.consumer, authMethod: provider.name };
+        return next();
+      }
     }
     
-    res.status(200).json({ message: 'SWIFT code deleted successfully' });
+    if (appConfig.requireAuth) {
+      return res.status(401).json({ message: 'Authentication required' });
+    }
+    req.consumer = null;
+    next();
   } catch (error) {
     next(error);
   }
 };
 
-// src/services/swiftCodeService.js
-const SwiftCode = require('../models/swiftCode');
+// src/middleware/redaction.js
+const appConfig = require('../config/app');
 
-exports.getSwiftCodeDetails = async (swiftCode) => {
-  // Find the requested SWIFT code
-  const swiftCodeData = await SwiftCode.findOne({ swiftCode: swiftCode.toUpperCase() });
-  
-  if (!swiftCodeData) {
-    return null;
+// Remove the listed fields from every SWIFT code record in a response body
+const redact = (value, fields) => {
+  if (Array.isArray(value)) {
+    return value.map(item => redact(item, fields));
+  }
+  if (!value || typeof value !== 'object') {
+    return value;
   }
   
-  // Format the base response
-  const response = {
-    address: swiftCodeData.address,
-    bankName: swiftCodeData.bankName,
-    countryISO2: swiftCodeData.countryISO2,
-    countryName: swiftCodeData.countryName,
-    isHeadquarter: swiftCodeData.isHeadquarter,
-    swiftCode: swiftCodeData.swiftCode
-  };
+  const isRecord = Object.prototype.hasOwnProperty.call(value, 'swiftCode');
+  const result = {};
   
-  // If this is a headquarters, include branches
-  if (swiftCodeData.isHeadquarter) {
-    // Get first 8 characters of the SWIFT code to find branches
-    const bankPrefix = swiftCodeData.swiftCode.substring(0, 8);
-    
-    // Find all branches of this bank (excluding the HQ itself)
-    const branches = await SwiftCode.find({
-      swiftCode: { $ne: swiftCodeData.swiftCode },
-      swiftCode: { $regex: `^${bankPrefix}` },
-      isHeadquarter: false
-    });
-    
-    response.branches = branches.map(branch => ({
-      address: branch.address,
-      bankName: branch.bankName,
-      countryISO2: branch.countryISO2,
-      isHeadquarter: branch.isHeadquarter,
-      swiftCode: branch.swiftCode
-    }));
+  for (const [key, nested] of Object.entries(value)) {
+    if (isRecord && fields.includes(key)) {
+      continue;
+    }
+    result[key] = redact(nested, fields);
   }
   
-  return response;
+  return result;
 };
 
-exports.getSwiftCodesByCountry = async (countryISO2) => {
-  // Find all SWIFT codes for the given country
-  const swiftCodes = await SwiftCode.find({ countryISO2: countryISO2.toUpperCase() });
+// Fields hidden from a consumer by the redaction profile of its key
+const redactedFields = (consumer) => {
+  const profile = consumer && consumer.redactionProfile;
+  return (profile && appConfig.redactionProfiles[profile]) || [];
+};
+
+// Apply the redaction profile of the calling API key to every JSON response; streamed responses
+// such as exports bypass res.json and filter their fields with redactedFields themselves
+module.exports = (req, res, next) => {
+  const fields = redactedFields(req.consumer);
   
-  if (swiftCodes.length === 0) {
-    return null;
+  if (fields.length === 0) {
+    return next();
   }
   
-  // Format the response
-  const response = {
-    countryISO2: countryISO2.toUpperCase(),
-    countryName: swiftCodes[0].countryName, // All records for this country should have the same name
-    swiftCodes: swiftCodes.map(code => ({
-      address: code.address,
-      bankName: code.bankName,
-      countryISO2: code.countryISO2,
-      isHeadquarter: code.isHeadquarter,
-      swiftCode: code.swiftCode
-    }))
-  };
+  const originalJson = res.json.bind(res);
+  res.json = (body) => originalJson(redact(body, fields));
   
-  return response;
+  next();
 };
 
-exports.addSwiftCode = async (swiftCodeData) => {
-  return await SwiftCode.create(swiftCodeData);
-};
+module.exports.redact = redact;
+module.exports.redactedFields = redactedFields;
 
-exports.deleteSwiftCode = async (swiftCode) => {
-  return await SwiftCode.deleteOne({ swiftCode: swiftCode.toUpperCase() });
-};
+// src/middleware/responseValidator.js
+const Ajv = require('ajv');
+const openapi = require('../docs/openapi');
+const appConfig = require('../config/app');
 
-// src/utils/dataParser.js
-const fs = require('fs');
-const path = require('path');
-const csv = require('csv-parser');
-const mongoose = require('mongoose');
-const SwiftCode = require('../models/swiftCode');
-const config = require('../config/database');
+const ajv = new Ajv({ allErrors: true, strict: false });
+ajv.addSchema(openapi, 'openapi');
 
-// Path to the CSV file - update this to match your file location
-const CSV_FILE_PATH = path.resolve(__dirname, '../../../data/swift_codes.csv');
+// Express "/country/:countryISO2" -> OpenAPI "/country/{countryISO2}", dropping parameter patterns like ":bic4([A-Za-z]{4})"
+const toOpenApiPath = (req) => {
+  const path = `${req.baseUrl}${req.route.path}`.replace(/:(\w+)(\([^)]*\))?/g, '{$1}');
+  return path.length > 1 ? path.replace(/\/$/, '') : path;
+};
 
-// Parse SWIFT codes from CSV file
-async function parseAndStoreSwiftCodes() {
-  try {
-    // Connect to MongoDB
-    await mongoose.connect(config.mongoURI);
-    console.log('Connected to MongoDB');
-    
-    // Clear existing data (optional)
-    await SwiftCode.deleteMany({});
-    console.log('Cleared existing SWIFT code data');
-    
-    const swiftCodes = [];
+// Find the documented schema for the matched route and status code
+const findSchema = (req, statusCode) => {
+  if (!req.route) {
+    return null;
+  }
+  
+  const pathItem = openapi.paths[toOpenApiPath(req)] || {};
+  const operation = pathItem[req.method.toLowerCase()];
+  const response = operation && operation.responses[statusCode];
+  
+  return response && response.content ? response.content['application/json'].schema : null;
+};
+
+// Validate outgoing JSON bodies against the OpenAPI document to catch serializer drift
+module.exports = (req, res, next) => {
+  const mode = appConfig.responseValidation;
+  
+  // Sparse fieldsets deliberately omit required fields
+  if (mode === 'off' || req.query.fields) {
+    return next();
+  }
+  
+  const originalJson = res.json.bind(res);
+  
+  res.json = (body) => {
+    const schema = findSchema(req, res.statusCode);
     
-    // Create a stream to read and parse the CSV file
-    fs.createReadStream(CSV_FILE_PATH)
-      .pipe(csv())
-      .on('data', (row) => {
-        // Extract and transform data
-        const swiftCode = row.SWIFT || row.swift_code || '';
-        const isHeadquarter = swiftCode.endsWith('XXX');
-        
-        // Format countries as uppercase
-        const countryISO2 = (row.COUNTRY_ISO || row.country_iso || '').toUpperCase();
-        const countryName = (row.COUNTRY_NAME || row.country_name || '').toUpperCase();
-        
-        // Create a SWIFT code record
-        const swiftCodeRecord = {
-          swiftCode: swiftCode,
-          bankName: row.BANK_NAME || row.bank_name || '',
-          address: row.ADDRESS || row.address || '',
-          countryISO2: countryISO2,
-          countryName: countryName,
-          isHeadquarter: isHeadquarter
-        };
+    if (schema) {
+      const validate = ajv.getSchema(`openapi${schema.$ref}`);
+      
+      // Validate what the client receives, e.g. dates as ISO strings
+      if (!validate(JSON.parse(JSON.stringify(body)))) {
+        const errors = validate.errors.map(error => `${error.instancePath || '/'} ${error.message}`);
+        console.warn(`Response schema mismatch for ${req.method} ${req.originalUrl}: ${errors.join('; ')}`);
         
-        swiftCodes.push(swiftCodeRecord);
-      })
-      .on('end', async () => {
-        // Insert all parsed records to the database
-        if (swiftCodes.length > 0) {
-          await SwiftCode.insertMany(swiftCodes);
-          console.log(`Successfully imported ${swiftCodes.length} SWIFT code records`);
-        } else {
-          console.log('No data found to import');
+        if (mode === 'fail') {
+          res.status(500);
+          return originalJson({ message: 'Response failed schema validation', errors });
         }
-        
-        // Disconnect from MongoDB
-        mongoose.disconnect();
-      });
-  } catch (error) {
-    console.error('Error parsing SWIFT codes:', error);
-    process.exit(1);
+      }
+    }
+    
+    return originalJson(body);
+  };
+  
+  next();
+};
+
+// src/middleware/mutationRecorder.js
+const fs = require('fs');
+const appConfig = require('../config/app');
+
+const MUTATING_METHODS = new Set(['POST', 'PUT', 'PATCH', 'DELETE']);
+const SENSITIVE_KEYS = /pass(word)?|secret|token|api[-_]?key|authorization/i;
+
+// Mask values of sensitive-looking keys at any depth
+const sanitize = (value) => {
+  if (Array.isArray(value)) {
+    return value.map(sanitize);
   }
-}
+  if (value && typeof value === 'object') {
+    return Object.fromEntries(Object.entries(value).map(([key, nested]) => [
+      key,
+      SENSITIVE_KEYS.test(key) ? '[REDACTED]' : sanitize(nested)
+    ]));
+  }
+  return value;
+};
 
-// Execute if this file is run directly
-if (require.main === module) {
-  parseAndStoreSwiftCodes();
-}
+let captureStream = null;
+
+const getCaptureStream = () => {
+  if (!captureStream) {
+    captureStream = fs.createWriteStream(appConfig.mutationCaptureFile, { flags: 'a' });
+  }
+  return captureStream;
+};
 
-module.exports = { parseAndStoreSwiftCodes };
\ No newline at end of file
+// Append every mutating request to an NDJSON log once its response has been sent
+module.exports = (req, res, next) => {
+  if (!appConfig.mutationCapture || !MUTATING_METHODS.has(req.method)) {
+    return next();
+  }
+  
+  const capturedAt = new Date().toISOString();
+  
+  res.on('finish', () => {
+    const mutation = {
+      capturedAt,
+      method: req.method,
+      path: req.originalUrl,
+      statusCode: res.statusCode
+    };
+    
+    if (req.body && Object.keys(req.body).length > 0) {
+      mutation.body = sanitize(req.body);
+    }
+    
+    getCaptureStream().write(`${JSON.stringify(mutation)}\n`);
+  });
+  
+  next();
+};
+
+module.exports.sanitize = sanitize;
+
+// src/middleware/auditLogger.js
+const appConfig = require('../config/app');
+const AuditLog = require('../models/auditLog');
+const { resolveActor } = require('../utils/actor');
+const { resolveClientIp } = require('../utils/clientIp');
+
+// Audited routes, keyed by method and mounted route path
+const AUDITED_ACTIONS = {
+  'POST /v1/swift-codes/': 'create',
+  'POST /v1/swift-codes/bulk': 'bulk-create',
+  'POST /v1/swift-codes/:swiftCode/restore': 'restore',
+  'POST /v1/swift-codes/:swiftCode/deactivate': 'deactivate',
+  'POST /v1/swift-codes/:swiftCode/activate': 'activate',
+  'POST /v1/swift-codes/:swiftCode/reassign': 'reassign',
+  'PATCH /v1/swift-codes/:swiftCode': 'update-names',
+  'PUT /v1/swift-codes/:swiftCode/external-ids/:system': 'set-external-id',
+  'DELETE /v1/swift-codes/bulk': 'bulk-delete',
+  'DELETE /v1/swift-codes/:swiftCode': 'delete',
+  'DELETE /v1/swift-codes/:swiftCode/external-ids/:system': 'remove-external-id',
+  'POST /v1/banks/': 'create-bank',
+  'PATCH /v1/banks/:bic8': 'rename-bank',
+  'DELETE /v1/banks/:bic8': 'delete-bank',
+  'POST /v1/events/mergers': 'record-merger',
+  'POST /v1/admin/imports': 'import',
+  'POST /v1/admin/config/reload': 'reload-config',
+  'PUT /v1/admin/countries/:countryISO2': 'set-country',
+  'DELETE /v1/admin/countries/:countryISO2': 'reset-country'
+};
+
+// SWIFT codes named by the path or body of a write
+const collectSwiftCodes = (req) => {
+  const codes = [];
+  const body = req.body;
+  
+  if (req.params && req.params.swiftCode) {
+    codes.push(req.params.swiftCode);
+  }
+  if (Array.isArray(body)) {
+    body.forEach(record => record && typeof record.swiftCode === 'string' && codes.push(record.swiftCode));
+  } else if (body && typeof body === 'object') {
+    if (typeof body.swiftCode === 'string') {
+      codes.push(body.swiftCode);
+    }
+    if (Array.isArray(body.swiftCodes)) {
+      body.swiftCodes.forEach(code => typeof code === 'string' && codes.push(code));
+    }
+  }
+  
+  return [...new Set(codes.map(code => code.trim().toUpperCase()))];
+};
+
+// Characters 5-6 of a BIC are its country
+const collectCountries = (req, swiftCodes) => {
+  const countries = swiftCodes.map(code => code.substring(4, 6)).filter(country => country.length === 2);
+  
+  if (req.params && typeof req.params.countryISO2 === 'string') {
+    countries.push(req.params.countryISO2.toUpperCase());
+  }
+  if (req.body && req.body.filter && typeof req.body.filter.countryISO2 === 'string') {
+    countries.push(req.body.filter.countryISO2.toUpperCase());
+  }
+  
+  return [...new Set(countries)];
+};
+
+// Record successful writes once their response has been sent
+module.exports = (req, res, next) => {
+  if (!appConfig.auditLog) {
+    return next();
+  }
+  
+  const at = new Date();
+  
+  res.on('finish', () => {
+    if (!req.route || res.statusCode >= 400) {
+      return;
+    }
+    
+    const action = AUDITED_ACTIONS[`${req.method} ${req.baseUrl}${req.route.path}`];
+    if (!action) {
+      return;
+    }
+    
+    const swiftCodes = collectSwiftCodes(req);
+    
+    AuditLog.create({
+      at,
+      actor: resolveActor(req),
+      action,
+      method: req.method,
+      path: req.originalUrl,
+      statusCode: res.statusCode,
+      clientIp: resolveClientIp(req),
+      swiftCodes,
+      countries: collectCountries(req, swiftCodes)
+    }).catch(error => console.error('Failed to write audit log entry:', error.message));
+  });
+  
+  next();
+};
+
+module.exports.AUDITED_ACTIONS = AUDITED_ACTIONS;
+
+// src/middleware/recordCeiling.js
+const SwiftCode = require('../models/swiftCode');
+const appConfig = require('../config/app');
+
+// Reject API writes that would grow the collection beyond the configured ceiling; imports are not affected
+module.exports = async (req, res, next) => {
+  try {
+    if (!appConfig.recordCeiling) {
+      return next();
+    }
+    
+    const incoming = Array.isArray(req.body) ? req.body.length : 1;
+    const count = await SwiftCode.estimatedDocumentCount();
+    
+    if (count + incoming > appConfig.recordCeiling) {
+      return res.status(403).json({ message: `Record ceiling of ${appConfig.recordCeiling} SWIFT codes reached` });
+    }
+    
+    next();
+  } catch (error) {
+    next(error);
+  }
+};
+
+// src/middleware/httpMetrics.js
+const metricsService = require('../services/metricsService');
+
+// Time every request and count it once the response has been sent
+module.exports = (req, res, next) => {
+  const start = process.hrtime.bigint();
+  
+  res.on('finish', () => {
+    metricsService.observeRequest(req, res, Number(process.hrtime.bigint() - start) / 1e9);
+  });
+  
+  next();
+};
+
+// src/middleware/stageTimings.js
+const metricsService = require('../services/metricsService');
+const stageTimer = require('../utils/stageTimer');
+const appConfig = require('../config/app');
+
+const formatServerTiming = (timings, total) => [...Object.entries(timings), ['total', total]]
+  .map(([stage, ms]) => `${stage};dur=${ms.toFixed(2)}`)
+  .join(', ');
+
+// Measure the stages of every request. Installed before the other res.json wrappers, so it encodes
+// the final body itself and serialization covers JSON.stringify alone
+module.exports = (req, res, next) => {
+  stageTimer.run(() => {
+    const start = stageTimer.start();
+    const timings = stageTimer.current();
+    const { writeHead } = res;
+    
+    res.json = (body) => {
+      const encodeStart = stageTimer.start();
+      const payload = JSON.stringify(body);
+      stageTimer.record('serialization', encodeStart);
+      
+      if (!res.get('Content-Type')) {
+        res.type('json');
+      }
+      return res.send(payload);
+    };
+    
+    // Headers are written once, whether by send, a stream or an implicit write
+    res.writeHead = function (...args) {
+      if (appConfig.stageTimingHeaders && !this.headersSent) {
+        this.setHeader('Server-Timing', formatServerTiming(timings, Number(stageTimer.start() - start) / 1e6));
+      }
+      return writeHead.apply(this, args);
+    };
+    
+    res.on('finish', () => metricsService.observeStages(req, timings));
+    
+    next();
+  });
+};
+
+// src/middleware/editLockGuard.js
+const editLockService = require('../services/editLockService');
+const { resolveActor } = require('../utils/actor');
+
+// Refuse writes to a record while another steward holds its edit lock; writes spanning several
+// records (bulk deletes, bank renames) check with lockedResponse themselves, imports are the dataset
+// of record and don't wait for stewards
+module.exports = async (req, res, next) => {
+  try {
+    const lock = await editLockService.findActiveLock(req.params.swiftCode);
+    
+    if (lock && lock.lockedBy !== resolveActor(req)) {
+      return res.status(423).json({ message: `Record is locked by ${lock.lockedBy}`, ...lock });
+    }
+    
+    next();
+  } catch (error) {
+    next(error);
+  }
+};
+
+// 423 body for a write refused because of the given locks
+module.exports.lockedResponse = (locks) => ({
+  message: `Records are locked by ${[...new Set(locks.map(lock => lock.lockedBy))].join(', ')}`,
+  locks
+});
+
+// src/middleware/resolveBic8.js
+const swiftCodeService = require('../services/swiftCodeService');
+
+// Upstream systems often store only the BIC8; point :swiftCode at the institution's headquarter
+// when exactly one record can be meant, and list the candidates when several could
+module.exports = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    
+    if (swiftCode.length !== 8) {
+      return next();
+    }
+    
+    const { resolved, candidates } = await swiftCodeService.resolveBic8(swiftCode);
+    
+    // Without a single headquarter, guessing a branch would act on the wrong record
+    if (!resolved && candidates.length > 0) {
+      return res.status(409).json({ message: `BIC8 ${swiftCode} does not identify a single headquarter`, candidates });
+    }
+    
+    if (resolved) {
+      req.params.swiftCode = resolved;
+    }
+    
+    next();
+  } catch (error) {
+    next(error);
+  }
+};
+
+// src/middleware/requireRole.js
+// Restrict a router to consumers carrying one of the given roles, or to any authenticated consumer when none are given
+module.exports = (...roles) => (req, res, next) => {
+  if (!req.consumer) {
+    return res.status(401).json({ message: 'Authentication required' });
+  }
+  
+  if (roles.length > 0 && !roles.includes(req.consumer.role)) {
+    return res.status(403).json({ message: 'Insufficient permissions' });
+  }
+  
+  next();
+};
+
+// src/middleware/rateLimiter.js
+const appConfig = require('../config/app');
+const { resolveClientIp } = require('../utils/clientIp');
+
+const WINDOW_MS = 60 * 1000;
+
+// Fixed one-minute windows per client IP; counts are per instance
+let windowStart = 0;
+let counts = new Map();
+
+module.exports = (req, res, next) => {
+  if (!appConfig.rateLimitPerMinute) {
+    return next();
+  }
+  
+  const now = Date.now();
+  if (now - windowStart >= WINDOW_MS) {
+    windowStart = now;
+    counts = new Map();
+  }
+  
+  const clientIp = resolveClientIp(req);
+  const count = (counts.get(clientIp) || 0) + 1;
+  counts.set(clientIp, count);
+  
+  if (count > appConfig.rateLimitPerMinute) {
+    res.set('Retry-After', String(Math.ceil((windowStart + WINDOW_MS - now) / 1000)));
+    return res.status(429).json({ message: 'Too many requests, try again later' });
+  }
+  
+  next();
+};
+
+// src/middleware/ipAllowlist.js
+const appConfig = require('../config/app');
+const { resolveClientIp } = require('../utils/clientIp');
+
+// Refuse requests from client IPs outside the admin allowlist, when one is configured
+module.exports = (req, res, next) => {
+  const allowlist = appConfig.adminIpAllowlist;
+  
+  if (allowlist.length > 0 && !allowlist.includes(resolveClientIp(req))) {
+    return res.status(403).json({ message: 'Client IP not allowed' });
+  }
+  
+  next();
+};
+
+// src/middleware/fileUpload.js
+const fs = require('fs');
+const os = require('os');
+const path = require('path');
+const crypto = require('crypto');
+const multer = require('multer');
+const appConfig = require('../config/app');
+
+// Previews read a few rows and stay in memory
+const memoryUpload = multer({
+  storage: multer.memoryStorage(),
+  limits: { fileSize: appConfig.uploadMaxBytes }
+});
+
+// Imports read whole files, which go straight to a temporary file the parser streams from
+const diskUpload = multer({
+  storage: multer.diskStorage({
+    destination: os.tmpdir(),
+    filename: (req, file, done) => done(null, `swift-import-${crypto.randomUUID()}${path.extname(file.originalname)}`)
+  }),
+  limits: { fileSize: appConfig.uploadMaxBytes }
+});
+
+// Accept a single file in the "file" field, answering 400 for oversized or malformed uploads; with
+// disk, req.file.path is removed once the response is done, whatever it was
+module.exports = (field = 'file', { disk = false } = {}) => {
+  const single = (disk ? diskUpload : memoryUpload).single(field);
+  
+  return (req, res, next) => single(req, res, (error) => {
+    if (req.file && req.file.path) {
+      res.on('close', () => fs.promises.rm(req.file.path, { force: true }).catch(() => {}));
+    }
+    if (error instanceof multer.MulterError) {
+      return res.status(400).json({ message: error.message });
+    }
+    next(error);
+  });
+};
+
+// src/middleware/normalizeInput.js
+const { normalizeInput, normalizeValue } = require('../utils/inputNormalizer');
+
+// Normalize request bodies and query strings once, before any handler sees them
+module.exports = (req, res, next) => {
+  if (req.body && typeof req.body === 'object') {
+    req.body = normalizeInput(req.body);
+  }
+  
+  for (const [name, value] of Object.entries(req.query)) {
+    req.query[name] = normalizeValue(name, value);
+  }
+  
+  next();
+};
+
+// Route parameters only exist once a router matched, so routers register this with router.param()
+module.exports.param = (req, res, next, value, name) => {
+  req.params[name] = normalizeValue(name, value);
+  next();
+};
+
+// src/middleware/validateRequest.js
+const { VALIDATION_OPTIONS } = require('../utils/swiftCodeValidator');
+const stageTimer = require('../utils/stageTimer');
+
+const LOCATIONS = ['params', 'query', 'body'];
+
+// Validate request parts against Joi schemas, e.g. validateRequest({ body: swiftCodeRecordSchema }),
+// answering 400 with every field-level problem instead of just the first
+module.exports = (schemas) => (req, res, next) => {
+  const start = stageTimer.start();
+  const errors = LOCATIONS
+    .filter(location => schemas[location])
+    .flatMap((location) => {
+      const { error } = schemas[location].validate(req[location], VALIDATION_OPTIONS);
+      
+      return error
+        ? error.details.map(detail => ({ location, field: detail.path.join('.'), message: detail.message }))
+        : [];
+    });
+  stageTimer.record('validation', start);
+  
+  if (errors.length > 0) {
+    return res.status(400).json({ message: errors[0].message, errors });
+  }
+  
+  next();
+};
+
+// src/auth/index.js
+const apiKeyProvider = require('./apiKeyProvider');
+const jwtProvider = require('./jwtProvider');
+const mtlsProvider = require('./mtlsProvider');
+
+// An auth provider is { name, authenticate(req) } where authenticate resolves to null when the request
+// carries none of its credentials, { error } when they are invalid, or { consumer } with at least a name
+// and a role, which requireRole, auditing and the per-consumer settings rely on
+const PROVIDERS = {
+  [apiKeyProvider.name]: apiKeyProvider,
+  [jwtProvider.name]: jwtProvider,
+  [mtlsProvider.name]: mtlsProvider
+};
+
+exports.registerProvider = (provider) => {
+  PROVIDERS[provider.name] = provider;
+};
+
+// Providers in the configured order, failing fast on typos so a deployment can't silently lose authentication
+exports.resolveProviders = (names) => names.map((name) => {
+  if (!PROVIDERS[name]) {
+    throw new Error(`Unknown auth provider ${name}, expected one of: ${Object.keys(PROVIDERS).join(', ')}`);
+  }
+  return PROVIDERS[name];
+});
+
+// src/auth/apiKeyProvider.js
+const apiKeyService = require('../services/apiKeyService');
+
+// X-API-Key header: configured keys first, then self-service ones
+exports.name = 'api-key';
+
+exports.authenticate = async (req) => {
+  const apiKey = req.get('X-API-Key');
+  
+  if (!apiKey) {
+    return null;
+  }
+  
+  const consumer = apiKeyService.fromConfiguredKey(apiKey) || await apiKeyService.findConsumer(apiKey);
+  return consumer ? { consumer } : { error: 'Invalid API key' };
+};
+
+// src/auth/jwtProvider.js
+const jwt = require('jsonwebtoken');
+const jwksClient = require('jwks-rsa');
+const appConfig = require('../config/app');
+
+// Authorization: Bearer <JWT>, signed with a shared secret or public key, or by an OIDC issuer's JWKS
+exports.name = 'jwt';
+
+let jwks = null;
+
+const getJwks = () => {
+  if (!jwks || jwks.uri !== appConfig.jwtJwksUri) {
+    jwks = { uri: appConfig.jwtJwksUri, client: jwksClient({ jwksUri: appConfig.jwtJwksUri, cache: true }) };
+  }
+  return jwks.client;
+};
+
+// jsonwebtoken asks for the key once it has read the token header
+const getKey = (header, callback) => {
+  if (!appConfig.jwtJwksUri) {
+    return callback(null, appConfig.jwtPublicKey || appConfig.jwtSecret);
+  }
+  getJwks().getSigningKey(header.kid)
+    .then(key => callback(null, key.getPublicKey()))
+    .catch(callback);
+};
+
+const verify = (token) => new Promise((resolve, reject) => {
+  jwt.verify(token, getKey, {
+    issuer: appConfig.jwtIssuer || undefined,
+    audience: appConfig.jwtAudience || undefined
+  }, (error, claims) => (error ? reject(error) : resolve(claims)));
+});
+
+exports.authenticate = async (req) => {
+  const [scheme, token] = (req.get('Authorization') || '').split(' ');
+  
+  if (scheme !== 'Bearer' || !token) {
+    return null;
+  }
+  
+  let claims;
+  try {
+    claims = await verify(token);
+  } catch (error) {
+    return { error: 'Invalid bearer token' };
+  }
+  
+  const name = claims[appConfig.jwtNameClaim];
+  if (!name) {
+    return { error: `Bearer token has no ${appConfig.jwtNameClaim} claim` };
+  }
+  
+  return {
+    consumer: {
+      name,
+      role: claims[appConfig.jwtRoleClaim] || 'consumer',
+      redactionProfile: claims.redactionProfile,
+      strictness: claims.strictness
+    }
+  };
+};
+
+// src/auth/mtlsProvider.js
+const appConfig = require('../config/app');
+
+// Client certificates of mutual TLS, either on the socket or verified by a TLS-terminating proxy
+exports.name = 'mtls';
+
+// Subject fields in RFC 4514 order, most specific first, e.g. "CN=gateway.bank-x.com,OU=Payments,O=Bank X,C=PL"
+const SUBJECT_FIELDS = ['CN', 'OU', 'O', 'L', 'ST', 'C'];
+
+const toDistinguishedName = (subject) => SUBJECT_FIELDS
+  .filter(field => subject[field])
+  .map(field => `${field}=${[].concat(subject[field]).join('+')}`)
+  .join(',');
+
+// Verified subject of the request, or null when it presented no certificate
+const readSubject = (req) => {
+  if (appConfig.mtlsSubjectHeader && req.get(appConfig.mtlsSubjectHeader)) {
+    return { subject: req.get(appConfig.mtlsSubjectHeader).trim(), authorized: true };
+  }
+  
+  const socket = req.socket;
+  const certificate = typeof socket.getPeerCertificate === 'function' ? socket.getPeerCertificate() : null;
+  
+  if (!certificate || !certificate.subject) {
+    return null;
+  }
+  return { subject: toDistinguishedName(certificate.subject), authorized: socket.authorized };
+};
+
+exports.authenticate = async (req) => {
+  const presented = readSubject(req);
+  
+  if (!presented) {
+    return null;
+  }
+  if (!presented.authorized) {
+    return { error: 'Client certificate is not trusted' };
+  }
+  
+  // The full subject wins over a mapping of its common name alone
+  const commonName = presented.subject.split(',')[0];
+  const consumer = appConfig.mtlsConsumers[presented.subject] || appConfig.mtlsConsumers[commonName];
+  
+  return consumer
+    ? { consumer: { ...consumer, certificateSubject: presented.subject } }
+    : { error: 'Client certificate is not mapped to a consumer' };
+};
+
+// src/docs/openapi.js
+// Response bodies reference component schemas by name
+const json = (description, schema) => ({
+  description,
+  content: { 'application/json': { schema: { $ref: `#/components/schemas/${schema}` } } }
+});
+
+const message = (description) => json(description, 'Message');
+
+module.exports = {
+  openapi: '3.0.3',
+  info: {
+    title: 'SWIFT Code Management API',
+    version: '1.0.0'
+  },
+  paths: {
+    '/v1/swift-codes': {
+      post: {
+        responses: {
+          201: json('SWIFT code created, Location header points at it', 'CreatedSwiftCode'),
+          400: json('Invalid payload', 'ValidationError'),
+          403: message('Record ceiling reached'),
+          409: message('SWIFT code already exists')
+        }
+      }
+    },
+    '/v1/swift-codes/bulk': {
+      post: {
+        responses: {
+          200: json('Every item was created', 'BulkCreateResult'),
+          207: json('Per-item creation results, at least one item failed', 'BulkCreateResult'),
+          400: message('Invalid payload'),
+          403: message('Record ceiling reached')
+        }
+      },
+      delete: {
+        responses: {
+          200: json('Deleted document counts per criterion', 'BulkDeleteResult'),
+          400: message('Invalid payload or missing confirmation'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions'),
+          423: json('Some of the records are locked by other stewards', 'EditLocks')
+        }
+      }
+    },
+    '/v1/swift-codes/lookup': {
+      post: {
+        responses: {
+          200: json('Details keyed by SWIFT code, every code found', 'LookupResult'),
+          207: json('Details keyed by SWIFT code, at least one code not found', 'LookupResult'),
+          400: message('Invalid payload')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}/assignments': {
+      get: {
+        responses: {
+          200: json('Institutions that held the code over time, or with ?at= the one holding it then', 'BicAssignments'),
+          400: json('Invalid date', 'ValidationError'),
+          404: message('SWIFT code not found, or nobody held it on that date')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}/reassign': {
+      post: {
+        responses: {
+          200: json('Code handed over to the new institution', 'Reassignment'),
+          400: json('Invalid holder or effective date', 'ValidationError'),
+          404: message('SWIFT code not found'),
+          409: message('Effective date overlaps the current holder, or the record is locked')
+        }
+      }
+    },
+    '/v1/swift-codes/validate/stream': {
+      post: {
+        responses: {
+          // application/x-ndjson, one BulkValidationResult item per input line, written as computed
+          200: { description: 'Stream of verdicts, one NDJSON line per code of the request stream' },
+          401: message('Authentication required'),
+          415: message('Body is not application/x-ndjson or text/plain')
+        }
+      }
+    },
+    '/v1/swift-codes/validate': {
+      post: {
+        responses: {
+          200: json('ISO 9362 validation verdict, one per code for swiftCodes', 'ValidationResponse'),
+          207: json('Per-code verdicts, at least one code invalid', 'BulkValidationResult'),
+          400: message('Invalid payload')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}': {
+      get: {
+        responses: {
+          200: json('SWIFT code details; a BIC8 resolves to its headquarter', 'SwiftCodeDetails'),
+          404: message('SWIFT code not found'),
+          409: json('BIC8 without a single headquarter, with the codes it could mean', 'AmbiguousBic8')
+        }
+      },
+      delete: {
+        responses: {
+          200: message('SWIFT code deleted'),
+          204: { description: 'SWIFT code was already deleted (Idempotency-Key header or idempotent=true)' },
+          404: message('SWIFT code not found'),
+          409: json('BIC8 without a single headquarter, with the codes it could mean', 'AmbiguousBic8'),
+          423: json('Record is locked by another steward', 'EditLock')
+        }
+      },
+      patch: {
+        responses: {
+          200: json('Record with its updated names', 'SwiftCodeRecordV2'),
+          400: message('Invalid or non-patchable fields'),
+          404: message('SWIFT code not found'),
+          423: json('Record is locked by another steward', 'EditLock')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}/restore': {
+      post: {
+        responses: {
+          200: message('SWIFT code restored'),
+          404: message('No deleted SWIFT code found'),
+          423: json('Record is locked by another steward', 'EditLock')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}/deactivate': {
+      post: {
+        responses: {
+          200: json('SWIFT code deactivated', 'StatusChange'),
+          400: message('Missing reason'),
+          404: message('SWIFT code not found'),
+          423: json('Record is locked by another steward', 'EditLock')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}/activate': {
+      post: {
+        responses: {
+          200: json('SWIFT code activated', 'StatusChange'),
+          400: message('Invalid reason'),
+          404: message('SWIFT code not found'),
+          423: json('Record is locked by another steward', 'EditLock')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}/lock': {
+      post: {
+        responses: {
+          200: json('Lock acquired or renewed by its holder', 'EditLock'),
+          401: message('Authentication required'),
+          404: message('SWIFT code not found'),
+          423: json('Record is locked by another steward', 'EditLock')
+        }
+      },
+      put: {
+        responses: {
+          200: json('Lock extended', 'EditLock'),
+          401: message('Authentication required'),
+          404: message('No active lock held on this record'),
+          423: json('Record is locked by another steward', 'EditLock')
+        }
+      },
+      delete: {
+        responses: {
+          200: message('Lock released'),
+          401: message('Authentication required'),
+          404: message('No lock held on this record')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}/history': {
+      get: {
+        responses: {
+          200: json('Revisions of the record, newest first', 'SwiftCodeHistory'),
+          404: message('SWIFT code not found')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}/history/diff': {
+      get: {
+        responses: {
+          200: json('Fields that differ between two versions of the record', 'RevisionDiff'),
+          400: json('Missing or invalid from/to version', 'ValidationError'),
+          404: message('Revision not found')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}/branches': {
+      get: {
+        responses: {
+          200: json('Page of branches of the headquarter', 'BranchPage'),
+          404: message('Headquarter SWIFT code not found')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}/siblings': {
+      get: {
+        responses: {
+          200: json('Page of other codes of the institution in the same country, headquarter first', 'SiblingPage'),
+          400: message('Invalid status'),
+          404: message('SWIFT code not found')
+        }
+      }
+    },
+    '/v1/swift-codes/{swiftCode}/headquarter': {
+      get: {
+        responses: {
+          200: json('Headquarter record of the code', 'SwiftCodeDetails'),
+          400: message('Invalid SWIFT code length'),
+          404: message('Headquarter not found')
+        }
+      }
+    },
+    '/v1/swift-codes/country/{countryISO2}': {
+      get: {
+        responses: {
+          200: json('SWIFT codes registered in the country, optionally grouped by city', 'CountryListing'),
+          400: json('Invalid sort option, type, status, connectivity or groupBy', 'ValidationError'),
+          404: message('Country not found')
+        }
+      }
+    },
+    '/v1/swift-codes/city/{city}': {
+      get: {
+        responses: {
+          200: json('SWIFT codes located in the city', 'CitySwiftCodes'),
+          400: message('Invalid countryISO2 or status'),
+          404: message('No SWIFT codes found in this city')
+        }
+      }
+    },
+    '/v1/swift-codes/lei/{lei}': {
+      get: {
+        responses: {
+          200: json('SWIFT codes mapped to the LEI, headquarter first', 'LeiSwiftCodes'),
+          400: message('Invalid LEI'),
+          404: message('No SWIFT codes found for this LEI')
+        }
+      }
+    },
+    '/v1/swift-codes/national/{scheme}/{value}': {
+      get: {
+        responses: {
+          200: json('SWIFT codes carrying the national clearing id', 'NationalIdSwiftCodes'),
+          400: message('Unknown scheme or malformed identifier'),
+          404: message('No SWIFT codes found for this national id')
+        }
+      }
+    },
+    '/v1/swift-codes/search': {
+      get: {
+        responses: {
+          200: json('Page of SWIFT codes whose primary or localized bank name starts with q', 'BankNameSearchResults'),
+          400: json('Invalid query, countryISO2 or status', 'ValidationError')
+        }
+      }
+    },
+    '/v1/swift-codes/near': {
+      get: {
+        responses: {
+          200: json('SWIFT codes within radiusKm of the point, nearest first', 'NearbySwiftCodes'),
+          400: message('Invalid coordinates, radius or status')
+        }
+      }
+    },
+    '/v1/swift-codes/count': {
+      get: {
+        responses: {
+          200: json('Number of SWIFT codes matching the filters', 'Count'),
+          400: json('Invalid filter', 'ValidationError')
+        }
+      }
+    },
+    '/v1/swift-codes/bank/{bic8}': {
+      get: {
+        responses: {
+          200: json('Every SWIFT code of the institution, headquarter first', 'InstitutionSwiftCodes'),
+          400: message('Invalid BIC8 or status'),
+          404: message('Bank not found')
+        }
+      }
+    },
+    '/v1/swift-codes/bank/{bic8}/catalogue': {
+      get: {
+        responses: {
+          200: json('Branch catalogue of the institution', 'BankCatalogue'),
+          400: message('Invalid BIC8'),
+          404: message('Bank not found')
+        }
+      }
+    },
+    '/v1/banks': {
+      get: {
+        responses: {
+          200: json('Page of banks ordered by BIC8', 'BankPage'),
+          400: message('Invalid countryISO2')
+        }
+      },
+      post: {
+        responses: {
+          201: json('Bank created', 'CreatedBank'),
+          400: message('Invalid bank'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions'),
+          409: message('Bank already exists')
+        }
+      }
+    },
+    '/v1/banks/{bic4}': {
+      get: {
+        responses: {
+          200: json('Countries and codes of the institution, aggregated by bank code', 'Institution'),
+          400: message('Invalid status'),
+          404: message('Institution not found')
+        }
+      }
+    },
+    '/v1/banks/{bic8}': {
+      get: {
+        responses: {
+          200: json('Bank with its headquarter and branch codes', 'Bank'),
+          400: message('Invalid BIC8'),
+          404: message('Bank not found')
+        }
+      },
+      patch: {
+        responses: {
+          200: json('Renamed bank; its SWIFT codes carry the new name', 'Bank'),
+          400: message('Invalid name'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions'),
+          404: message('Bank not found'),
+          423: json('Some of the bank\'s codes are locked by other stewards', 'EditLocks')
+        }
+      },
+      delete: {
+        responses: {
+          200: message('Bank deleted'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions'),
+          404: message('Bank not found'),
+          409: message('Bank still has SWIFT codes')
+        }
+      }
+    },
+    '/v1/banks/{bankName}/swift-codes': {
+      get: {
+        responses: {
+          200: json('SWIFT codes registered under the institution name', 'BankNameSwiftCodes'),
+          400: message('Invalid bank name or status'),
+          404: message('Bank not found')
+        }
+      }
+    },
+    '/v1/keys': {
+      get: {
+        responses: {
+          200: json('API keys of the calling consumer, newest first', 'ApiKeyList'),
+          401: message('Authentication required')
+        }
+      },
+      post: {
+        responses: {
+          201: json('New API key, shown only once', 'IssuedApiKey'),
+          400: message('Invalid label'),
+          401: message('Authentication required'),
+          403: message('API key limit reached')
+        }
+      }
+    },
+    '/v1/keys/{id}/rotate': {
+      post: {
+        responses: {
+          201: json('Replacement key; the old one works until previousKeyExpiresAt', 'IssuedApiKey'),
+          401: message('Authentication required'),
+          404: message('API key not found')
+        }
+      }
+    },
+    '/v1/keys/{id}': {
+      delete: {
+        responses: {
+          200: message('API key revoked'),
+          401: message('Authentication required'),
+          404: message('API key not found')
+        }
+      }
+    },
+    '/v1/meta/error-codes': {
+      get: {
+        responses: {
+          200: json('Every machine-readable error code with its HTTP status', 'ErrorCodeCatalogue')
+        }
+      }
+    },
+    '/v1/admin/imports': {
+      post: {
+        responses: {
+          200: json('Summary of the import, or with dryRun=true what it would do', 'ImportResult'),
+          400: json('Missing file, invalid mapping or invalid options', 'ValidationError'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions'),
+          409: message('An import is already running')
+        }
+      }
+    },
+    '/v1/admin/imports/schedule': {
+      get: {
+        responses: {
+          200: json('Scheduled re-import configuration and the outcome of its last run', 'ImportSchedule'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/admin/imports/preview': {
+      post: {
+        responses: {
+          200: json('First rows of the upload mapped to records, with their issues', 'ImportPreview'),
+          400: message('Missing file or invalid mapping'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/admin/imports/{id}/rejects': {
+      get: {
+        responses: {
+          200: json('Page of rows the import rejected, in file order', 'ImportRejectPage'),
+          400: json('Invalid reason or status', 'ValidationError'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions'),
+          404: message('Import run not found')
+        }
+      }
+    },
+    '/v1/admin/imports/{id}/changeset': {
+      get: {
+        responses: {
+          200: json('Codes a delta import added, modified and removed', 'ImportChangeset'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions'),
+          404: message('Delta import run not found')
+        }
+      }
+    },
+    '/v1/admin/imports/{id}/rejects/{rejectId}/resubmit': {
+      post: {
+        responses: {
+          201: json('Corrected row stored as a SWIFT code', 'ResubmittedReject'),
+          400: json('Invalid corrections, or the corrected row is still invalid', 'ValidationError'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions'),
+          404: message('Import reject not found'),
+          409: message('Reject already resubmitted or SWIFT code already exists')
+        }
+      }
+    },
+    '/v1/admin/countries': {
+      get: {
+        responses: {
+          200: json('Country registry: the built-in ISO 3166-1 list with the changes made through the API', 'CountryRegistry'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/admin/countries/{countryISO2}': {
+      put: {
+        responses: {
+          200: json('Country renamed, withdrawn or reinstated', 'CountryEntry'),
+          201: json('Newly assigned country code added', 'CountryEntry'),
+          400: json('Invalid code or body, or a new code without name', 'ValidationError'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      },
+      delete: {
+        responses: {
+          200: message('Country back to its built-in entry, or removed if added through the API'),
+          400: json('Invalid country code', 'ValidationError'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions'),
+          404: message('No registry change for this country code'),
+          409: message('Country code is still used by SWIFT codes')
+        }
+      }
+    },
+    '/v1/admin/config/reload': {
+      post: {
+        responses: {
+          200: json('Reloadable settings changed by the config file', 'ConfigReload'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/admin/maintenance/normalize': {
+      get: {
+        responses: {
+          200: json('State of the latest normalization run on this instance', 'NormalizationState'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      },
+      post: {
+        responses: {
+          202: json('Normalization started in the background', 'NormalizationState'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions'),
+          409: json('A normalization run is still going', 'NormalizationState')
+        }
+      }
+    },
+    '/v1/admin/maintenance/smoke-test': {
+      post: {
+        responses: {
+          200: json('Latencies of the main read paths and the invariants they violated', 'SmokeTestReport'),
+          400: json('Invalid sample size', 'ValidationError'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/admin/reports/orphan-branches': {
+      get: {
+        responses: {
+          200: json('Page of branches whose BIC8 + XXX headquarter is missing', 'OrphanBranchReport'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/admin/dashboard/recent-changes': {
+      get: {
+        responses: {
+          200: json('Latest record changes with the last day broken down by action', 'RecentChanges'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/admin/dashboard/pending-approvals': {
+      get: {
+        responses: {
+          200: json('Pending records and open import rejects awaiting a steward', 'PendingApprovals'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/admin/dashboard/failing-imports': {
+      get: {
+        responses: {
+          200: json('Latest imports that failed or rejected rows, and the scheduled import when its last run failed', 'FailingImports'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/admin/dashboard/quality-offenders': {
+      get: {
+        responses: {
+          200: json('Institutions with the most data-quality issues', 'QualityOffenders'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/admin/audit-log': {
+      get: {
+        responses: {
+          200: json('Page of audit log entries, newest first (text/csv with format=csv)', 'AuditLogPage'),
+          400: message('Invalid filter'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/admin/siem/events': {
+      get: {
+        responses: {
+          // application/x-ndjson, one SiemEvent per line, oldest first
+          200: { description: 'Audit entries and record changes in the stable SIEM event layout' },
+          400: json('Invalid date or event type', 'ValidationError'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions')
+        }
+      }
+    },
+    '/v1/events/mergers': {
+      get: {
+        responses: {
+          200: json('Page of merger and transfer events, oldest first', 'MergerEventPage'),
+          400: json('Invalid date or BIC filter', 'ValidationError')
+        }
+      },
+      post: {
+        responses: {
+          201: json('Event recorded and the absorbed codes deprecated', 'RecordedMergerEvent'),
+          400: json('Invalid event', 'ValidationError'),
+          401: message('Authentication required'),
+          403: message('Insufficient permissions'),
+          404: message('A BIC of the event has no SWIFT codes')
+        }
+      }
+    },
+    '/v1/iban/resolve': {
+      post: {
+        responses: {
+          200: json('Bank code of the IBAN and the SWIFT record it maps to', 'IbanResolution'),
+          400: json('Invalid or unsupported IBAN', 'ValidationError'),
+          404: message('No SWIFT code found for the bank of this IBAN')
+        }
+      }
+    },
+    '/v2/swift-codes/{swiftCode}': {
+      get: {
+        responses: {
+          200: json('SWIFT code details in the canonical shape', 'SwiftCodeDetailsV2'),
+          404: message('SWIFT code not found'),
+          409: json('BIC8 without a single headquarter, with the codes it could mean', 'AmbiguousBic8')
+        }
+      }
+    },
+    '/v2/swift-codes/country/{countryISO2}': {
+      get: {
+        responses: {
+          200: json('SWIFT codes registered in the country, canonical shape', 'CountrySwiftCodesV2'),
+          400: json('Invalid sort option, type, status or connectivity', 'ValidationError'),
+          404: message('Country not found')
+        }
+      }
+    }
+  },
+  components: {
+    schemas: {
+      Message: {
+        type: 'object',
+        required: ['message'],
+        properties: {
+          message: { type: 'string' }
+        }
+      },
+      ValidationError: {
+        allOf: [
+          { $ref: '#/components/schemas/Message' },
+          {
+            type: 'object',
+            properties: {
+              // Field-level problems from request validation, or data-quality rejections
+              errors: {
+                type: 'array',
+                items: {
+                  type: 'object',
+                  required: ['message'],
+                  properties: {
+                    location: { type: 'string', enum: ['params', 'query', 'body'] },
+                    field: { type: 'string' },
+                    code: { type: 'string' },
+                    message: { type: 'string' }
+                  }
+                }
+              }
+            }
+          }
+        ]
+      },
+      BranchRecord: {
+        type: 'object',
+        required: ['address', 'bankName', 'countryISO2', 'isHeadquarter', 'swiftCode'],
+        properties: {
+          address: { type: 'string' },
+          bankName: { type: 'string' },
+          countryISO2: { type: 'string', minLength: 2, maxLength: 2 },
+          isHeadquarter: { type: 'boolean' },
+          swiftCode: { type: 'string', minLength: 8, maxLength: 11 },
+          createdAt: { type: 'string' },
+          updatedAt: { type: 'string' }
+        }
+      },
+      SwiftCodeDetails: {
+        type: 'object',
+        required: ['address', 'bankName', 'countryISO2', 'countryName', 'isHeadquarter', 'swiftCode'],
+        properties: {
+          address: { type: 'string' },
+          bankName: { type: 'string' },
+          countryISO2: { type: 'string', minLength: 2, maxLength: 2 },
+          countryName: { type: 'string' },
+          isHeadquarter: { type: 'boolean' },
+          swiftCode: { type: 'string', minLength: 8, maxLength: 11 },
+          status: { type: 'string', enum: ['active', 'inactive', 'pending', 'retired'] },
+          connectivityStatus: { type: 'string', enum: ['connected', 'not-connected', 'test', 'reverse-billing'] },
+          statusReason: { type: 'string' },
+          externalIds: { type: 'object', additionalProperties: { type: 'string' } },
+          isTemporary: { type: 'boolean' },
+          expiresAt: { type: 'string' },
+          deprecated: { type: 'boolean' },
+          successor: { type: 'string' },
+          fallbackApplied: { type: 'boolean' },
+          requestedSwiftCode: { type: 'string' },
+          createdAt: { type: 'string' },
+          updatedAt: { type: 'string' },
+          branches: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/BranchRecord' }
+          },
+          branchPagination: { $ref: '#/components/schemas/PageInfo' }
+        }
+      },
+      SwiftCodeRecordV2: {
+        type: 'object',
+        required: ['swiftCode', 'bankName', 'address', 'countryISO2', 'countryName', 'isHeadquarter'],
+        properties: {
+          swiftCode: { type: 'string', minLength: 8, maxLength: 11 },
+          bic8: { type: 'string', minLength: 8, maxLength: 8 },
+          bankName: { type: 'string' },
+          address: { type: 'string' },
+          countryISO2: { type: 'string', minLength: 2, maxLength: 2 },
+          countryName: { type: 'string' },
+          isHeadquarter: { type: 'boolean' },
+          localizedNames: { type: 'object', additionalProperties: { type: 'string' } },
+          originalAddress: { type: 'string' },
+          city: { type: 'string' },
+          lei: { type: 'string' },
+          nationalIds: { type: 'array', items: { $ref: '#/components/schemas/NationalId' } },
+          latitude: { type: 'number' },
+          longitude: { type: 'number' },
+          branchDescription: { type: 'string' },
+          externalIds: { type: 'object', additionalProperties: { type: 'string' } },
+          isTemporary: { type: 'boolean' },
+          expiresAt: { type: 'string' },
+          deprecated: { type: 'boolean' },
+          successor: { type: 'string' },
+          status: { type: 'string', enum: ['active', 'inactive', 'pending', 'retired'] },
+          connectivityStatus: { type: 'string', enum: ['connected', 'not-connected', 'test', 'reverse-billing'] },
+          statusReason: { type: 'string' },
+          createdAt: { type: 'string' },
+          updatedAt: { type: 'string' }
+        }
+      },
+      BicAssignment: {
+        type: 'object',
+        required: ['holder', 'validFrom', 'validTo'],
+        properties: {
+          holder: {
+            type: 'object',
+            properties: {
+              bankName: { type: 'string' },
+              address: { type: 'string' },
+              city: { type: 'string' },
+              countryISO2: { type: 'string' },
+              countryName: { type: 'string' },
+              lei: { type: 'string' }
+            }
+          },
+          // Null before the first recorded reassignment, or for the current holder respectively
+          validFrom: { type: 'string', nullable: true },
+          validTo: { type: 'string', nullable: true }
+        }
+      },
+      BicAssignments: {
+        type: 'object',
+        required: ['swiftCode'],
+        properties: {
+          swiftCode: { type: 'string' },
+          assignments: { type: 'array', items: { $ref: '#/components/schemas/BicAssignment' } },
+          at: { type: 'string' },
+          assignment: { $ref: '#/components/schemas/BicAssignment' }
+        }
+      },
+      Reassignment: {
+        type: 'object',
+        required: ['message', 'record', 'assignments'],
+        properties: {
+          message: { type: 'string' },
+          record: { $ref: '#/components/schemas/SwiftCodeRecordV2' },
+          assignments: { type: 'array', items: { $ref: '#/components/schemas/BicAssignment' } }
+        }
+      },
+      StatusChange: {
+        type: 'object',
+        required: ['message', 'record'],
+        properties: {
+          message: { type: 'string' },
+          record: { $ref: '#/components/schemas/SwiftCodeRecordV2' }
+        }
+      },
+      IbanResolution: {
+        type: 'object',
+        required: ['iban', 'countryISO2', 'bankCode', 'branchCode', 'resolvedBy', 'record'],
+        properties: {
+          iban: { type: 'string' },
+          countryISO2: { type: 'string', minLength: 2, maxLength: 2 },
+          bankCode: { type: 'string' },
+          branchCode: { type: 'string', nullable: true },
+          resolvedBy: { type: 'string', enum: ['national-id', 'bank-code', 'mapping-table'] },
+          record: { $ref: '#/components/schemas/SwiftCodeRecordV2' }
+        }
+      },
+      RecentChanges: {
+        type: 'object',
+        required: ['since', 'changeCount', 'byAction', 'changes'],
+        properties: {
+          since: { type: 'string' },
+          changeCount: { type: 'integer' },
+          byAction: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['action', 'count'],
+              properties: { action: { type: 'string' }, count: { type: 'integer' } }
+            }
+          },
+          changes: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['swiftCode', 'version', 'action', 'actor', 'at'],
+              properties: {
+                swiftCode: { type: 'string' },
+                version: { type: 'integer' },
+                action: { type: 'string' },
+                actor: { type: 'string' },
+                at: { type: 'string' }
+              }
+            }
+          }
+        }
+      },
+      PendingApprovals: {
+        type: 'object',
+        required: ['pendingRecordCount', 'pendingRecords', 'openRejectCount', 'openRejectsByImport'],
+        properties: {
+          pendingRecordCount: { type: 'integer' },
+          pendingRecords: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['swiftCode', 'bankName', 'countryISO2', 'statusReason', 'statusChangedAt'],
+              properties: {
+                swiftCode: { type: 'string' },
+                bankName: { type: 'string' },
+                countryISO2: { type: 'string' },
+                statusReason: { type: 'string', nullable: true },
+                statusChangedAt: { type: 'string', nullable: true }
+              }
+            }
+          },
+          openRejectCount: { type: 'integer' },
+          openRejectsByImport: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['importRunId', 'source', 'completedAt', 'count'],
+              properties: {
+                importRunId: { type: 'string' },
+                source: { type: 'string', nullable: true },
+                completedAt: { type: 'string', nullable: true },
+                count: { type: 'integer' }
+              }
+            }
+          }
+        }
+      },
+      FailingImports: {
+        type: 'object',
+        required: ['scheduledImport', 'imports'],
+        properties: {
+          // Last run of the scheduled import, only when it failed
+          scheduledImport: {
+            type: 'object',
+            nullable: true,
+            required: ['status', 'error'],
+            properties: {
+              status: { type: 'string', enum: ['failed'] },
+              source: { type: 'string' },
+              startedAt: { type: 'string' },
+              error: { type: 'string' }
+            }
+          },
+          imports: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['importRunId', 'status', 'error', 'trigger', 'source', 'mode', 'completedAt', 'recordCount', 'rejectedCount', 'openRejectCount'],
+              properties: {
+                importRunId: { type: 'string' },
+                status: { type: 'string', enum: ['completed', 'failed'] },
+                error: { type: 'string', nullable: true },
+                trigger: { type: 'string', nullable: true },
+                source: { type: 'string', nullable: true },
+                mode: { type: 'string', nullable: true, enum: ['replace', 'upsert', 'delta', null] },
+                completedAt: { type: 'string' },
+                recordCount: { type: 'integer', nullable: true },
+                rejectedCount: { type: 'integer' },
+                openRejectCount: { type: 'integer' }
+              }
+            }
+          }
+        }
+      },
+      QualityOffenders: {
+        type: 'object',
+        required: ['offenders'],
+        properties: {
+          offenders: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['bic8', 'bankName', 'codeCount', 'issueCount', 'issues'],
+              properties: {
+                bic8: { type: 'string' },
+                bankName: { type: 'string' },
+                // Codes of the institution with at least one issue
+                codeCount: { type: 'integer' },
+                issueCount: { type: 'integer' },
+                issues: {
+                  type: 'object',
+                  required: ['COUNTRY_MISMATCH', 'HEADQUARTER_MISMATCH', 'UNKNOWN_COUNTRY'],
+                  additionalProperties: { type: 'integer' }
+                }
+              }
+            }
+          }
+        }
+      },
+      OrphanBranchReport: {
+        type: 'object',
+        required: ['missingHeadquarterCount', 'branches', 'pagination'],
+        properties: {
+          missingHeadquarterCount: { type: 'integer' },
+          branches: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['swiftCode', 'bankName', 'countryISO2', 'expectedHeadquarter'],
+              properties: {
+                swiftCode: { type: 'string' },
+                bankName: { type: 'string' },
+                countryISO2: { type: 'string' },
+                expectedHeadquarter: { type: 'string' }
+              }
+            }
+          },
+          pagination: { $ref: '#/components/schemas/PageInfo' }
+        }
+      },
+      ImportReject: {
+        type: 'object',
+        required: ['id', 'line', 'record', 'reasons', 'status', 'resubmittedAt', 'resubmittedBy', 'resubmittedSwiftCode'],
+        properties: {
+          id: { type: 'string' },
+          line: { type: 'integer' },
+          record: { type: 'object' },
+          reasons: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['code', 'field', 'message'],
+              properties: {
+                code: { type: 'string', enum: ['MISSING_FIELD', 'INVALID_FORMAT', 'UNKNOWN_COUNTRY', 'INVALID_RECORD', 'DUPLICATE_IN_FILE'] },
+                field: { type: 'string' },
+                message: { type: 'string' }
+              }
+            }
+          },
+          status: { type: 'string', enum: ['open', 'resubmitted'] },
+          resubmittedAt: { type: 'string', nullable: true },
+          resubmittedBy: { type: 'string', nullable: true },
+          resubmittedSwiftCode: { type: 'string', nullable: true }
+        }
+      },
+      ImportRejectPage: {
+        type: 'object',
+        required: ['importRunId', 'reasonCounts', 'rejects', 'pagination'],
+        properties: {
+          importRunId: { type: 'string' },
+          reasonCounts: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['code', 'count'],
+              properties: {
+                code: { type: 'string' },
+                count: { type: 'integer' }
+              }
+            }
+          },
+          rejects: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/ImportReject' }
+          },
+          pagination: { $ref: '#/components/schemas/PageInfo' }
+        }
+      },
+      ResubmittedReject: {
+        type: 'object',
+        required: ['message', 'reject', 'record', 'warnings'],
+        properties: {
+          message: { type: 'string' },
+          reject: { $ref: '#/components/schemas/ImportReject' },
+          record: { $ref: '#/components/schemas/SwiftCodeRecordV2' },
+          warnings: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/Warning' }
+          }
+        }
+      },
+      MergerEvent: {
+        type: 'object',
+        required: ['id', 'type', 'effectiveDate', 'absorbingBic', 'absorbedBics', 'affectedCodes'],
+        properties: {
+          id: { type: 'string' },
+          type: { type: 'string', enum: ['merger', 'transfer'] },
+          effectiveDate: { type: 'string' },
+          absorbingBic: { type: 'string' },
+          absorbedBics: { type: 'array', items: { type: 'string' } },
+          affectedCodes: { type: 'array', items: { type: 'string' } },
+          notes: { type: 'string' },
+          recordedBy: { type: 'string' },
+          createdAt: { type: 'string' }
+        }
+      },
+      MergerEventPage: {
+        type: 'object',
+        required: ['events', 'pagination'],
+        properties: {
+          events: { type: 'array', items: { $ref: '#/components/schemas/MergerEvent' } },
+          pagination: { $ref: '#/components/schemas/PageInfo' }
+        }
+      },
+      RecordedMergerEvent: {
+        type: 'object',
+        required: ['message', 'event'],
+        properties: {
+          message: { type: 'string' },
+          event: { $ref: '#/components/schemas/MergerEvent' }
+        }
+      },
+      CountryEntry: {
+        type: 'object',
+        required: ['countryISO2', 'name', 'builtIn', 'withdrawn'],
+        properties: {
+          countryISO2: { type: 'string', minLength: 2, maxLength: 2 },
+          name: { type: 'string' },
+          // False for codes added through the API
+          builtIn: { type: 'boolean' },
+          withdrawn: { type: 'boolean' },
+          withdrawnAt: { type: 'string' }
+        }
+      },
+      CountryRegistry: {
+        type: 'object',
+        required: ['countries'],
+        properties: {
+          countries: { type: 'array', items: { $ref: '#/components/schemas/CountryEntry' } }
+        }
+      },
+      ConfigReload: {
+        type: 'object',
+        required: ['message', 'reloadedAt', 'changed'],
+        properties: {
+          message: { type: 'string' },
+          reloadedAt: { type: 'string' },
+          changed: { type: 'array', items: { type: 'string' } }
+        }
+      },
+      // scanned, corrected and conflicts are set once completed, error once failed
+      NormalizationState: {
+        type: 'object',
+        required: ['status'],
+        properties: {
+          message: { type: 'string' },
+          status: { type: 'string', enum: ['idle', 'running', 'completed', 'failed'] },
+          actor: { type: 'string' },
+          startedAt: { type: 'string', format: 'date-time' },
+          completedAt: { type: 'string', format: 'date-time' },
+          error: { type: 'string' },
+          scanned: { type: 'integer' },
+          corrected: { type: 'integer' },
+          // Codes left alone because their canonical form belongs to another record
+          conflicts: {
+            type: 'array',
+            items: { type: 'string' }
+          }
+        }
+      },
+      AmbiguousBic8: {
+        allOf: [
+          { $ref: '#/components/schemas/Message' },
+          {
+            type: 'object',
+            required: ['candidates'],
+            properties: {
+              candidates: {
+                type: 'array',
+                items: { type: 'string' }
+              }
+            }
+          }
+        ]
+      },
+      RevisionDiff: {
+        type: 'object',
+        required: ['swiftCode', 'from', 'to', 'changes'],
+        properties: {
+          swiftCode: { type: 'string' },
+          from: { type: 'integer' },
+          to: { type: 'integer' },
+          changes: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['field', 'from', 'to'],
+              properties: {
+                field: { type: 'string' },
+                // Any JSON value, null when the field is absent from that version
+                from: {},
+                to: {}
+              }
+            }
+          }
+        }
+      },
+      SmokeTestReport: {
+        type: 'object',
+        required: ['startedAt', 'durationMs', 'sampledCodes', 'passed', 'paths', 'mismatches'],
+        properties: {
+          startedAt: { type: 'string' },
+          durationMs: { type: 'integer' },
+          sampledCodes: { type: 'integer' },
+          passed: { type: 'boolean' },
+          paths: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['path', 'samples', 'p50Ms', 'p95Ms', 'maxMs'],
+              properties: {
+                path: { type: 'string' },
+                samples: { type: 'integer' },
+                p50Ms: { type: 'number', nullable: true },
+                p95Ms: { type: 'number', nullable: true },
+                maxMs: { type: 'number', nullable: true }
+              }
+            }
+          },
+          mismatches: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['path', 'swiftCode', 'message'],
+              properties: {
+                path: { type: 'string' },
+                swiftCode: { type: 'string' },
+                message: { type: 'string' }
+              }
+            }
+          }
+        }
+      },
+      CreatedSwiftCode: {
+        type: 'object',
+        required: ['message', 'record', 'warnings'],
+        properties: {
+          message: { type: 'string' },
+          record: { $ref: '#/components/schemas/SwiftCodeRecordV2' },
+          warnings: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/Warning' }
+          }
+        }
+      },
+      // Data-quality issue the active strictness profile accepted instead of rejecting
+      Warning: {
+        type: 'object',
+        required: ['code', 'field', 'message'],
+        properties: {
+          code: { type: 'string', enum: ['UNKNOWN_COUNTRY', 'COUNTRY_MISMATCH', 'HEADQUARTER_MISMATCH'] },
+          field: { type: 'string' },
+          message: { type: 'string' }
+        }
+      },
+      SwiftCodeDetailsV2: {
+        allOf: [
+          { $ref: '#/components/schemas/SwiftCodeRecordV2' },
+          {
+            type: 'object',
+            properties: {
+              fallbackApplied: { type: 'boolean' },
+              requestedSwiftCode: { type: 'string' },
+              branches: {
+                type: 'array',
+                items: { $ref: '#/components/schemas/SwiftCodeRecordV2' }
+              },
+              branchPagination: { $ref: '#/components/schemas/PageInfo' }
+            }
+          }
+        ]
+      },
+      CountrySwiftCodesV2: {
+        type: 'object',
+        required: ['countryISO2', 'countryName', 'swiftCodes', 'meta'],
+        properties: {
+          countryISO2: { type: 'string', minLength: 2, maxLength: 2 },
+          countryName: { type: 'string' },
+          swiftCodes: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/SwiftCodeRecordV2' }
+          },
+          meta: { $ref: '#/components/schemas/CountryListingMeta' }
+        }
+      },
+      CountryListingMeta: {
+        type: 'object',
+        required: ['totalCount', 'headquarterCount', 'branchCount'],
+        properties: {
+          totalCount: { type: 'integer' },
+          headquarterCount: { type: 'integer' },
+          branchCount: { type: 'integer' },
+          page: { type: 'integer' },
+          limit: { type: 'integer' },
+          totalPages: { type: 'integer' },
+          defaultLimit: { type: 'integer' },
+          maxLimit: { type: 'integer' }
+        }
+      },
+      PageInfo: {
+        type: 'object',
+        required: ['page', 'limit', 'totalCount', 'totalPages'],
+        properties: {
+          page: { type: 'integer' },
+          limit: { type: 'integer' },
+          totalCount: { type: 'integer' },
+          totalPages: { type: 'integer' },
+          // Configured page sizes of the endpoint
+          defaultLimit: { type: 'integer' },
+          maxLimit: { type: 'integer' }
+        }
+      },
+      BranchPage: {
+        type: 'object',
+        required: ['swiftCode', 'branches', 'pagination'],
+        properties: {
+          swiftCode: { type: 'string' },
+          branches: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/BranchRecord' }
+          },
+          pagination: { $ref: '#/components/schemas/PageInfo' }
+        }
+      },
+      SiblingPage: {
+        type: 'object',
+        required: ['swiftCode', 'siblings', 'pagination'],
+        properties: {
+          swiftCode: { type: 'string' },
+          siblings: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/BranchRecord' }
+          },
+          pagination: { $ref: '#/components/schemas/PageInfo' }
+        }
+      },
+      CountrySwiftCodes: {
+        type: 'object',
+        required: ['countryISO2', 'countryName', 'swiftCodes', 'meta'],
+        properties: {
+          countryISO2: { type: 'string', minLength: 2, maxLength: 2 },
+          countryName: { type: 'string' },
+          swiftCodes: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/BranchRecord' }
+          },
+          meta: { $ref: '#/components/schemas/CountryListingMeta' }
+        }
+      },
+      LeiSwiftCodes: {
+        type: 'object',
+        required: ['lei', 'swiftCodes'],
+        properties: {
+          lei: { type: 'string', minLength: 20, maxLength: 20 },
+          swiftCodes: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/BranchRecord' }
+          }
+        }
+      },
+      BankNameSearchResults: {
+        type: 'object',
+        required: ['query', 'swiftCodes', 'pagination'],
+        properties: {
+          query: { type: 'string' },
+          swiftCodes: {
+            type: 'array',
+            items: {
+              allOf: [{ $ref: '#/components/schemas/BranchRecord' }],
+              properties: {
+                localizedNames: { type: 'object', additionalProperties: { type: 'string' } }
+              }
+            }
+          },
+          pagination: { $ref: '#/components/schemas/PageInfo' }
+        }
+      },
+      NationalId: {
+        type: 'object',
+        required: ['scheme', 'value'],
+        properties: {
+          scheme: { type: 'string' },
+          value: { type: 'string' }
+        }
+      },
+      NationalIdSwiftCodes: {
+        type: 'object',
+        required: ['scheme', 'value', 'swiftCodes'],
+        properties: {
+          scheme: { type: 'string' },
+          value: { type: 'string' },
+          swiftCodes: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/BranchRecord' }
+          }
+        }
+      },
+      NearbySwiftCodes: {
+        type: 'object',
+        required: ['latitude', 'longitude', 'radiusKm', 'swiftCodes'],
+        properties: {
+          latitude: { type: 'number' },
+          longitude: { type: 'number' },
+          radiusKm: { type: 'number' },
+          swiftCodes: {
+            type: 'array',
+            items: {
+              allOf: [{ $ref: '#/components/schemas/BranchRecord' }],
+              required: ['latitude', 'longitude', 'distanceKm'],
+              properties: {
+                latitude: { type: 'number' },
+                longitude: { type: 'number' },
+                distanceKm: { type: 'number' }
+              }
+            }
+          }
+        }
+      },
+      ApiKey: {
+        type: 'object',
+        required: ['id', 'prefix', 'createdAt'],
+        properties: {
+          id: { type: 'string' },
+          prefix: { type: 'string' },
+          label: { type: 'string' },
+          createdAt: { type: 'string' },
+          expiresAt: { type: 'string', nullable: true },
+          revokedAt: { type: 'string', nullable: true }
+        }
+      },
+      ApiKeyList: {
+        type: 'object',
+        required: ['keys'],
+        properties: {
+          keys: { type: 'array', items: { $ref: '#/components/schemas/ApiKey' } }
+        }
+      },
+      IssuedApiKey: {
+        allOf: [{ $ref: '#/components/schemas/ApiKey' }],
+        required: ['apiKey'],
+        properties: {
+          apiKey: { type: 'string' },
+          previousKeyExpiresAt: { type: 'string' }
+        }
+      },
+      ErrorCodeCatalogue: {
+        type: 'object',
+        required: ['errorCodes'],
+        properties: {
+          errorCodes: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['code', 'status', 'description'],
+              properties: {
+                code: { type: 'string' },
+                status: { type: 'integer' },
+                description: { type: 'string' }
+              }
+            }
+          }
+        }
+      },
+      Bank: {
+        type: 'object',
+        required: ['bic8', 'name', 'headquarter', 'branches'],
+        properties: {
+          bic8: { type: 'string', minLength: 8, maxLength: 8 },
+          name: { type: 'string' },
+          countryISO2: { type: 'string' },
+          headquarter: { type: 'string', nullable: true },
+          branches: { type: 'array', items: { type: 'string' } },
+          createdAt: { type: 'string' },
+          updatedAt: { type: 'string' }
+        }
+      },
+      Institution: {
+        type: 'object',
+        required: ['bankCode', 'countryCount', 'codeCount', 'countries'],
+        properties: {
+          bankCode: { type: 'string', minLength: 4, maxLength: 4 },
+          countryCount: { type: 'integer' },
+          codeCount: { type: 'integer' },
+          countries: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['countryISO2', 'countryName', 'bic8s', 'swiftCodes'],
+              properties: {
+                countryISO2: { type: 'string' },
+                countryName: { type: 'string' },
+                bic8s: { type: 'array', items: { type: 'string' } },
+                swiftCodes: {
+                  type: 'array',
+                  items: { $ref: '#/components/schemas/BranchRecord' }
+                }
+              }
+            }
+          }
+        }
+      },
+      CreatedBank: {
+        type: 'object',
+        required: ['message', 'bank'],
+        properties: {
+          message: { type: 'string' },
+          bank: { $ref: '#/components/schemas/Bank' }
+        }
+      },
+      BankPage: {
+        type: 'object',
+        required: ['banks', 'pagination'],
+        properties: {
+          banks: { type: 'array', items: { $ref: '#/components/schemas/Bank' } },
+          pagination: { $ref: '#/components/schemas/PageInfo' }
+        }
+      },
+      CitySwiftCodes: {
+        type: 'object',
+        required: ['city', 'swiftCodes'],
+        properties: {
+          city: { type: 'string' },
+          swiftCodes: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/BranchRecord' }
+          }
+        }
+      },
+      ImportSchedule: {
+        type: 'object',
+        required: ['enabled', 'source', 'cron', 'timezone', 'lastRun'],
+        properties: {
+          enabled: { type: 'boolean' },
+          source: { type: 'string', nullable: true },
+          cron: { type: 'string' },
+          timezone: { type: 'string' },
+          lastRun: {
+            type: 'object',
+            required: ['status'],
+            properties: {
+              status: { type: 'string', enum: ['idle', 'running', 'imported', 'skipped', 'failed'] },
+              source: { type: 'string' },
+              startedAt: { type: 'string' },
+              sourceHash: { type: 'string' },
+              importRunId: { type: 'string' },
+              imported: { type: 'integer' },
+              // Skipped runs: why, when it wasn't an unchanged source
+              reason: { type: 'string' },
+              error: { type: 'string' }
+            }
+          }
+        }
+      },
+      ImportSummary: {
+        type: 'object',
+        required: ['message', 'importRunId', 'mode', 'imported', 'collapsedDuplicates', 'rejectedCount'],
+        properties: {
+          message: { type: 'string' },
+          importRunId: { type: 'string' },
+          mode: { type: 'string', enum: ['replace', 'upsert', 'delta'] },
+          imported: { type: 'integer' },
+          collapsedDuplicates: { type: 'integer' },
+          rejectedCount: { type: 'integer' },
+          // Upsert imports only
+          insertedCount: { type: 'integer' },
+          updatedCount: { type: 'integer' },
+          deactivatedCount: { type: 'integer' },
+          // Delta imports only
+          addedCount: { type: 'integer' },
+          modifiedCount: { type: 'integer' },
+          removedCount: { type: 'integer' },
+          unchangedCount: { type: 'integer' },
+          changeset: { $ref: '#/components/schemas/Changeset' },
+          // Rejection reasons by code; a row failing several checks counts once per reason
+          rejectReasonCounts: { type: 'object', additionalProperties: { type: 'integer' } },
+          rejectsFile: { type: 'string' }
+        }
+      },
+      Changeset: {
+        type: 'object',
+        required: ['added', 'modified', 'removed'],
+        properties: {
+          added: { type: 'array', items: { type: 'string' } },
+          modified: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['swiftCode', 'changes'],
+              properties: {
+                swiftCode: { type: 'string' },
+                // Changed fields, each as { from, to }
+                changes: { type: 'object', additionalProperties: { type: 'object', required: ['from', 'to'] } }
+              }
+            }
+          },
+          removed: { type: 'array', items: { type: 'string' } }
+        }
+      },
+      ImportChangeset: {
+        type: 'object',
+        required: ['importRunId', 'source', 'completedAt', 'changeset'],
+        properties: {
+          importRunId: { type: 'string' },
+          source: { type: 'string', nullable: true },
+          completedAt: { type: 'string' },
+          addedCount: { type: 'integer' },
+          modifiedCount: { type: 'integer' },
+          removedCount: { type: 'integer' },
+          unchangedCount: { type: 'integer' },
+          changeset: { $ref: '#/components/schemas/Changeset' }
+        }
+      },
+      DryRunReport: {
+        type: 'object',
+        required: ['source', 'mode', 'rows', 'wouldInsert', 'wouldUpdate', 'collapsedDuplicates', 'rejectedCount', 'rejects', 'warnings'],
+        properties: {
+          source: { type: 'string' },
+          mode: { type: 'string', enum: ['replace', 'upsert', 'delta'] },
+          rows: { type: 'integer' },
+          wouldInsert: { type: 'integer' },
+          wouldUpdate: { type: 'integer' },
+          // Replace imports remove every existing record, delta imports the codes missing from the file;
+          // upserts may deactivate the missing ones
+          wouldRemove: { type: 'integer' },
+          wouldDeactivate: { type: 'integer' },
+          // Delta dry runs only
+          changeset: { $ref: '#/components/schemas/Changeset' },
+          collapsedDuplicates: { type: 'integer' },
+          rejectedCount: { type: 'integer' },
+          rejects: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['line', 'reasons'],
+              properties: {
+                line: { type: 'integer' },
+                swiftCode: { type: 'string', nullable: true },
+                reasons: { type: 'array', items: { type: 'object' } }
+              }
+            }
+          },
+          warnings: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['line', 'swiftCode', 'issues'],
+              properties: {
+                line: { type: 'integer' },
+                swiftCode: { type: 'string' },
+                issues: { type: 'array', items: { type: 'object' } }
+              }
+            }
+          }
+        }
+      },
+      ImportResult: {
+        anyOf: [
+          { $ref: '#/components/schemas/ImportSummary' },
+          { $ref: '#/components/schemas/DryRunReport' }
+        ]
+      },
+      ImportPreview: {
+        type: 'object',
+        required: ['columns', 'missingColumns', 'records', 'issueCount'],
+        properties: {
+          columns: { type: 'array', items: { type: 'string' } },
+          missingColumns: { type: 'array', items: { type: 'string' } },
+          records: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['line', 'record', 'issues'],
+              properties: {
+                line: { type: 'integer' },
+                record: { type: 'object' },
+                issues: {
+                  type: 'array',
+                  items: {
+                    type: 'object',
+                    required: ['code', 'message'],
+                    properties: {
+                      code: { type: 'string' },
+                      field: { type: 'string' },
+                      message: { type: 'string' }
+                    }
+                  }
+                }
+              }
+            }
+          },
+          issueCount: { type: 'integer' }
+        }
+      },
+      AuditLogPage: {
+        type: 'object',
+        required: ['entries', 'pagination'],
+        properties: {
+          entries: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['at', 'actor', 'action'],
+              properties: {
+                at: { type: 'string' },
+                actor: { type: 'string' },
+                action: { type: 'string' },
+                method: { type: 'string' },
+                path: { type: 'string' },
+                statusCode: { type: 'integer' },
+                clientIp: { type: 'string' },
+                swiftCodes: { type: 'array', items: { type: 'string' } },
+                countries: { type: 'array', items: { type: 'string' } }
+              }
+            }
+          },
+          pagination: { $ref: '#/components/schemas/PageInfo' }
+        }
+      },
+      // One line of GET /v1/admin/siem/events; every key is always present
+      SiemEvent: {
+        type: 'object',
+        required: ['schemaVersion', 'eventId', 'eventType', 'occurredAt', 'source', 'actor', 'action', 'http', 'target', 'change'],
+        properties: {
+          schemaVersion: { type: 'integer' },
+          eventId: { type: 'string' },
+          eventType: { type: 'string', enum: ['audit', 'change'] },
+          occurredAt: { type: 'string' },
+          source: { type: 'string' },
+          actor: { type: 'string' },
+          action: { type: 'string' },
+          http: {
+            type: 'object',
+            nullable: true,
+            properties: {
+              method: { type: 'string', nullable: true },
+              path: { type: 'string', nullable: true },
+              statusCode: { type: 'integer', nullable: true },
+              clientIp: { type: 'string', nullable: true }
+            }
+          },
+          target: {
+            type: 'object',
+            properties: {
+              swiftCodes: { type: 'array', items: { type: 'string' } },
+              countries: { type: 'array', items: { type: 'string' } }
+            }
+          },
+          change: {
+            type: 'object',
+            nullable: true,
+            properties: {
+              swiftCode: { type: 'string' },
+              version: { type: 'integer' },
+              changes: {
+                type: 'array',
+                items: { type: 'object', required: ['field', 'from', 'to'] }
+              }
+            }
+          }
+        }
+      },
+      BankNameSwiftCodes: {
+        type: 'object',
+        required: ['bankName', 'swiftCodes'],
+        properties: {
+          bankName: { type: 'string' },
+          swiftCodes: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/BranchRecord' }
+          }
+        }
+      },
+      EditLock: {
+        type: 'object',
+        required: ['swiftCode', 'lockedBy', 'acquiredAt', 'expiresAt'],
+        properties: {
+          message: { type: 'string' },
+          swiftCode: { type: 'string' },
+          lockedBy: { type: 'string' },
+          acquiredAt: { type: 'string' },
+          expiresAt: { type: 'string' }
+        }
+      },
+      EditLocks: {
+        type: 'object',
+        required: ['message', 'locks'],
+        properties: {
+          message: { type: 'string' },
+          locks: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/EditLock' }
+          }
+        }
+      },
+      SwiftCodeHistory: {
+        type: 'object',
+        required: ['swiftCode', 'revisions', 'pagination'],
+        properties: {
+          swiftCode: { type: 'string' },
+          revisions: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['version', 'action', 'actor', 'at'],
+              properties: {
+                version: { type: 'integer' },
+                action: { type: 'string' },
+                actor: { type: 'string' },
+                at: { type: 'string' },
+                previous: { type: 'object', nullable: true },
+                current: { type: 'object', nullable: true }
+              }
+            }
+          },
+          pagination: { $ref: '#/components/schemas/PageInfo' }
+        }
+      },
+      Count: {
+        type: 'object',
+        required: ['count'],
+        properties: {
+          count: { type: 'integer', minimum: 0 }
+        }
+      },
+      InstitutionSwiftCodes: {
+        type: 'object',
+        required: ['bic8', 'swiftCodes'],
+        properties: {
+          bic8: { type: 'string', minLength: 8, maxLength: 8 },
+          swiftCodes: {
+            type: 'array',
+            items: { $ref: '#/components/schemas/BranchRecord' }
+          }
+        }
+      },
+      CountrySwiftCodesByCity: {
+        type: 'object',
+        required: ['countryISO2', 'countryName', 'cities'],
+        properties: {
+          countryISO2: { type: 'string', minLength: 2, maxLength: 2 },
+          countryName: { type: 'string' },
+          cities: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['city', 'swiftCodes'],
+              properties: {
+                city: { type: 'string', nullable: true },
+                swiftCodes: {
+                  type: 'array',
+                  items: { $ref: '#/components/schemas/BranchRecord' }
+                }
+              }
+            }
+          }
+        }
+      },
+      CountryListing: {
+        oneOf: [
+          { $ref: '#/components/schemas/CountrySwiftCodes' },
+          { $ref: '#/components/schemas/CountrySwiftCodesByCity' }
+        ]
+      },
+      BankCatalogue: {
+        type: 'object',
+        required: ['bic8', 'bankName', 'countryISO2', 'countryName', 'branches'],
+        properties: {
+          bic8: { type: 'string', minLength: 8, maxLength: 8 },
+          bankName: { type: 'string' },
+          countryISO2: { type: 'string' },
+          countryName: { type: 'string' },
+          branches: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['swiftCode', 'branchCode', 'isHeadquarter', 'address', 'branchDescription'],
+              properties: {
+                swiftCode: { type: 'string' },
+                branchCode: { type: 'string' },
+                isHeadquarter: { type: 'boolean' },
+                address: { type: 'string' },
+                branchDescription: { type: 'string', nullable: true }
+              }
+            }
+          }
+        }
+      },
+      BulkCreateResult: {
+        type: 'object',
+        required: ['summary', 'results'],
+        properties: {
+          summary: {
+            type: 'object',
+            required: ['total', 'created', 'duplicate', 'invalid'],
+            properties: {
+              total: { type: 'integer' },
+              created: { type: 'integer' },
+              duplicate: { type: 'integer' },
+              invalid: { type: 'integer' }
+            }
+          },
+          results: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['index', 'swiftCode', 'status'],
+              properties: {
+                index: { type: 'integer' },
+                swiftCode: { type: 'string', nullable: true },
+                status: { type: 'string', enum: ['created', 'duplicate', 'invalid'] },
+                statusCode: { type: 'integer' },
+                reason: { type: 'string' },
+                error: { $ref: '#/components/schemas/ItemError' },
+                warnings: {
+                  type: 'array',
+                  items: { $ref: '#/components/schemas/Warning' }
+                }
+              }
+            }
+          }
+        }
+      },
+      BulkDeleteResult: {
+        type: 'object',
+        required: ['totalDeleted', 'deleted'],
+        properties: {
+          totalDeleted: { type: 'integer' },
+          deleted: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['criterion', 'value', 'deletedCount'],
+              properties: {
+                criterion: { type: 'string', enum: ['swiftCode', 'filter'] },
+                value: {},
+                deletedCount: { type: 'integer' }
+              }
+            }
+          }
+        }
+      },
+      ValidationResult: {
+        type: 'object',
+        required: ['swiftCode', 'valid', 'errors', 'exists'],
+        properties: {
+          swiftCode: { type: 'string' },
+          valid: { type: 'boolean' },
+          errors: { type: 'array', items: { type: 'string' } },
+          exists: { type: 'boolean' }
+        }
+      },
+      BulkValidationResult: {
+        type: 'object',
+        required: ['results'],
+        properties: {
+          results: {
+            type: 'array',
+            items: {
+              type: 'object',
+              required: ['index', 'swiftCode', 'valid', 'errors', 'exists', 'statusCode'],
+              properties: {
+                index: { type: 'integer' },
+                swiftCode: { type: 'string' },
+                valid: { type: 'boolean' },
+                errors: { type: 'array', items: { type: 'string' } },
+                exists: { type: 'boolean' },
+                statusCode: { type: 'integer' },
+                error: { $ref: '#/components/schemas/ItemError' }
+              }
+            }
+          }
+        }
+      },
+      ValidationResponse: {
+        oneOf: [
+          { $ref: '#/components/schemas/ValidationResult' },
+          { $ref: '#/components/schemas/BulkValidationResult' }
+        ]
+      },
+      // Failure of one item in a multi-status response
+      ItemError: {
+        type: 'object',
+        required: ['code', 'message'],
+        properties: {
+          code: { type: 'string' },
+          message: { type: 'string' }
+        }
+      },
+      LookupResult: {
+        type: 'object',
+        required: ['results'],
+        properties: {
+          results: {
+            type: 'object',
+            additionalProperties: {
+              type: 'object',
+              required: ['found', 'statusCode'],
+              properties: {
+                found: { type: 'boolean' },
+                statusCode: { type: 'integer' },
+                error: { $ref: '#/components/schemas/ItemError' }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+};
+
+// src/models/swiftCode.js
+const mongoose = require('mongoose');
+const {
+  decomposeBic,
+  toBic8,
+  connectivityOf,
+  isHeadquarterCode,
+  CONNECTIVITY_STATUSES,
+  ISO_9362_PATTERN
+} = require('../utils/bic');
+const { toSearchNames } = require('../utils/bankNames');
+const { isValidCountryCode, getCountryName } = require('../utils/countries');
+const branchCache = require('../utils/branchCache');
+const SwiftCodeLookup = require('./swiftCodeLookup');
+const { timeQueries } = require('../utils/stageTimer');
+
+// GeoJSON point of the branch premises
+const pointSchema = new mongoose.Schema({
+  type: {
+    type: String,
+    enum: ['Point'],
+    required: true
+  },
+  coordinates: {
+    type: [Number],
+    required: true
+  }
+}, { _id: false });
+
+// Domestic clearing identifier of the same branch, e.g. { scheme: 'ABA', value: '026009593' }
+const nationalIdSchema = new mongoose.Schema({
+  scheme: {
+    type: String,
+    required: true,
+    uppercase: true
+  },
+  value: {
+    type: String,
+    required: true
+  }
+}, { _id: false });
+
+// Decommissioned BICs (e.g. after a merger) are retired rather than deleted
+const RECORD_STATUSES = ['active', 'inactive', 'pending', 'retired'];
+
+const swiftCodeSchema = new mongoose.Schema({
+  swiftCode: {
+    type: String,
+    required: true,
+    unique: true,
+    trim: true,
+    uppercase: true,
+    // Last line of defence for writes that bypass the request validator, e.g. imports
+    match: [ISO_9362_PATTERN, 'SWIFT code {VALUE} does not follow ISO 9362']
+  },
+  // Primary (English/Latin script) name
+  bankName: {
+    type: String,
+    required: true,
+    trim: true
+  },
+  // Names in local scripts keyed by language code, e.g. { ja: "三菱UFJ銀行" }
+  localizedNames: {
+    type: Map,
+    of: String,
+    default: undefined
+  },
+  // Derived from bankName and localizedNames for search
+  searchNames: {
+    type: [String],
+    default: undefined
+  },
+  address: {
+    type: String,
+    required: true,
+    trim: true
+  },
+  // Address as submitted, kept when normalization changed it
+  originalAddress: {
+    type: String,
+    trim: true
+  },
+  // Town the record is located in, from the directory's TOWN NAME column
+  city: {
+    type: String,
+    trim: true,
+    uppercase: true
+  },
+  countryISO2: {
+    type: String,
+    required: true,
+    trim: true,
+    uppercase: true,
+    validate: [isValidCountryCode, 'Country code {VALUE} is not a valid ISO 3166-1 country']
+  },
+  // Always the ISO 3166-1 name of countryISO2, so spellings can't drift between records
+  countryName: {
+    type: String, 
+    required: true,
+    trim: true,
+    uppercase: true
+  },
+  // Derived from the code on every write, a submitted value only feeds the HEADQUARTER_MISMATCH check
+  isHeadquarter: {
+    type: Boolean,
+    required: true
+  },
+  location: {
+    type: pointSchema,
+    default: undefined
+  },
+  // Legal Entity Identifier of the institution, used to join BICs with regulatory reports
+  lei: {
+    type: String,
+    trim: true,
+    uppercase: true
+  },
+  nationalIds: {
+    type: [nationalIdSchema],
+    default: undefined
+  },
+  // ISO 9362 components of swiftCode, derived before validation
+  bic8: String,
+  bankCode: String,
+  countryCode: String,
+  locationCode: String,
+  branchCode: String,
+  // Derived from the location code so payments can avoid unconnected BICs
+  connectivityStatus: {
+    type: String,
+    enum: CONNECTIVITY_STATUSES
+  },
+  branchDescription: {
+    type: String,
+    trim: true
+  },
+  // Provisional records for counterparties whose directory entry hasn't propagated yet
+  isTemporary: {
+    type: Boolean,
+    default: false
+  },
+  expiresAt: {
+    type: Date,
+    required: function () {
+      return this.isTemporary;
+    }
+  },
+  deprecatedAt: {
+    type: Date,
+    default: null
+  },
+  // Code that took over this one's business in a merger or transfer
+  successor: {
+    type: String,
+    trim: true,
+    uppercase: true
+  },
+  // Only active records are presented as valid; the others stay queryable by status
+  status: {
+    type: String,
+    enum: RECORD_STATUSES,
+    default: 'active'
+  },
+  statusReason: {
+    type: String,
+    trim: true
+  },
+  statusChangedAt: Date,
+  // Set instead of deleting so accidental deletions can be restored
+  deletedAt: {
+    type: Date,
+    default: null
+  },
+  // Identifiers of this record in other systems, e.g. { GLEIF: "...", CORE_BANKING: "..." }
+  externalIds: {
+    type: Map,
+    of: String,
+    default: undefined
+  },
+  // Import that last wrote the record from its file; upsert imports find codes missing from the file by it
+  importRun: {
+    type: mongoose.Schema.Types.ObjectId,
+    ref: 'ImportRun'
+  }
+}, {
+  // createdAt/updatedAt let caches and sync jobs tell how fresh a record is
+  timestamps: true
+});
+
+// Index for faster querying
+swiftCodeSchema.index({ swiftCode: 1 });
+swiftCodeSchema.index({ countryISO2: 1 });
+swiftCodeSchema.index({ countryISO2: 1, bankName: 1, swiftCode: 1 });
+swiftCodeSchema.index({ countryISO2: 1, swiftCode: 1 });
+swiftCodeSchema.index({ countryISO2: 1, status: 1, swiftCode: 1 });
+swiftCodeSchema.index({ countryISO2: 1, connectivityStatus: 1, swiftCode: 1 });
+// Also serves ?type= filters on the country listing through its (countryISO2, isHeadquarter) prefix
+swiftCodeSchema.index({ countryISO2: 1, isHeadquarter: -1, swiftCode: 1 });
+swiftCodeSchema.index({ swiftCode: 1, isHeadquarter: 1 });
+swiftCodeSchema.index({ city: 1, countryISO2: 1 });
+// Records without coordinates are simply left out of the 2dsphere index
+swiftCodeSchema.index({ location: '2dsphere' });
+// Branch matching: { bic8, isHeadquarter: false } sorted by code
+swiftCodeSchema.index({ bic8: 1, isHeadquarter: 1, swiftCode: 1 });
+swiftCodeSchema.index({ bankCode: 1 });
+swiftCodeSchema.index({ locationCode: 1, countryCode: 1 });
+swiftCodeSchema.index({ branchCode: 1 });
+swiftCodeSchema.index({ lei: 1 }, { sparse: true });
+swiftCodeSchema.index({ searchNames: 1 });
+swiftCodeSchema.index({ 'nationalIds.scheme': 1, 'nationalIds.value': 1 });
+// Case-insensitive exact matches on the institution's legal name
+swiftCodeSchema.index({ bankName: 1, swiftCode: 1 }, { collation: { locale: 'en', strength: 2 } });
+swiftCodeSchema.index({ expiresAt: 1 }, { partialFilterExpression: { isTemporary: true } });
+swiftCodeSchema.index({ 'externalIds.$**': 1 });
+
+// insertMany validates every document, so imports populate the derived fields too
+swiftCodeSchema.pre('validate', function () {
+  if (this.swiftCode) {
+    this.bic8 = toBic8(this.swiftCode);
+    Object.assign(this, decomposeBic(this.swiftCode));
+    this.connectivityStatus = connectivityOf(this.swiftCode);
+    this.isHeadquarter = isHeadquarterCode(this.swiftCode);
+  }
+  if (isValidCountryCode(this.countryISO2)) {
+    this.countryName = getCountryName(this.countryISO2);
+  }
+  if (this.bankName) {
+    this.searchNames = toSearchNames(this.bankName, this.localizedNames);
+  }
+});
+
+// Soft-deleted records are hidden unless a query filters on deletedAt or sets { withDeleted: true }
+function excludeDeleted() {
+  if (this.getOptions().withDeleted || Object.prototype.hasOwnProperty.call(this.getFilter(), 'deletedAt')) {
+    return;
+  }
+  this.where({ deletedAt: null });
+}
+
+swiftCodeSchema.pre(['find', 'findOne', 'findOneAndUpdate', 'countDocuments', 'distinct', 'updateOne', 'updateMany'], excludeDeleted);
+
+swiftCodeSchema.pre('aggregate', function () {
+  if (this.options.withDeleted) {
+    return;
+  }
+  
+  const pipeline = this.pipeline();
+  
+  // $geoNear has to remain the first stage of a pipeline
+  if (pipeline[0] && pipeline[0].$geoNear) {
+    pipeline.splice(1, 0, { $match: { deletedAt: null } });
+  } else {
+    pipeline.unshift({ $match: { deletedAt: null } });
+  }
+});
+
+// Any write to a code invalidates the cached branch lists and lookup entries of its institution; the
+// write only resolves once the entries are gone, so the next lookup already sees it
+const invalidate = async (bic8s) => {
+  const institutions = [].concat(bic8s).filter(Boolean);
+  branchCache.invalidate(institutions);
+  await SwiftCodeLookup.deleteMany({ bic8: { $in: institutions } });
+};
+
+const invalidateAll = async () => {
+  branchCache.clear();
+  await SwiftCodeLookup.deleteMany({});
+};
+
+swiftCodeSchema.post('save', (doc) => invalidate(doc.bic8));
+swiftCodeSchema.post('insertMany', (docs) => invalidate(docs.map(doc => doc.bic8)));
+
+// Query writes name their institution through the filter; anything broader clears everything
+function invalidateBranches() {
+  const { swiftCode, bic8 } = this.getFilter();
+  
+  if (typeof bic8 === 'string') {
+    return invalidate(bic8);
+  }
+  if (typeof swiftCode === 'string') {
+    return invalidate(toBic8(swiftCode));
+  }
+  return invalidateAll();
+}
+
+swiftCodeSchema.post(
+  ['updateOne', 'updateMany', 'findOneAndUpdate', 'replaceOne', 'deleteOne', 'deleteMany', 'findOneAndDelete'],
+  { document: false, query: true },
+  invalidateBranches
+);
+
+// Mongoose 7 runs no middleware for Model.bulkWrite, so its callers invalidate through this instead
+swiftCodeSchema.statics.invalidateInstitutions = invalidate;
+
+swiftCodeSchema.plugin(timeQueries);
+
+const SwiftCode = mongoose.model('SwiftCode', swiftCodeSchema);
+
+module.exports = SwiftCode;
+module.exports.RECORD_STATUSES = RECORD_STATUSES;
+
+// src/models/importRun.js
+const mongoose = require('mongoose');
+
+const isCompleted = function () {
+  return this.status !== 'failed';
+};
+
+// One document per import: completed ones are the dataset versions, failed ones are kept for the dashboard
+const importRunSchema = new mongoose.Schema({
+  status: {
+    type: String,
+    enum: ['completed', 'failed'],
+    default: 'completed'
+  },
+  // Failed imports only: what stopped the import
+  error: String,
+  // What started the import: 'cli', 'upload' or 'schedule'
+  trigger: String,
+  source: {
+    type: String,
+    trim: true
+  },
+  // SHA-256 of the source file, so scheduled imports can skip an unchanged one
+  sourceHash: String,
+  // 'replace' rebuilds the collection from the file, 'upsert' merges the file into it, 'delta' applies
+  // only the differences from the stored records
+  mode: {
+    type: String,
+    enum: ['replace', 'upsert', 'delta'],
+    default: 'replace'
+  },
+  startedAt: {
+    type: Date,
+    required: true
+  },
+  // When the import finished, or failed
+  completedAt: {
+    type: Date,
+    required: true
+  },
+  recordCount: {
+    type: Number,
+    required: isCompleted
+  },
+  collapsedDuplicates: {
+    type: Number,
+    default: 0
+  },
+  // Rows that failed schema validation and were left out of the dataset
+  rejectedCount: {
+    type: Number,
+    default: 0
+  },
+  // Upsert imports only: codes added, codes changed and codes set inactive for missing from the file
+  insertedCount: Number,
+  updatedCount: Number,
+  deactivatedCount: Number,
+  // Delta imports only: codes added, codes with changed fields, codes removed and codes left as they were
+  addedCount: Number,
+  modifiedCount: Number,
+  removedCount: Number,
+  unchangedCount: Number,
+  // Delta imports only: { added: [codes], modified: [{ swiftCode, changes }], removed: [codes] }, the
+  // auditable record of what the import changed
+  changeset: mongoose.Schema.Types.Mixed,
+  // Institutions (distinct BIC8s) versus locations (codes) per country at import time
+  countryRollup: [{
+    _id: false,
+    countryISO2: String,
+    institutions: Number,
+    locations: Number
+  }]
+});
+
+importRunSchema.index({ completedAt: -1 });
+importRunSchema.index({ status: 1, completedAt: -1 });
+importRunSchema.index({ 'countryRollup.countryISO2': 1 });
+
+const ImportRun = mongoose.model('ImportRun', importRunSchema);
+
+module.exports = ImportRun;
+// Runs that produced a dataset version; runs stored before failures were recorded have no status
+module.exports.COMPLETED = { status: { $ne: 'failed' } };
+
+// src/models/importReject.js
+const mongoose = require('mongoose');
+
+// Why a row was rejected, reusing the machine-readable error codes of API writes
+const REJECT_REASONS = ['MISSING_FIELD', 'INVALID_FORMAT', 'UNKNOWN_COUNTRY', 'INVALID_RECORD', 'DUPLICATE_IN_FILE'];
+
+// A row an import left out because it failed schema validation, kept until a steward corrects it
+const importRejectSchema = new mongoose.Schema({
+  importRun: {
+    type: mongoose.Schema.Types.ObjectId,
+    ref: 'ImportRun',
+    required: true
+  },
+  // Line of the source file; line 1 of a CSV file holds the headers
+  line: Number,
+  // The row as parsed, before any correction
+  record: {
+    type: mongoose.Schema.Types.Mixed,
+    required: true
+  },
+  reasons: [{
+    _id: false,
+    code: {
+      type: String,
+      enum: REJECT_REASONS
+    },
+    field: String,
+    message: String
+  }],
+  resubmittedAt: {
+    type: Date,
+    default: null
+  },
+  resubmittedBy: String,
+  // Code the corrected row was stored under
+  resubmittedSwiftCode: String
+}, {
+  timestamps: true
+});
+
+importRejectSchema.index({ importRun: 1, 'reasons.code': 1, line: 1 });
+
+const ImportReject = mongoose.model('ImportReject', importRejectSchema);
+
+module.exports = ImportReject;
+module.exports.REJECT_REASONS = REJECT_REASONS;
+
+// src/models/auditLog.js
+const mongoose = require('mongoose');
+
+// One document per successful write to the directory
+const auditLogSchema = new mongoose.Schema({
+  at: {
+    type: Date,
+    required: true,
+    default: Date.now
+  },
+  actor: {
+    type: String,
+    required: true,
+    trim: true
+  },
+  action: {
+    type: String,
+    required: true
+  },
+  method: String,
+  path: String,
+  statusCode: Number,
+  clientIp: String,
+  // Codes and countries touched by the write, used by the access-review filters
+  swiftCodes: [String],
+  countries: [String]
+});
+
+auditLogSchema.index({ at: -1 });
+auditLogSchema.index({ actor: 1, at: -1 });
+auditLogSchema.index({ action: 1, at: -1 });
+auditLogSchema.index({ countries: 1, at: -1 });
+
+const AuditLog = mongoose.model('AuditLog', auditLogSchema);
+
+module.exports = AuditLog;
+
+// src/models/editLock.js
+const mongoose = require('mongoose');
+
+// Short-lived claim of a record by the steward editing it in the back office
+const editLockSchema = new mongoose.Schema({
+  swiftCode: {
+    type: String,
+    required: true,
+    unique: true,
+    uppercase: true
+  },
+  holder: {
+    type: String,
+    required: true
+  },
+  acquiredAt: {
+    type: Date,
+    required: true
+  },
+  expiresAt: {
+    type: Date,
+    required: true
+  }
+});
+
+// MongoDB removes expired locks on its own; queries still check expiresAt because the sweep is lazy
+editLockSchema.index({ expiresAt: 1 }, { expireAfterSeconds: 0 });
+
+const EditLock = mongoose.model('EditLock', editLockSchema);
+
+module.exports = EditLock;
+
+// src/models/lease.js
+const mongoose = require('mongoose');
+
+// Cluster-wide claim of a task only one process may run at a time, such as an import; renewed while
+// the task runs, so a crashed holder loses it once expiresAt passes
+const leaseSchema = new mongoose.Schema({
+  // Name of the task
+  _id: String,
+  holder: {
+    type: String,
+    required: true
+  },
+  acquiredAt: {
+    type: Date,
+    required: true
+  },
+  expiresAt: {
+    type: Date,
+    required: true
+  }
+});
+
+// MongoDB removes expired leases on its own; acquiring still checks expiresAt because the sweep is lazy
+leaseSchema.index({ expiresAt: 1 }, { expireAfterSeconds: 0 });
+
+const Lease = mongoose.model('Lease', leaseSchema);
+
+module.exports = Lease;
+
+// src/models/swiftCodeRevision.js
+const mongoose = require('mongoose');
+
+// One document per change to a record, numbered per SWIFT code
+const swiftCodeRevisionSchema = new mongoose.Schema({
+  swiftCode: {
+    type: String,
+    required: true,
+    uppercase: true
+  },
+  version: {
+    type: Number,
+    required: true
+  },
+  action: {
+    type: String,
+    required: true
+  },
+  actor: {
+    type: String,
+    required: true
+  },
+  at: {
+    type: Date,
+    required: true,
+    default: Date.now
+  },
+  // Full record values before and after the change
+  previous: mongoose.Schema.Types.Mixed,
+  current: mongoose.Schema.Types.Mixed
+});
+
+swiftCodeRevisionSchema.index({ swiftCode: 1, version: -1 }, { unique: true });
+
+const SwiftCodeRevision = mongoose.model('SwiftCodeRevision', swiftCodeRevisionSchema, 'swiftCodeHistory');
+
+module.exports = SwiftCodeRevision;
+
+// src/models/bank.js
+const mongoose = require('mongoose');
+
+// One institution per BIC8; bank-level facts live here instead of drifting across its branch records
+const bankSchema = new mongoose.Schema({
+  bic8: {
+    type: String,
+    required: true,
+    unique: true,
+    trim: true,
+    uppercase: true
+  },
+  name: {
+    type: String,
+    required: true,
+    trim: true
+  },
+  countryISO2: {
+    type: String,
+    trim: true,
+    uppercase: true
+  },
+  // Kept in sync with the SWIFT code collection on every write and import
+  headquarter: {
+    type: mongoose.Schema.Types.ObjectId,
+    ref: 'SwiftCode',
+    default: null
+  },
+  branches: [{
+    type: mongoose.Schema.Types.ObjectId,
+    ref: 'SwiftCode'
+  }]
+}, {
+  timestamps: true
+});
+
+bankSchema.index({ countryISO2: 1, bic8: 1 });
+
+const Bank = mongoose.model('Bank', bankSchema);
+
+module.exports = Bank;
+
+// src/models/apiKey.js
+const mongoose = require('mongoose');
+
+// Self-service API key; only its hash is stored, the key itself is shown once at creation
+const apiKeySchema = new mongoose.Schema({
+  keyHash: {
+    type: String,
+    required: true,
+    unique: true
+  },
+  // First characters of the key so owners can tell their keys apart
+  prefix: {
+    type: String,
+    required: true
+  },
+  owner: {
+    type: String,
+    required: true
+  },
+  label: {
+    type: String,
+    trim: true
+  },
+  // Copied from the key that created it, so self-service can't escalate privileges
+  role: String,
+  redactionProfile: String,
+  strictness: String,
+  keyLimit: Number,
+  // Hash of the configured API_KEYS entry the key descends from; the key stops working once that entry is removed
+  parentKeyHash: String,
+  // Set on the old key of a rotation, which keeps working until then
+  expiresAt: {
+    type: Date,
+    default: null
+  },
+  revokedAt: {
+    type: Date,
+    default: null
+  }
+}, {
+  timestamps: true
+});
+
+apiKeySchema.index({ owner: 1, createdAt: -1 });
+
+const ApiKey = mongoose.model('ApiKey', apiKeySchema);
+
+module.exports = ApiKey;
+
+// src/models/country.js
+const mongoose = require('mongoose');
+
+// Change to the built-in ISO 3166-1 registry, so geopolitical changes don't need a release
+const countrySchema = new mongoose.Schema({
+  countryISO2: {
+    type: String,
+    required: true,
+    unique: true,
+    trim: true,
+    uppercase: true,
+    match: [/^[A-Z]{2}$/, 'countryISO2 must be 2 letters']
+  },
+  // Preferred display name; empty keeps the built-in one
+  name: {
+    type: String,
+    trim: true,
+    uppercase: true
+  },
+  withdrawn: {
+    type: Boolean,
+    default: false
+  },
+  withdrawnAt: {
+    type: Date,
+    default: null
+  }
+}, {
+  timestamps: true
+});
+
+const Country = mongoose.model('Country', countrySchema);
+
+module.exports = Country;
+
+// src/models/mergerEvent.js
+const mongoose = require('mongoose');
+
+const MERGER_EVENT_TYPES = ['merger', 'transfer'];
+
+// Consolidation of one or more institutions or branches into another, kept as a timeline; absorbed
+// BICs are BIC8s (every code of the institution) or single BIC11s
+const mergerEventSchema = new mongoose.Schema({
+  type: {
+    type: String,
+    enum: MERGER_EVENT_TYPES,
+    default: 'merger'
+  },
+  effectiveDate: {
+    type: Date,
+    required: true
+  },
+  absorbingBic: {
+    type: String,
+    required: true,
+    trim: true,
+    uppercase: true
+  },
+  absorbedBics: {
+    type: [String],
+    required: true
+  },
+  notes: {
+    type: String,
+    trim: true
+  },
+  // Codes the event deprecated, resolved from absorbedBics when it was recorded
+  affectedCodes: [String],
+  recordedBy: String
+}, {
+  timestamps: true
+});
+
+mergerEventSchema.index({ effectiveDate: 1 });
+
+const MergerEvent = mongoose.model('MergerEvent', mergerEventSchema);
+
+module.exports = MergerEvent;
+module.exports.MERGER_EVENT_TYPES = MERGER_EVENT_TYPES;
+
+// src/models/bicAssignment.js
+const mongoose = require('mongoose');
+
+// Period during which a BIC belonged to one institution; BICs are occasionally reassigned, and the
+// windows of a code never overlap. Kept apart from the SWIFT code collection so imports don't wipe them
+const bicAssignmentSchema = new mongoose.Schema({
+  swiftCode: {
+    type: String,
+    required: true,
+    trim: true,
+    uppercase: true
+  },
+  // Institution fields of the record while it held the code
+  holder: {
+    type: mongoose.Schema.Types.Mixed,
+    required: true
+  },
+  // Null when the holder had the code before its first recorded reassignment
+  validFrom: {
+    type: Date,
+    default: null
+  },
+  // Null for the current holder
+  validTo: {
+    type: Date,
+    default: null
+  }
+}, {
+  timestamps: true
+});
+
+bicAssignmentSchema.index({ swiftCode: 1, validFrom: 1 }, { unique: true });
+
+const BicAssignment = mongoose.model('BicAssignment', bicAssignmentSchema);
+
+module.exports = BicAssignment;
+
+// src/models/swiftCodeLookup.js
+const mongoose = require('mongoose');
+const { timeQueries } = require('../utils/stageTimer');
+
+// Denormalized read model of the exact-lookup hot path: the v1 detail record of a code and, for a
+// headquarter, its branch entries, so a lookup reads one small document. Entries of an institution are
+// dropped on any write to one of its codes and rebuilt on their next read or after an import, and expire
+// after lookupEntryTtlMs in case an invalidation was missed
+const swiftCodeLookupSchema = new mongoose.Schema({
+  swiftCode: {
+    type: String,
+    required: true,
+    unique: true
+  },
+  bic8: {
+    type: String,
+    required: true,
+    index: true
+  },
+  // Headquarter code of a branch, null for headquarters and branches without one
+  headquarter: {
+    type: String,
+    default: null
+  },
+  record: {
+    type: mongoose.Schema.Types.Mixed,
+    required: true
+  },
+  // Headquarters only, sorted by code
+  branches: {
+    type: [mongoose.Schema.Types.Mixed],
+    default: undefined
+  },
+  branchCount: {
+    type: Number,
+    default: 0
+  },
+  expiresAt: {
+    type: Date,
+    required: true
+  }
+}, {
+  timestamps: true
+});
+
+// MongoDB removes expired entries on its own; reads still check expiresAt because the sweep is lazy
+swiftCodeLookupSchema.index({ expiresAt: 1 }, { expireAfterSeconds: 0 });
+
+swiftCodeLookupSchema.plugin(timeQueries);
+
+const SwiftCodeLookup = mongoose.model('SwiftCodeLookup', swiftCodeLookupSchema);
+
+module.exports = SwiftCodeLookup;
+
+// src/routes/swiftCodeRoutes.js
+const express = require('express');
+const swiftCodeController = require('../controllers/swiftCodeController');
+const editLockController = require('../controllers/editLockController');
+const recordCeiling = require('../middleware/recordCeiling');
+const requireRole = require('../middleware/requireRole');
+const editLockGuard = require('../middleware/editLockGuard');
+const resolveBic8 = require('../middleware/resolveBic8');
+const normalizeInput = require('../middleware/normalizeInput');
+const validateRequest = require('../middleware/validateRequest');
+const schemas = require('../validation/requestSchemas');
+
+const router = express.Router();
+
+router.param('swiftCode', normalizeInput.param);
+router.param('countryISO2', normalizeInput.param);
+router.param('city', normalizeInput.param);
+router.param('lei', normalizeInput.param);
+router.param('bic8', normalizeInput.param);
+
+// HEAD route - existence check without a body, registered first so GET doesn't answer it
+router.head('/:swiftCode', swiftCodeController.checkSwiftCodeExists);
+
+// GET routes
+router.get('/export', swiftCodeController.exportSwiftCodes);
+router.get('/count', validateRequest(schemas.count), swiftCodeController.countSwiftCodes);
+router.get('/near', swiftCodeController.getSwiftCodesNear);
+router.get('/search', validateRequest(schemas.search), swiftCodeController.searchSwiftCodes);
+router.get('/:swiftCode', resolveBic8, swiftCodeController.getSwiftCodeDetails);
+router.get('/:swiftCode/branches', swiftCodeController.getBranches);
+router.get('/:swiftCode/siblings', swiftCodeController.getSiblings);
+router.get('/:swiftCode/headquarter', swiftCodeController.getHeadquarter);
+router.get('/:swiftCode/history', swiftCodeController.getHistory);
+router.get('/:swiftCode/assignments', validateRequest(schemas.assignments), swiftCodeController.getAssignments);
+router.get('/:swiftCode/history/diff', validateRequest(schemas.historyDiff), swiftCodeController.getHistoryDiff);
+router.get('/country/:countryISO2', validateRequest(schemas.countryListing), swiftCodeController.getSwiftCodesByCountry);
+router.get('/city/:city', swiftCodeController.getSwiftCodesByCity);
+router.get('/lei/:lei', swiftCodeController.getSwiftCodesByLei);
+router.get('/national/:scheme/:value', swiftCodeController.getSwiftCodesByNationalId);
+router.get('/bank/:bic8', swiftCodeController.getSwiftCodesByBic8);
+router.get('/bank/:bic8/catalogue', swiftCodeController.getBankCatalogue);
+router.get('/external/:system/:externalId', swiftCodeController.getSwiftCodesByExternalId);
+
+// POST routes
+router.post('/', recordCeiling, validateRequest(schemas.createSwiftCode), swiftCodeController.addSwiftCode);
+router.post('/bulk', recordCeiling, swiftCodeController.addSwiftCodesBulk);
+router.post('/lookup', swiftCodeController.lookupSwiftCodes);
+router.post('/validate', swiftCodeController.validateSwiftCode);
+router.post('/validate/stream', swiftCodeController.streamValidation);
+router.post('/:swiftCode/restore', editLockGuard, swiftCodeController.restoreSwiftCode);
+router.post('/:swiftCode/deactivate', editLockGuard, swiftCodeController.deactivateSwiftCode);
+router.post('/:swiftCode/activate', editLockGuard, swiftCodeController.activateSwiftCode);
+router.post('/:swiftCode/reassign', editLockGuard, validateRequest(schemas.reassign), swiftCodeController.reassignSwiftCode);
+// Locks belong to an authenticated steward; anonymous callers would all share one holder
+router.post('/:swiftCode/lock', requireRole(), editLockController.acquireLock);
+
+// PUT routes
+router.put('/:swiftCode/external-ids/:system', editLockGuard, swiftCodeController.setExternalId);
+router.put('/:swiftCode/lock', requireRole(), editLockController.heartbeatLock);
+
+// PATCH routes
+router.patch('/:swiftCode', editLockGuard, swiftCodeController.updateSwiftCode);
+
+// DELETE routes
+// A filter can match a whole country, so mass deletes are for admins only
+router.delete('/bulk', requireRole('admin'), swiftCodeController.deleteSwiftCodesBulk);
+router.delete('/:swiftCode', resolveBic8, editLockGuard, swiftCodeController.deleteSwiftCode);
+router.delete('/:swiftCode/external-ids/:system', editLockGuard, swiftCodeController.removeExternalId);
+router.delete('/:swiftCode/lock', requireRole(), editLockController.releaseLock);
+
+module.exports = router;
+
+// src/routes/statsRoutes.js
+const express = require('express');
+const statsController = require('../controllers/statsController');
+const normalizeInput = require('../middleware/normalizeInput');
+
+const router = express.Router();
+
+router.param('countryISO2', normalizeInput.param);
+
+// GET routes
+router.get('/', statsController.getDatasetStats);
+router.get('/countries', statsController.getCountryRollup);
+router.get('/countries/:countryISO2/trend', statsController.getCountryRollupTrend);
+
+module.exports = router;
+
+// src/routes/bankRoutes.js
+const express = require('express');
+const swiftCodeController = require('../controllers/swiftCodeController');
+const bankController = require('../controllers/bankController');
+const normalizeInput = require('../middleware/normalizeInput');
+const requireRole = require('../middleware/requireRole');
+
+const router = express.Router();
+
+router.param('bic4', normalizeInput.param);
+router.param('bic8', normalizeInput.param);
+
+// GET routes
+router.get('/', bankController.listBanks);
+// Four letters are a bank code, anything else is looked up as a BIC8
+router.get('/:bic4([A-Za-z]{4})', bankController.getInstitution);
+router.get('/:bic8', bankController.getBank);
+router.get('/:bankName/swift-codes', swiftCodeController.getSwiftCodesByBankName);
+
+// Writes reach every code of an institution, so they are admin-only like the admin routes
+
+// POST routes
+router.post('/', requireRole('admin'), bankController.createBank);
+
+// PATCH routes
+router.patch('/:bic8', requireRole('admin'), bankController.renameBank);
+
+// DELETE routes
+router.delete('/:bic8', requireRole('admin'), bankController.deleteBank);
+
+module.exports = router;
+
+// src/routes/swiftCodeRoutesV2.js
+const express = require('express');
+const swiftCodeController = require('../controllers/swiftCodeController');
+const normalizeInput = require('../middleware/normalizeInput');
+const resolveBic8 = require('../middleware/resolveBic8');
+const validateRequest = require('../middleware/validateRequest');
+const schemas = require('../validation/requestSchemas');
+
+const router = express.Router();
+
+router.param('swiftCode', normalizeInput.param);
+router.param('countryISO2', normalizeInput.param);
+
+// GET routes - v2 response shape
+router.get('/:swiftCode', resolveBic8, swiftCodeController.getSwiftCodeDetailsV2);
+router.get('/country/:countryISO2', validateRequest(schemas.countryListingV2), swiftCodeController.getSwiftCodesByCountryV2);
+
+module.exports = router;
+
+// src/routes/adminRoutes.js
+const express = require('express');
+const adminController = require('../controllers/adminController');
+const requireRole = require('../middleware/requireRole');
+const ipAllowlist = require('../middleware/ipAllowlist');
+const fileUpload = require('../middleware/fileUpload');
+const validateRequest = require('../middleware/validateRequest');
+const schemas = require('../validation/requestSchemas');
+
+const router = express.Router();
+
+router.use(ipAllowlist);
+router.use(requireRole('admin'));
+
+// GET routes
+router.get('/audit-log', adminController.getAuditLog);
+router.get('/siem/events', validateRequest(schemas.siemEvents), adminController.streamSiemEvents);
+router.get('/reports/orphan-branches', adminController.getOrphanBranches);
+router.get('/dashboard/recent-changes', adminController.getRecentChanges);
+router.get('/dashboard/pending-approvals', adminController.getPendingApprovals);
+router.get('/dashboard/failing-imports', adminController.getFailingImports);
+router.get('/dashboard/quality-offenders', adminController.getQualityOffenders);
+router.get('/imports/schedule', adminController.getImportSchedule);
+router.get('/maintenance/normalize', adminController.getNormalization);
+router.get('/imports/:id/rejects', validateRequest(schemas.importRejects), adminController.getImportRejects);
+router.get('/imports/:id/changeset', adminController.getImportChangeset);
+router.get('/countries', adminController.listCountries);
+
+// POST routes
+router.post('/imports', fileUpload('file', { disk: true }), validateRequest(schemas.runImport), adminController.runImport);
+router.post('/imports/preview', fileUpload(), adminController.previewImport);
+router.post('/config/reload', adminController.reloadConfig);
+router.post('/maintenance/normalize', adminController.normalizeRecords);
+router.post('/maintenance/smoke-test', validateRequest(schemas.smokeTest), adminController.runSmokeTest);
+router.post('/imports/:id/rejects/:rejectId/resubmit', validateRequest(schemas.resubmitImportReject), adminController.resubmitImportReject);
+
+// PUT routes
+router.put('/countries/:countryISO2', validateRequest(schemas.saveCountry), adminController.saveCountry);
+
+// DELETE routes
+router.delete('/countries/:countryISO2', validateRequest(schemas.countryCode), adminController.resetCountry);
+
+module.exports = router;
+
+// src/routes/metaRoutes.js
+const express = require('express');
+const metaController = require('../controllers/metaController');
+
+const router = express.Router();
+
+// GET routes
+router.get('/error-codes', metaController.getErrorCodes);
+
+module.exports = router;
+
+// src/routes/ibanRoutes.js
+const express = require('express');
+const ibanController = require('../controllers/ibanController');
+const validateRequest = require('../middleware/validateRequest');
+const schemas = require('../validation/requestSchemas');
+
+const router = express.Router();
+
+// POST routes
+router.post('/resolve', validateRequest(schemas.resolveIban), ibanController.resolveIban);
+
+module.exports = router;
+
+// src/routes/apiKeyRoutes.js
+const express = require('express');
+const apiKeyController = require('../controllers/apiKeyController');
+const requireRole = require('../middleware/requireRole');
+
+const router = express.Router();
+
+// Any consumer can manage their own keys
+router.use(requireRole());
+
+// GET routes
+router.get('/', apiKeyController.listKeys);
+
+// POST routes
+router.post('/', apiKeyController.createKey);
+router.post('/:id/rotate', apiKeyController.rotateKey);
+
+// DELETE routes
+router.delete('/:id', apiKeyController.revokeKey);
+
+module.exports = router;
+
+// src/routes/eventRoutes.js
+const express = require('express');
+const eventController = require('../controllers/eventController');
+const requireRole = require('../middleware/requireRole');
+const validateRequest = require('../middleware/validateRequest');
+const schemas = require('../validation/requestSchemas');
+
+const router = express.Router();
+
+// GET routes
+router.get('/mergers', validateRequest(schemas.mergerEvents), eventController.listMergerEvents);
+
+// POST routes
+router.post('/mergers', requireRole('admin'), validateRequest(schemas.recordMergerEvent), eventController.recordMergerEvent);
+
+module.exports = router;
+
+// src/controllers/swiftCodeController.js
+const readline = require('readline');
+const swiftCodeService = require('../services/swiftCodeService');
+const exportService = require('../services/exportService');
+const appConfig = require('../config/app');
+const {
+  validateSwiftCodeData,
+  normalizeSwiftCodeData,
+  validateSwiftCodeFormat,
+  isValidExternalIdSystem,
+  validateLocalizedNames
+} = require('../utils/swiftCodeValidator');
+const { parsePagination } = require('../utils/pagination');
+const { parseFields, SELECTABLE_FIELDS } = require('../utils/fields');
+const { serializeRecord } = require('../serializers/swiftCodeSerializer');
+const { evaluateWrite } = require('../utils/dataQuality');
+const { resolveActor } = require('../utils/actor');
+const { redactedFields } = require('../middleware/redaction');
+const { lockedResponse } = require('../middleware/editLockGuard');
+const editLockService = require('../services/editLockService');
+const { ERROR_CODES, itemError, multiStatusCode } = require('../errors/errorCodes');
+const { validateCoordinates } = require('../utils/geo');
+const { isValidLei } = require('../utils/lei');
+const { validateNationalId, normalizeNationalId } = require('../utils/nationalIds');
+
+// Nearby search stays local; KYC checks only need the surroundings of a claimed address
+const DEFAULT_NEAR_RADIUS_KM = 5;
+const MAX_NEAR_RADIUS_KM = 100;
+
+// API keys may carry their own strictness, otherwise the environment default applies
+const resolveStrictness = (req) => (req.consumer && req.consumer.strictness) || appConfig.validationStrictness;
+
+// Parse ?fields=, reporting unknown names so the caller can answer 400
+const parseFieldsParam = (req) => {
+  const { fields, unknown } = parseFields(req.query.fields);
+  const error = unknown.length > 0
+    ? `Unknown fields: ${unknown.join(', ')}. Selectable fields: ${SELECTABLE_FIELDS.join(', ')}`
+    : null;
+  return { fields, error };
+};
+
+// Listings show active records unless ?status= asks for another lifecycle state
+const validateStatusParam = (status) => {
+  if (status !== undefined && !swiftCodeService.RECORD_STATUSES.includes(status)) {
+    return `Invalid status, expected one of: ${swiftCodeService.RECORD_STATUSES.join(', ')}`;
+  }
+  return null;
+};
+
+// Large banking groups have hundreds of branches, so HQ details page them
+const parseBranchPaging = (query) => parsePagination({ page: query.branchPage, limit: query.branchLimit }, 'branches');
+
+// Country listings stay complete unless the client asks for a page
+const parseListingPaging = (query) => (query.page !== undefined || query.limit !== undefined
+  ? parsePagination(query, 'country')
+  : null);
+
+exports.getSwiftCodeDetails = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const { fields, error } = parseFieldsParam(req);
+    
+    if (error) {
+      return res.status(400).json({ message: error });
+    }
+    
+    const result = await swiftCodeService.getSwiftCodeDetails(swiftCode, {
+      fallbackToHeadquarter: appConfig.unknownCodeFallback === 'headquarter',
+      branchPaging: parseBranchPaging(req.query),
+      timestamps: req.query.includeTimestamps === 'true',
+      fields
+    });
+    
+    if (!result) {
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getSwiftCodeDetailsV2 = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const { fields, error } = parseFieldsParam(req);
+    
+    if (error) {
+      return res.status(400).json({ message: error });
+    }
+    
+    const result = await swiftCodeService.getSwiftCodeDetailsV2(swiftCode, {
+      fallbackToHeadquarter: appConfig.unknownCodeFallback === 'headquarter',
+      branchPaging: parseBranchPaging(req.query),
+      timestamps: req.query.includeTimestamps === 'true',
+      fields
+    });
+    
+    if (!result) {
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getHistory = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const result = await swiftCodeService.getHistory(swiftCode, parsePagination(req.query));
+    
+    if (!result) {
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+// Query values were checked by the historyDiff request schema
+exports.getHistoryDiff = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const result = await swiftCodeService.diffHistory(swiftCode, parseInt(req.query.from, 10), parseInt(req.query.to, 10));
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Revision not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+// ?at= was checked by the assignments request schema
+exports.getAssignments = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const at = req.query.at ? new Date(req.query.at) : null;
+    const result = await swiftCodeService.getAssignments(swiftCode, at);
+    
+    if (!result) {
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    if (at && !result.assignment) {
+      return res.status(404).json({ message: `No institution held ${result.swiftCode} on ${at.toISOString()}` });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.reassignSwiftCode = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const result = await swiftCodeService.reassignSwiftCode(swiftCode, req.body, resolveActor(req));
+    
+    if (!result) {
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    if (result.conflict) {
+      return res.status(409).json({ message: result.conflict });
+    }
+    
+    res.status(200).json({ message: 'SWIFT code reassigned', ...result });
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.checkSwiftCodeExists = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const exists = await swiftCodeService.swiftCodeExists(swiftCode);
+    
+    res.status(exists ? 200 : 404).end();
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getBranches = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const { countryISO2, city } = req.query;
+    
+    const result = await swiftCodeService.getBranches(swiftCode, {
+      countryISO2,
+      city,
+      ...parsePagination(req.query)
+    });
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Headquarter SWIFT code not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getSiblings = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const { city, status } = req.query;
+    
+    const statusError = validateStatusParam(status);
+    if (statusError) {
+      return res.status(400).json({ message: statusError });
+    }
+    
+    const result = await swiftCodeService.getSiblings(swiftCode, {
+      city,
+      status,
+      ...parsePagination(req.query)
+    });
+    
+    if (!result) {
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getHeadquarter = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    
+    if (swiftCode.length !== 8 && swiftCode.length !== 11) {
+      return res.status(400).json({ message: 'SWIFT code must be 8 or 11 characters' });
+    }
+    
+    const result = await swiftCodeService.getHeadquarter(swiftCode);
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Headquarter not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getSwiftCodesByCountry = async (req, res, next) => {
+  try {
+    const { countryISO2 } = req.params;
+    const { sort, type, status, connectivity, groupBy } = req.query;
+    const { fields, error } = parseFieldsParam(req);
+    
+    if (error) {
+      return res.status(400).json({ message: error });
+    }
+    
+    // Grouped listings have a fixed entry shape, so they don't combine with sparse fieldsets
+    if (groupBy && fields) {
+      return res.status(400).json({ message: 'fields cannot be combined with groupBy' });
+    }
+    
+    const result = groupBy
+      ? await swiftCodeService.getSwiftCodesByCountryGroupedByCity(countryISO2, { sort, type, status, connectivity })
+      : await swiftCodeService.getSwiftCodesByCountry(countryISO2, {
+        sort,
+        type,
+        status,
+        connectivity,
+        fields,
+        paging: parseListingPaging(req.query),
+        timestamps: req.query.includeTimestamps === 'true'
+      });
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Country not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getSwiftCodesByCountryV2 = async (req, res, next) => {
+  try {
+    const { countryISO2 } = req.params;
+    const { sort, type, status, connectivity } = req.query;
+    const { fields, error } = parseFieldsParam(req);
+    
+    if (error) {
+      return res.status(400).json({ message: error });
+    }
+    
+    const result = await swiftCodeService.getSwiftCodesByCountryV2(countryISO2, {
+      sort,
+      type,
+      status,
+      connectivity,
+      fields,
+      paging: parseListingPaging(req.query),
+      timestamps: req.query.includeTimestamps === 'true'
+    });
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Country not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getSwiftCodesByBic8 = async (req, res, next) => {
+  try {
+    const { bic8 } = req.params;
+    
+    const { status } = req.query;
+    
+    if (bic8.length !== 8) {
+      return res.status(400).json({ message: 'BIC8 must be exactly 8 characters' });
+    }
+    
+    const statusError = validateStatusParam(status);
+    if (statusError) {
+      return res.status(400).json({ message: statusError });
+    }
+    
+    const result = await swiftCodeService.getSwiftCodesByBic8(bic8, { status });
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Bank not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getSwiftCodesByCity = async (req, res, next) => {
+  try {
+    const { city } = req.params;
+    const { countryISO2, status } = req.query;
+    
+    if (countryISO2 !== undefined && String(countryISO2).length !== 2) {
+      return res.status(400).json({ message: 'countryISO2 must be exactly 2 characters' });
+    }
+    
+    const statusError = validateStatusParam(status);
+    if (statusError) {
+      return res.status(400).json({ message: statusError });
+    }
+    
+    const result = await swiftCodeService.getSwiftCodesByCity(city, countryISO2 && String(countryISO2), { status });
+    
+    if (!result) {
+      return res.status(404).json({ message: 'No SWIFT codes found in this city' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getSwiftCodesByLei = async (req, res, next) => {
+  try {
+    const { lei } = req.params;
+    
+    if (!isValidLei(lei)) {
+      return res.status(400).json({ message: 'Invalid LEI' });
+    }
+    
+    const result = await swiftCodeService.getSwiftCodesByLei(lei);
+    
+    if (!result) {
+      return res.status(404).json({ message: 'No SWIFT codes found for this LEI' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getSwiftCodesByNationalId = async (req, res, next) => {
+  try {
+    const nationalIdError = validateNationalId(req.params);
+    if (nationalIdError) {
+      return res.status(400).json({ message: nationalIdError });
+    }
+    
+    const { scheme, value } = normalizeNationalId(req.params);
+    const result = await swiftCodeService.getSwiftCodesByNationalId(scheme, value);
+    
+    if (!result) {
+      return res.status(404).json({ message: 'No SWIFT codes found for this national id' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getSwiftCodesNear = async (req, res, next) => {
+  try {
+    const { lat, lng, radiusKm, status } = req.query;
+    const latitude = Number(lat);
+    const longitude = Number(lng);
+    const radius = radiusKm === undefined ? DEFAULT_NEAR_RADIUS_KM : Number(radiusKm);
+    
+    if (lat === undefined || lng === undefined) {
+      return res.status(400).json({ message: 'lat and lng are required' });
+    }
+    
+    const coordinatesError = validateCoordinates(latitude, longitude);
+    if (coordinatesError) {
+      return res.status(400).json({ message: coordinatesError });
+    }
+    
+    if (!(radius > 0) || radius > MAX_NEAR_RADIUS_KM) {
+      return res.status(400).json({ message: `radiusKm must be greater than 0 and at most ${MAX_NEAR_RADIUS_KM}` });
+    }
+    
+    const statusError = validateStatusParam(status);
+    if (statusError) {
+      return res.status(400).json({ message: statusError });
+    }
+    
+    const { limit } = parsePagination(req.query);
+    const result = await swiftCodeService.getSwiftCodesNear(latitude, longitude, radius, { status, limit });
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.searchSwiftCodes = async (req, res, next) => {
+  try {
+    const { q, countryISO2, status } = req.query;
+    
+    const result = await swiftCodeService.searchByBankName(q, {
+      countryISO2,
+      status,
+      ...parsePagination(req.query, 'search')
+    });
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getSwiftCodesByBankName = async (req, res, next) => {
+  try {
+    const { bankName } = req.params;
+    
+    const { status } = req.query;
+    
+    if (!swiftCodeService.normalizeBankName(bankName)) {
+      return res.status(400).json({ message: 'Bank name must not be empty' });
+    }
+    
+    const statusError = validateStatusParam(status);
+    if (statusError) {
+      return res.status(400).json({ message: statusError });
+    }
+    
+    const result = await swiftCodeService.getSwiftCodesByBankName(bankName, { status });
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Bank not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.countSwiftCodes = async (req, res, next) => {
+  try {
+    const { countryISO2, isHeadquarter, bankName, status, connectivity } = req.query;
+    const filter = {};
+    
+    if (countryISO2 !== undefined) {
+      filter.countryISO2 = String(countryISO2);
+    }
+    
+    if (isHeadquarter !== undefined) {
+      filter.isHeadquarter = isHeadquarter === 'true';
+    }
+    
+    if (bankName !== undefined) {
+      filter.bankName = String(bankName);
+    }
+    
+    // Unlike listings, counts cover every status unless one is given
+    if (status !== undefined) {
+      filter.status = status;
+    }
+    
+    if (connectivity !== undefined) {
+      filter.connectivityStatus = connectivity;
+    }
+    
+    const count = await swiftCodeService.countSwiftCodes(filter);
+    
+    res.status(200).json({ count });
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getBankCatalogue = async (req, res, next) => {
+  try {
+    const { bic8 } = req.params;
+    
+    if (bic8.length !== 8) {
+      return res.status(400).json({ message: 'BIC8 must be exactly 8 characters' });
+    }
+    
+    const result = await swiftCodeService.getBankCatalogue(bic8);
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Bank not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.addSwiftCode = async (req, res, next) => {
+  try {
+    // The payload was checked by the createSwiftCode request schema
+    const swiftCodeData = normalizeSwiftCodeData(req.body);
+    
+    // The strictness profile decides which data-quality issues block the write
+    const { errors, warnings } = evaluateWrite(swiftCodeData, resolveStrictness(req));
+    if (errors.length > 0) {
+      return res.status(400).json({ message: errors[0].message, errors });
+    }
+    
+    const created = await swiftCodeService.addSwiftCode(swiftCodeData);
+    
+    // Return the stored, normalized record so clients don't need a follow-up GET
+    res.location(`/v1/swift-codes/${created.swiftCode}`);
+    res.status(201).json({ message: 'SWIFT code added successfully', record: serializeRecord(created), warnings });
+  } catch (error) {
+    if (error.code === 11000) { // MongoDB duplicate key error
+      const deleted = await swiftCodeService.isSoftDeleted(req.body.swiftCode).catch(() => false);
+      res.status(409).json({
+        message: deleted ? 'SWIFT code was deleted, restore it instead' : 'SWIFT code already exists'
+      });
+    } else {
+      next(error);
+    }
+  }
+};
+
+exports.restoreSwiftCode = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const result = await swiftCodeService.restoreSwiftCode(swiftCode, resolveActor(req));
+    
+    if (result.restoredCount === 0) {
+      return res.status(404).json({ message: 'No deleted SWIFT code found' });
+    }
+    
+    res.status(200).json({ message: 'SWIFT code restored successfully' });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// Shared by activate/deactivate; suspensions must say why
+const changeStatus = (status, reasonRequired) => async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const { reason } = req.body || {};
+    
+    if (reason !== undefined && typeof reason !== 'string') {
+      return res.status(400).json({ message: 'Field reason must be a string' });
+    }
+    
+    if (reasonRequired && !(reason && reason.trim())) {
+      return res.status(400).json({ message: 'Missing required field: reason' });
+    }
+    
+    const record = await swiftCodeService.setStatus(swiftCode, status, reason && reason.trim(), resolveActor(req));
+    
+    if (!record) {
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    res.status(200).json({ message: `SWIFT code ${status === 'active' ? 'activated' : 'deactivated'}`, record });
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.deactivateSwiftCode = changeStatus('inactive', true);
+exports.activateSwiftCode = changeStatus('active', false);
+
+// HTTP status each bulk outcome would have had as a single request
+const BULK_STATUS_CODES = {
+  created: 201,
+  duplicate: ERROR_CODES.DUPLICATE_SWIFT_CODE.status,
+  invalid: ERROR_CODES.INVALID_RECORD.status
+};
+
+exports.addSwiftCodesBulk = async (req, res, next) => {
+  try {
+    const records = req.body;
+    
+    if (!Array.isArray(records) || records.length === 0) {
+      return res.status(400).json({ message: 'Request body must be a non-empty array of SWIFT code records' });
+    }
+    
+    if (records.length > appConfig.bulkMaxItems) {
+      return res.status(400).json({ message: `Bulk requests are limited to ${appConfig.bulkMaxItems} records` });
+    }
+    
+    // Validate each row up front, only valid rows reach the database
+    const results = new Array(records.length);
+    const validRows = [];
+    
+    const strictness = resolveStrictness(req);
+    
+    records.forEach((record, index) => {
+      const validationError = validateSwiftCodeData(record);
+      const swiftCode = record && typeof record.swiftCode === 'string' ? record.swiftCode : null;
+      
+      if (validationError) {
+        results[index] = {
+          index,
+          swiftCode,
+          status: 'invalid',
+          statusCode: 400,
+          reason: validationError,
+          error: itemError('INVALID_RECORD', validationError)
+        };
+        return;
+      }
+      
+      const data = normalizeSwiftCodeData(record);
+      const { errors, warnings } = evaluateWrite(data, strictness);
+      
+      if (errors.length > 0) {
+        results[index] = {
+          index,
+          swiftCode,
+          status: 'invalid',
+          statusCode: 400,
+          reason: errors[0].message,
+          error: itemError(errors[0].code, errors[0].message)
+        };
+      } else {
+        validRows.push({ index, data, warnings });
+      }
+    });
+    
+    const outcomes = await swiftCodeService.addSwiftCodesBulk(validRows.map(row => row.data));
+    
+    outcomes.forEach((outcome, i) => {
+      const { index, data, warnings } = validRows[i];
+      const { errorCode, ...rest } = outcome;
+      results[index] = { index, swiftCode: data.swiftCode, ...rest, statusCode: BULK_STATUS_CODES[outcome.status] };
+      
+      // Data-quality warnings only matter for rows that were actually stored
+      if (outcome.status === 'created') {
+        results[index].warnings = warnings;
+      } else {
+        results[index].error = itemError(errorCode, outcome.reason);
+      }
+    });
+    
+    const summary = { total: results.length, created: 0, duplicate: 0, invalid: 0 };
+    results.forEach(result => summary[result.status]++);
+    
+    res.status(multiStatusCode(results)).json({ summary, results });
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.deleteSwiftCode = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const result = await swiftCodeService.deleteSwiftCode(swiftCode, resolveActor(req));
+    
+    if (result.deletedCount === 0) {
+      // Retried deletes of an already deleted code succeed for at-least-once pipelines
+      const idempotent = Boolean(req.get('Idempotency-Key')) || req.query.idempotent === 'true';
+      
+      if (idempotent && await swiftCodeService.isSoftDeleted(swiftCode)) {
+        return res.status(204).end();
+      }
+      
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    res.status(200).json({ message: 'SWIFT code deleted successfully' });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// Returns a message describing what is wrong with a list of codes, or null when it is usable
+const validateCodeList = (swiftCodes) => {
+  if (!Array.isArray(swiftCodes) || swiftCodes.length === 0 || swiftCodes.some(code => typeof code !== 'string')) {
+    return 'swiftCodes must be a non-empty array of strings';
+  }
+  
+  if (swiftCodes.length > appConfig.bulkMaxItems) {
+    return `Bulk requests are limited to ${appConfig.bulkMaxItems} records`;
+  }
+  
+  return null;
+};
+
+const BULK_DELETE_FILTER_FIELDS = {
+  countryISO2: 'string',
+  isHeadquarter: 'boolean',
+  bankName: 'string'
+};
+
+exports.deleteSwiftCodesBulk = async (req, res, next) => {
+  try {
+    const { swiftCodes, filter, confirm } = req.body || {};
+    
+    // Guard against accidental mass deletion
+    if (confirm !== true && req.query.confirm !== 'true') {
+      return res.status(400).json({ message: 'Bulk delete requires confirm: true' });
+    }
+    
+    if (swiftCodes && filter) {
+      return res.status(400).json({ message: 'Provide either swiftCodes or filter, not both' });
+    }
+    
+    if (swiftCodes) {
+      const listError = validateCodeList(swiftCodes);
+      if (listError) {
+        return res.status(400).json({ message: listError });
+      }
+      
+      const locks = await editLockService.findLocksHeldByOthers({ swiftCode: { $in: swiftCodes.map(code => code.toUpperCase()) } }, resolveActor(req));
+      if (locks.length > 0) {
+        return res.status(423).json(lockedResponse(locks));
+      }
+      
+      const result = await swiftCodeService.deleteSwiftCodesByCodes(swiftCodes, resolveActor(req));
+      return res.status(200).json(result);
+    }
+    
+    if (filter) {
+      const fields = typeof filter === 'object' ? Object.keys(filter) : [];
+      
+      if (fields.length === 0) {
+        return res.status(400).json({ message: 'filter must contain at least one criterion' });
+      }
+      
+      for (const field of fields) {
+        if (!BULK_DELETE_FILTER_FIELDS[field]) {
+          return res.status(400).json({ message: `Unsupported filter field: ${field}` });
+        }
+        if (typeof filter[field] !== BULK_DELETE_FILTER_FIELDS[field]) {
+          return res.status(400).json({ message: `Filter field ${field} must be a ${BULK_DELETE_FILTER_FIELDS[field]}` });
+        }
+      }
+      
+      const locks = await editLockService.findLocksHeldByOthers(swiftCodeService.toBulkDeleteQuery(filter), resolveActor(req));
+      if (locks.length > 0) {
+        return res.status(423).json(lockedResponse(locks));
+      }
+      
+      const result = await swiftCodeService.deleteSwiftCodesByFilter(filter, resolveActor(req));
+      return res.status(200).json(result);
+    }
+    
+    res.status(400).json({ message: 'Provide a list of swiftCodes or a filter' });
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.lookupSwiftCodes = async (req, res, next) => {
+  try {
+    const { swiftCodes } = req.body || {};
+    
+    const listError = validateCodeList(swiftCodes);
+    if (listError) {
+      return res.status(400).json({ message: listError });
+    }
+    
+    const results = await swiftCodeService.lookupSwiftCodes(swiftCodes);
+    
+    for (const [code, result] of Object.entries(results)) {
+      if (result.found) {
+        result.statusCode = 200;
+      } else {
+        result.statusCode = ERROR_CODES.NOT_FOUND.status;
+        result.error = itemError('NOT_FOUND', `SWIFT code ${code} not found`);
+      }
+    }
+    
+    res.status(multiStatusCode(Object.values(results))).json({ results });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// Verdict on one code of a bulk or streamed validation
+const checkCode = async (code, index) => {
+  const errors = validateSwiftCodeFormat(code);
+  const valid = errors.length === 0;
+  const result = {
+    index,
+    swiftCode: code,
+    valid,
+    errors,
+    exists: valid ? await swiftCodeService.swiftCodeExists(code) : false,
+    statusCode: valid ? 200 : ERROR_CODES.INVALID_FORMAT.status
+  };
+  
+  if (!valid) {
+    result.error = itemError('INVALID_FORMAT', errors[0]);
+  }
+  return result;
+};
+
+// Long-lived screening channel: the client streams one code per line, either bare or as
+// {"swiftCode": "...", "id": ...}, and each verdict is written as an NDJSON line as soon as it is
+// computed, in input order, while the rest of the request is still arriving. Only line-based bodies
+// qualify: express.json would already have consumed a JSON one
+exports.streamValidation = async (req, res, next) => {
+  if (!req.is(['application/x-ndjson', 'text/plain'])) {
+    return res.status(415).json({ message: 'Send one code per line as application/x-ndjson or text/plain' });
+  }
+  
+  const lines = readline.createInterface({ input: req, crlfDelay: Infinity });
+  const { validationStreamMaxLines: maxLines, validationStreamIdleTimeoutMs: idleTimeout } = appConfig;
+  let index = 0;
+  let cutOff = null;
+  
+  // Clients may take their time between lines, but not forever; like failures, the cut-off reaches
+  // them as a cut stream
+  req.setTimeout(idleTimeout, () => {
+    cutOff = new Error(`No line received for ${idleTimeout} ms`);
+    lines.close();
+  });
+  // A client that went away stops the reading
+  res.on('close', () => lines.close());
+  
+  res.status(200);
+  res.type('application/x-ndjson');
+  // Verdicts go out one by one, so nothing may buffer them
+  res.set('X-Accel-Buffering', 'no');
+  res.flushHeaders();
+  
+  try {
+    for await (const line of lines) {
+      const text = line.trim();
+      if (!text) {
+        continue;
+      }
+      if (index >= maxLines) {
+        throw new Error(`Streams are limited to ${maxLines} lines`);
+      }
+      
+      let input = { swiftCode: text };
+      if (text.startsWith('{')) {
+        try {
+          input = JSON.parse(text);
+        } catch (error) {
+          input = { swiftCode: null };
+        }
+      }
+      
+      const verdict = typeof input.swiftCode === 'string'
+        ? await checkCode(input.swiftCode, index)
+        : {
+          index,
+          swiftCode: null,
+          valid: false,
+          errors: ['Line must be a SWIFT code or a JSON object with a swiftCode string'],
+          exists: false,
+          statusCode: ERROR_CODES.INVALID_RECORD.status,
+          error: itemError('INVALID_RECORD', 'Line must be a SWIFT code or a JSON object with a swiftCode string')
+        };
+      
+      if (input.id !== undefined) {
+        verdict.id = input.id;
+      }
+      index++;
+      
+      await exportService.writeChunk(res, `${JSON.stringify(verdict)}\n`);
+    }
+    
+    if (cutOff) {
+      throw cutOff;
+    }
+    res.end();
+  } catch (error) {
+    // Headers are gone, so the client learns about failures from the cut stream
+    res.destroy(error);
+  }
+};
+
+exports.validateSwiftCode = async (req, res, next) => {
+  try {
+    const { swiftCode, swiftCodes } = req.body || {};
+    
+    // Bulk form: one verdict per code, 207 when any of them is invalid
+    if (swiftCodes !== undefined) {
+      const listError = validateCodeList(swiftCodes);
+      if (listError) {
+        return res.status(400).json({ message: listError });
+      }
+      
+      const results = await Promise.all(swiftCodes.map((code, index) => checkCode(code, index)));
+      
+      return res.status(multiStatusCode(results)).json({ results });
+    }
+    
+    if (typeof swiftCode !== 'string') {
+      return res.status(400).json({ message: 'Missing required field: swiftCode' });
+    }
+    
+    const errors = validateSwiftCodeFormat(swiftCode);
+    const valid = errors.length === 0;
+    
+    // Only well-formed codes are worth a directory lookup
+    const exists = valid ? await swiftCodeService.swiftCodeExists(swiftCode) : false;
+    
+    res.status(200).json({
+      swiftCode,
+      valid,
+      errors,
+      exists
+    });
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.exportSwiftCodes = async (req, res, next) => {
+  const format = (req.query.format || 'json').toLowerCase();
+  const layout = (req.query.layout || 'flat').toLowerCase();
+  
+  if (!exportService.EXPORT_FORMATS.includes(format)) {
+    return res.status(400).json({ message: `Unsupported export format: ${format}` });
+  }
+  
+  if (!exportService.EXPORT_LAYOUTS.includes(layout)) {
+    return res.status(400).json({ message: `Unsupported export layout: ${layout}` });
+  }
+  
+  if (!exportService.supportsLayout(format, layout)) {
+    return res.status(400).json({ message: `The ${layout} layout is not available for ${format} exports` });
+  }
+  
+  // The body is a file, so the page sizes travel in headers
+  const { page, limit, pageSizes } = parsePagination(req.query, 'export');
+  const paging = limit > 0 ? { page, limit } : null;
+  
+  try {
+    res.status(200);
+    res.attachment(`swift-codes.${format}`);
+    if (paging) {
+      res.set({ 'X-Page': String(page), 'X-Page-Limit': String(limit) });
+    }
+    if (pageSizes.maxLimit) {
+      res.set('X-Page-Max-Limit', String(pageSizes.maxLimit));
+    }
+    const hidden = redactedFields(req.consumer);
+    const fields = exportService.EXPORT_FIELDS.filter(field => !hidden.includes(field));
+    await exportService.streamExport(format, res, { layout, paging, fields });
+  } catch (error) {
+    // Once streaming has started the only option left is to abort the download
+    if (res.headersSent) {
+      res.destroy(error);
+    } else {
+      next(error);
+    }
+  }
+};
+
+exports.getSwiftCodesByExternalId = async (req, res, next) => {
+  try {
+    const { system, externalId } = req.params;
+    
+    if (!isValidExternalIdSystem(system)) {
+      return res.status(400).json({ message: `Invalid external id system: ${system}` });
+    }
+    
+    const swiftCodes = await swiftCodeService.getSwiftCodesByExternalId(system, externalId);
+    
+    if (swiftCodes.length === 0) {
+      return res.status(404).json({ message: 'No SWIFT code linked to this external id' });
+    }
+    
+    res.status(200).json({ system, externalId, swiftCodes });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// Only names can be edited in place; everything else is fixed by the directory
+const PATCHABLE_FIELDS = ['bankName', 'localizedNames'];
+
+exports.updateSwiftCode = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const body = req.body || {};
+    const unknown = Object.keys(body).filter(field => !PATCHABLE_FIELDS.includes(field));
+    
+    if (unknown.length > 0 || Object.keys(body).length === 0) {
+      return res.status(400).json({ message: `Only ${PATCHABLE_FIELDS.join(' and ')} can be updated` });
+    }
+    
+    if (body.bankName !== undefined && (typeof body.bankName !== 'string' || !body.bankName.trim())) {
+      return res.status(400).json({ message: 'Field bankName must be a non-empty string' });
+    }
+    
+    if (body.localizedNames !== undefined) {
+      const localizedNamesError = validateLocalizedNames(body.localizedNames);
+      if (localizedNamesError) {
+        return res.status(400).json({ message: localizedNamesError });
+      }
+    }
+    
+    const updated = await swiftCodeService.updateNames(swiftCode, {
+      bankName: body.bankName && body.bankName.trim(),
+      localizedNames: body.localizedNames
+    }, resolveActor(req));
+    
+    if (!updated) {
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    res.status(200).json(updated);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.setExternalId = async (req, res, next) => {
+  try {
+    const { swiftCode, system } = req.params;
+    const { value } = req.body || {};
+    
+    if (!isValidExternalIdSystem(system)) {
+      return res.status(400).json({ message: `Invalid external id system: ${system}` });
+    }
+    if (typeof value !== 'string' || !value.trim()) {
+      return res.status(400).json({ message: 'Missing required field: value' });
+    }
+    
+    const externalIds = await swiftCodeService.setExternalId(swiftCode, system, value, resolveActor(req));
+    
+    if (!externalIds) {
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    res.status(200).json({ swiftCode, externalIds });
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.removeExternalId = async (req, res, next) => {
+  try {
+    const { swiftCode, system } = req.params;
+    
+    if (!isValidExternalIdSystem(system)) {
+      return res.status(400).json({ message: `Invalid external id system: ${system}` });
+    }
+    
+    const externalIds = await swiftCodeService.removeExternalId(swiftCode, system, resolveActor(req));
+    
+    if (!externalIds) {
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    res.status(200).json({ swiftCode, externalIds });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// src/controllers/statsController.js
+const statsService = require('../services/statsService');
+
+exports.getDatasetStats = async (req, res, next) => {
+  try {
+    const result = await statsService.getDatasetStats();
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getCountryRollup = async (req, res, next) => {
+  try {
+    const countries = await statsService.computeCountryRollup();
+    res.status(200).json({ countries });
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getCountryRollupTrend = async (req, res, next) => {
+  try {
+    const { countryISO2 } = req.params;
+    const result = await statsService.getCountryRollupTrend(countryISO2);
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Country not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+// src/controllers/adminController.js
+const auditService = require('../services/auditService');
+const siemService = require('../services/siemService');
+const reportService = require('../services/reportService');
+const dashboardService = require('../services/dashboardService');
+const importRejectService = require('../services/importRejectService');
+const importRunService = require('../services/importRunService');
+const countryService = require('../services/countryService');
+const { previewSwiftCodes, importSwiftCodes, MAPPABLE_FIELDS, ImportRunningError } = require('../utils/dataParser');
+const normalizeRecords = require('../jobs/normalizeRecords');
+const smokeTest = require('../jobs/smokeTest');
+const scheduledImport = require('../jobs/scheduledImport');
+const configReload = require('../config/reload');
+const { parsePagination } = require('../utils/pagination');
+const { resolveActor } = require('../utils/actor');
+const appConfig = require('../config/app');
+const { serializeRecord } = require('../serializers/swiftCodeSerializer');
+
+const MAX_PREVIEW_ROWS = 100;
+
+// The mapping arrives as a JSON form field: { "<record field>": "<column header>" }
+const parseMapping = (value) => {
+  if (value === undefined) {
+    return { mapping: null };
+  }
+  
+  let mapping;
+  try {
+    mapping = JSON.parse(value);
+  } catch (error) {
+    return { error: 'mapping must be a JSON object' };
+  }
+  
+  if (!mapping || typeof mapping !== 'object' || Array.isArray(mapping)) {
+    return { error: 'mapping must be a JSON object' };
+  }
+  
+  const unknown = Object.keys(mapping).filter(field => !MAPPABLE_FIELDS.includes(field));
+  if (unknown.length > 0) {
+    return { error: `Unknown mapping fields: ${unknown.join(', ')}. Mappable fields: ${MAPPABLE_FIELDS.join(', ')}` };
+  }
+  
+  return { mapping };
+};
+
+exports.getAuditLog = async (req, res, next) => {
+  try {
+    const { filter, error } = auditService.buildAuditFilter(req.query);
+    
+    if (error) {
+      return res.status(400).json({ message: error });
+    }
+    
+    if (req.query.format === 'csv') {
+      res.status(200);
+      res.attachment('audit-log.csv');
+      return await auditService.streamAuditCsv(filter, res);
+    }
+    
+    const result = await auditService.findAuditEntries(filter, parsePagination(req.query));
+    
+    res.status(200).json(result);
+  } catch (error) {
+    if (res.headersSent) {
+      res.destroy(error);
+    } else {
+      next(error);
+    }
+  }
+};
+
+// Audit entries and record changes as NDJSON for SIEM ingestion, see siemService for the event layout
+exports.streamSiemEvents = async (req, res, next) => {
+  try {
+    res.status(200);
+    return await siemService.streamEvents(siemService.buildEventFilter(req.query), res);
+  } catch (error) {
+    if (res.headersSent) {
+      res.destroy(error);
+    } else {
+      next(error);
+    }
+  }
+};
+
+exports.getOrphanBranches = async (req, res, next) => {
+  try {
+    const result = await reportService.findOrphanBranches({
+      countryISO2: req.query.countryISO2,
+      ...parsePagination(req.query)
+    });
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+// Dashboard panels show the top entries only
+const dashboardLimit = (query) => parsePagination({ limit: query.limit }, 'dashboard').limit;
+
+exports.getRecentChanges = async (req, res, next) => {
+  try {
+    res.status(200).json(await dashboardService.getRecentChanges({ limit: dashboardLimit(req.query) }));
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getPendingApprovals = async (req, res, next) => {
+  try {
+    res.status(200).json(await dashboardService.getPendingApprovals({ limit: dashboardLimit(req.query) }));
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getFailingImports = async (req, res, next) => {
+  try {
+    res.status(200).json(await dashboardService.getFailingImports({ limit: dashboardLimit(req.query) }));
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getQualityOffenders = async (req, res, next) => {
+  try {
+    res.status(200).json({ offenders: await dashboardService.getQualityOffenders({ limit: dashboardLimit(req.query) }) });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// Rewriting every record outlives the request, so the run goes on in the background and
+// GET /maintenance/normalize reports on it
+exports.normalizeRecords = (req, res) => {
+  const state = normalizeRecords.start(resolveActor(req));
+  
+  if (!state) {
+    return res.status(409).json({ message: 'Normalization is already running', ...normalizeRecords.getState() });
+  }
+  
+  res.status(202).json({ message: 'Normalization started', ...state });
+};
+
+exports.getNormalization = (req, res) => {
+  res.status(200).json(normalizeRecords.getState());
+};
+
+exports.reloadConfig = (req, res, next) => {
+  try {
+    const { reloadedAt, changed } = configReload.reload();
+    
+    res.status(200).json({
+      message: changed.length > 0 ? `Reloaded ${changed.join(', ')}` : 'Configuration unchanged',
+      reloadedAt,
+      changed
+    });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// Failed invariants are part of the report, not an error of the request
+exports.runSmokeTest = async (req, res, next) => {
+  try {
+    const report = await smokeTest.run({ samples: req.body.samples });
+    
+    res.status(200).json(report);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.previewImport = async (req, res, next) => {
+  try {
+    if (!req.file) {
+      return res.status(400).json({ message: 'A CSV file is required in the "file" field' });
+    }
+    
+    const { mapping, error } = parseMapping(req.body.mapping);
+    if (error) {
+      return res.status(400).json({ message: error });
+    }
+    
+    const rows = parseInt(req.body.rows, 10);
+    const preview = await previewSwiftCodes(req.file.buffer, {
+      mapping,
+      rows: rows > 0 ? Math.min(rows, MAX_PREVIEW_ROWS) : undefined
+    });
+    
+    res.status(200).json(preview);
+  } catch (error) {
+    next(error);
+  }
+};
+
+// Run the parser on an uploaded CSV or workbook, which fileUpload stored in a temporary file
+exports.runImport = async (req, res, next) => {
+  if (!req.file) {
+    return res.status(400).json({ message: 'A CSV or XLSX file is required in the "file" field' });
+  }
+  
+  const { mapping, error } = parseMapping(req.body.mapping);
+  if (error) {
+    return res.status(400).json({ message: error });
+  }
+  
+  const filePath = req.file.path;
+  
+  try {
+    const { mode, deactivateMissing, dryRun, sheet, delimiter } = req.body;
+    const options = {
+      mapping,
+      mode,
+      deactivateMissing: deactivateMissing === undefined ? undefined : deactivateMissing === 'true',
+      sheet,
+      delimiter,
+      source: `upload:${req.file.originalname}`,
+      trigger: 'upload'
+    };
+    
+    if (dryRun === 'true') {
+      return res.status(200).json(await importSwiftCodes(filePath, { ...options, dryRun: true }));
+    }
+    
+    const summary = await importSwiftCodes(filePath, options);
+    
+    res.status(200).json({ message: `Imported ${summary.imported} SWIFT code records`, ...summary, importRunId: summary.importRunId.toString() });
+  } catch (error) {
+    if (error instanceof ImportRunningError) {
+      return res.status(409).json({ message: error.message });
+    }
+    next(error);
+  }
+};
+
+exports.getImportSchedule = (req, res) => {
+  res.status(200).json({
+    enabled: Boolean(appConfig.importScheduleSource),
+    source: appConfig.importScheduleSource || null,
+    cron: appConfig.importScheduleCron,
+    timezone: appConfig.importScheduleTimezone,
+    lastRun: scheduledImport.getState()
+  });
+};
+
+exports.getImportRejects = async (req, res, next) => {
+  try {
+    const result = await importRejectService.listRejects(req.params.id, {
+      reason: req.query.reason,
+      field: req.query.field,
+      status: req.query.status,
+      ...parsePagination(req.query)
+    });
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Import run not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getImportChangeset = async (req, res, next) => {
+  try {
+    const result = await importRunService.findChangeset(req.params.id);
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Delta import run not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.resubmitImportReject = async (req, res, next) => {
+  try {
+    const { id, rejectId } = req.params;
+    // Same strictness profile as POST /v1/swift-codes
+    const strictness = (req.consumer && req.consumer.strictness) || appConfig.validationStrictness;
+    const result = await importRejectService.resubmitReject(id, rejectId, req.body, resolveActor(req), strictness);
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Import reject not found' });
+    }
+    
+    if (result.conflict) {
+      return res.status(409).json({ message: result.conflict });
+    }
+    
+    if (result.reasons) {
+      return res.status(400).json({ message: result.reasons[0].message, errors: result.reasons });
+    }
+    
+    res.location(`/v1/swift-codes/${result.record.swiftCode}`);
+    res.status(201).json({
+      message: 'Corrected row imported',
+      reject: result.reject,
+      record: serializeRecord(result.record),
+      warnings: result.warnings
+    });
+  } catch (error) {
+    if (error.code === 11000) { // MongoDB duplicate key error
+      res.status(409).json({ message: 'SWIFT code already exists' });
+    } else {
+      next(error);
+    }
+  }
+};
+
+exports.listCountries = (req, res) => {
+  res.status(200).json({ countries: countryService.listCountries() });
+};
+
+exports.saveCountry = async (req, res, next) => {
+  try {
+    const { country, created, error } = await countryService.saveCountry(req.params.countryISO2, req.body, resolveActor(req));
+    
+    if (error) {
+      return res.status(400).json({ message: error });
+    }
+    
+    res.status(created ? 201 : 200).json(country);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.resetCountry = async (req, res, next) => {
+  try {
+    const { deleted, inUse } = await countryService.resetCountry(req.params.countryISO2, resolveActor(req));
+    
+    if (inUse) {
+      return res.status(409).json({ message: 'Country code is still used by SWIFT codes' });
+    }
+    
+    if (!deleted) {
+      return res.status(404).json({ message: 'No registry change for this country code' });
+    }
+    
+    res.status(200).json({ message: 'Country registry change removed' });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// src/controllers/editLockController.js
+const editLockService = require('../services/editLockService');
+const swiftCodeService = require('../services/swiftCodeService');
+const { resolveActor } = require('../utils/actor');
+
+exports.acquireLock = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    
+    if (!await swiftCodeService.swiftCodeExists(swiftCode)) {
+      return res.status(404).json({ message: 'SWIFT code not found' });
+    }
+    
+    const { acquired, lock } = await editLockService.acquireLock(swiftCode, resolveActor(req));
+    
+    if (!acquired) {
+      return res.status(423).json({ message: `Record is locked by ${lock.lockedBy}`, ...lock });
+    }
+    
+    res.status(200).json(lock);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.heartbeatLock = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const lock = await editLockService.heartbeat(swiftCode, resolveActor(req));
+    
+    if (!lock) {
+      const current = await editLockService.findActiveLock(swiftCode);
+      
+      if (current) {
+        return res.status(423).json({ message: `Record is locked by ${current.lockedBy}`, ...current });
+      }
+      return res.status(404).json({ message: 'No active lock held on this record' });
+    }
+    
+    res.status(200).json(lock);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.releaseLock = async (req, res, next) => {
+  try {
+    const { swiftCode } = req.params;
+    const released = await editLockService.releaseLock(swiftCode, resolveActor(req));
+    
+    if (!released) {
+      return res.status(404).json({ message: 'No lock held on this record' });
+    }
+    
+    res.status(200).json({ message: 'Lock released' });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// src/controllers/bankController.js
+const bankService = require('../services/bankService');
+const swiftCodeService = require('../services/swiftCodeService');
+const editLockService = require('../services/editLockService');
+const { lockedResponse } = require('../middleware/editLockGuard');
+const { parsePagination } = require('../utils/pagination');
+const { resolveActor } = require('../utils/actor');
+
+exports.listBanks = async (req, res, next) => {
+  try {
+    const { countryISO2 } = req.query;
+    
+    if (countryISO2 !== undefined && String(countryISO2).length !== 2) {
+      return res.status(400).json({ message: 'countryISO2 must be exactly 2 characters' });
+    }
+    
+    const result = await bankService.listBanks(
+      { countryISO2: countryISO2 && String(countryISO2) },
+      parsePagination(req.query)
+    );
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+// Coverage of an institution across countries, keyed by its 4-letter bank code
+exports.getInstitution = async (req, res, next) => {
+  try {
+    const { bic4 } = req.params;
+    const { status } = req.query;
+    
+    if (status !== undefined && !swiftCodeService.RECORD_STATUSES.includes(status)) {
+      return res.status(400).json({
+        message: `Invalid status, expected one of: ${swiftCodeService.RECORD_STATUSES.join(', ')}`
+      });
+    }
+    
+    const result = await swiftCodeService.getInstitutionByBankCode(bic4, { status });
+    
+    if (!result) {
+      return res.status(404).json({ message: 'Institution not found' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.getBank = async (req, res, next) => {
+  try {
+    const { bic8 } = req.params;
+    
+    if (!bankService.isValidBic8(bic8)) {
+      return res.status(400).json({ message: 'BIC8 must be 6 letters followed by 2 letters or digits' });
+    }
+    
+    const bank = await bankService.getBank(bic8);
+    
+    if (!bank) {
+      return res.status(404).json({ message: 'Bank not found' });
+    }
+    
+    res.status(200).json(bank);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.createBank = async (req, res, next) => {
+  try {
+    const { bic8, name, countryISO2 } = req.body || {};
+    
+    if (typeof bic8 !== 'string' || !bankService.isValidBic8(bic8)) {
+      return res.status(400).json({ message: 'BIC8 must be 6 letters followed by 2 letters or digits' });
+    }
+    
+    if (typeof name !== 'string' || !name.trim()) {
+      return res.status(400).json({ message: 'Missing required field: name' });
+    }
+    
+    if (countryISO2 !== undefined && (typeof countryISO2 !== 'string' || countryISO2.length !== 2)) {
+      return res.status(400).json({ message: 'countryISO2 must be exactly 2 characters' });
+    }
+    
+    const bank = await bankService.createBank({ bic8, name, countryISO2 });
+    
+    if (!bank) {
+      return res.status(409).json({ message: 'Bank already exists' });
+    }
+    
+    res.status(201).json({ message: 'Bank created successfully', bank });
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.renameBank = async (req, res, next) => {
+  try {
+    const { bic8 } = req.params;
+    const { name } = req.body || {};
+    
+    if (typeof name !== 'string' || !name.trim()) {
+      return res.status(400).json({ message: 'Missing required field: name' });
+    }
+    
+    // The rename rewrites every code of the bank, so a steward editing one of them holds it back
+    const locks = await editLockService.findLocksHeldByOthers({ bic8: bic8.toUpperCase() }, resolveActor(req));
+    if (locks.length > 0) {
+      return res.status(423).json(lockedResponse(locks));
+    }
+    
+    const bank = await bankService.renameBank(bic8, name.trim(), resolveActor(req));
+    
+    if (!bank) {
+      return res.status(404).json({ message: 'Bank not found' });
+    }
+    
+    res.status(200).json(bank);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.deleteBank = async (req, res, next) => {
+  try {
+    const { bic8 } = req.params;
+    const { deleted, hasCodes } = await bankService.deleteBank(bic8);
+    
+    if (hasCodes) {
+      return res.status(409).json({ message: 'Bank still has SWIFT codes, delete them first' });
+    }
+    
+    if (!deleted) {
+      return res.status(404).json({ message: 'Bank not found' });
+    }
+    
+    res.status(200).json({ message: 'Bank deleted successfully' });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// src/controllers/metaController.js
+const { listErrorCodes } = require('../errors/errorCodes');
+
+exports.getErrorCodes = (req, res) => {
+  res.status(200).json({ errorCodes: listErrorCodes() });
+};
+
+// src/controllers/ibanController.js
+const ibanService = require('../services/ibanService');
+
+exports.resolveIban = async (req, res, next) => {
+  try {
+    // The IBAN was checked by the resolveIban request schema
+    const { iban } = req.body;
+    const result = await ibanService.resolveIban(iban);
+    
+    if (!result) {
+      return res.status(404).json({ message: 'No SWIFT code found for the bank of this IBAN' });
+    }
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+// src/controllers/apiKeyController.js
+const apiKeyService = require('../services/apiKeyService');
+
+exports.listKeys = async (req, res, next) => {
+  try {
+    const keys = await apiKeyService.listKeys(req.consumer.name);
+    
+    res.status(200).json({ keys });
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.createKey = async (req, res, next) => {
+  try {
+    const { label } = req.body || {};
+    
+    if (label !== undefined && typeof label !== 'string') {
+      return res.status(400).json({ message: 'Field label must be a string' });
+    }
+    
+    const key = await apiKeyService.createKey(req.consumer, label);
+    
+    if (!key) {
+      return res.status(403).json({ message: 'API key limit reached, revoke a key first' });
+    }
+    
+    res.status(201).json(key);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.rotateKey = async (req, res, next) => {
+  try {
+    const key = await apiKeyService.rotateKey(req.consumer.name, req.params.id);
+    
+    if (!key) {
+      return res.status(404).json({ message: 'API key not found' });
+    }
+    
+    res.status(201).json(key);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.revokeKey = async (req, res, next) => {
+  try {
+    const revoked = await apiKeyService.revokeKey(req.consumer.name, req.params.id);
+    
+    if (!revoked) {
+      return res.status(404).json({ message: 'API key not found' });
+    }
+    
+    res.status(200).json({ message: 'API key revoked' });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// src/controllers/eventController.js
+const mergerEventService = require('../services/mergerEventService');
+const { parsePagination } = require('../utils/pagination');
+const { resolveActor } = require('../utils/actor');
+
+exports.listMergerEvents = async (req, res, next) => {
+  try {
+    const { from, to, bic } = req.query;
+    const result = await mergerEventService.listMergerEvents({ from, to, bic }, parsePagination(req.query));
+    
+    res.status(200).json(result);
+  } catch (error) {
+    next(error);
+  }
+};
+
+exports.recordMergerEvent = async (req, res, next) => {
+  try {
+    const { event, missing } = await mergerEventService.recordMergerEvent(req.body, resolveActor(req));
+    
+    if (missing) {
+      return res.status(404).json({ message: `No SWIFT codes found for: ${missing.join(', ')}` });
+    }
+    
+    res.status(201).json({ message: `Deprecated ${event.affectedCodes.length} codes in favour of the absorbing BIC`, event });
+  } catch (error) {
+    next(error);
+  }
+};
+
+// src/services/swiftCodeService.js
+const SwiftCode = require('../models/swiftCode');
+const historyService = require('./historyService');
+const bankService = require('./bankService');
+const assignmentService = require('./assignmentService');
+const lookupService = require('./lookupService');
+const appConfig = require('../config/app');
+const branchCache = require('../utils/branchCache');
+const { toBic8, decomposeBic, CONNECTIVITY_STATUSES } = require('../utils/bic');
+const { toPoint } = require('../utils/geo');
+const { escapeRegex } = require('../utils/regex');
+const { toSearchName, toSearchNames } = require('../utils/bankNames');
+const { normalizeSwiftCodeData } = require('../utils/swiftCodeValidator');
+const { buildPageInfo, applyPage } = require('../utils/pagination');
+const { serializeRecord, serializeDetail, serializeBranch } = require('../serializers/swiftCodeSerializer');
+const { toProjection, pickFields } = require('../utils/fields');
+
+// Fields the detail lookup needs regardless of the requested fieldset
+const DETAIL_REQUIRED_FIELDS = ['swiftCode', 'isHeadquarter'];
+const DETAIL_STRUCTURAL_KEYS = ['branches', 'branchPagination', 'fallbackApplied', 'requestedSwiftCode'];
+const TIMESTAMP_FIELDS = ['createdAt', 'updatedAt'];
+
+// Record freshness, only added when the caller asked for it
+const withTimestamps = (record, swiftCodeData, enabled) => (enabled
+  ? { ...record, createdAt: swiftCodeData.createdAt, updatedAt: swiftCodeData.updatedAt }
+  : record);
+
+// Records stored before the status field existed count as active
+const statusFilter = (status = 'active') => (status === 'active' ? { $in: ['active', null] } : status);
+
+// Standalone representation of a single record
+const toRecord = (swiftCodeData) => ({
+  address: swiftCodeData.address,
+  bankName: swiftCodeData.bankName,
+  countryISO2: swiftCodeData.countryISO2,
+  countryName: swiftCodeData.countryName,
+  isHeadquarter: swiftCodeData.isHeadquarter,
+  swiftCode: swiftCodeData.swiftCode
+});
+
+// Branches of a headquarter, de-duplicated and never including the HQ itself
+const queryBranches = async (headquarter, projection, { page = 1, limit, pageSizes } = {}) => {
+  // Branches share the headquarter's precomputed bic8
+  const query = {
+    bic8: toBic8(headquarter.swiftCode),
+    isHeadquarter: false,
+    swiftCode: { $ne: headquarter.swiftCode }
+  };
+  
+  const branchQuery = SwiftCode.find(query, projection).sort({ swiftCode: 1 });
+  applyPage(branchQuery, page, limit);
+  
+  const [branches, totalCount] = await Promise.all([
+    branchQuery,
+    limit ? SwiftCode.countDocuments(query) : null
+  ]);
+  
+  const seen = new Set();
+  const unique = branches.filter(branch => !seen.has(branch.swiftCode) && seen.add(branch.swiftCode));
+  
+  return {
+    branches: unique,
+    pagination: limit ? buildPageInfo(page, limit, totalCount, pageSizes) : null
+  };
+};
+
+// Served from the branch cache until a code of the same BIC8 changes
+const findBranches = async (headquarter, projection = null, paging = {}) => {
+  const bic8 = toBic8(headquarter.swiftCode);
+  const key = JSON.stringify([headquarter.swiftCode, projection, paging.page, paging.limit]);
+  const cached = branchCache.get(bic8, key);
+  
+  if (cached) {
+    return cached;
+  }
+  
+  const result = await queryBranches(headquarter, projection, paging);
+  branchCache.set(bic8, key, result);
+  return result;
+};
+
+// Find a record and, when it is a headquarter, its branches
+const findSwiftCodeWithBranches = async (swiftCode, options = {}) => {
+  const requestedCode = swiftCode.toUpperCase();
+  const projection = toProjection(
+    options.fields,
+    options.timestamps ? [...DETAIL_REQUIRED_FIELDS, ...TIMESTAMP_FIELDS] : DETAIL_REQUIRED_FIELDS
+  );
+  
+  // Find the requested SWIFT code
+  let swiftCodeData = await SwiftCode.findOne({ swiftCode: requestedCode }, projection);
+  let fallbackApplied = false;
+  
+  // Unknown branch codes may resolve to their BIC8 headquarters instead
+  if (!swiftCodeData && options.fallbackToHeadquarter && requestedCode.length === 11 && !requestedCode.endsWith('XXX')) {
+    swiftCodeData = await SwiftCode.findOne({ swiftCode: `${requestedCode.substring(0, 8)}XXX` }, projection);
+    fallbackApplied = Boolean(swiftCodeData);
+  }
+  
+  if (!swiftCodeData) {
+    return null;
+  }
+  
+  const { branches, pagination: branchPagination } = swiftCodeData.isHeadquarter
+    ? await findBranches(swiftCodeData, projection, options.branchPaging)
+    : { branches: null, pagination: null };
+  
+  return { requestedCode, swiftCodeData, branches, branchPagination, fallbackApplied };
+};
+
+// Plain lookups, without a fieldset or timestamps, are answered from the lookup read model; a code it
+// doesn't know, such as a BIC8 or an unknown branch, falls through to the full query
+const findDetailsFromLookup = async (swiftCode, options) => {
+  if (!appConfig.lookupReadModel || options.fields || options.timestamps) {
+    return null;
+  }
+  
+  const entry = await lookupService.findDetails(swiftCode, options.branchPaging);
+  if (!entry) {
+    return null;
+  }
+  
+  const response = { ...entry.record };
+  
+  if (options.fallbackToHeadquarter) {
+    response.fallbackApplied = false;
+  }
+  if (entry.branches) {
+    response.branches = entry.branches;
+  }
+  if (entry.branchPagination) {
+    response.branchPagination = entry.branchPagination;
+  }
+  
+  return response;
+};
+
+exports.getSwiftCodeDetails = async (swiftCode, options = {}) => {
+  const fromLookup = await findDetailsFromLookup(swiftCode, options);
+  if (fromLookup) {
+    return fromLookup;
+  }
+  
+  const found = await findSwiftCodeWithBranches(swiftCode, options);
+  
+  if (!found) {
+    return null;
+  }
+  
+  const { requestedCode, swiftCodeData, branches, branchPagination, fallbackApplied } = found;
+  
+  // Format the base response
+  const response = serializeDetail(swiftCodeData);
+  
+  if (options.fallbackToHeadquarter) {
+    response.fallbackApplied = fallbackApplied;
+    if (fallbackApplied) {
+      response.requestedSwiftCode = requestedCode;
+    }
+  }
+  
+  // If this is a headquarters, include branches
+  if (branches) {
+    response.branches = branches.map(branch => withTimestamps(
+      pickFields(serializeBranch(branch), options.fields),
+      branch,
+      options.timestamps
+    ));
+  }
+  
+  if (branchPagination) {
+    response.branchPagination = branchPagination;
+  }
+  
+  return withTimestamps(pickFields(response, options.fields, DETAIL_STRUCTURAL_KEYS), swiftCodeData, options.timestamps);
+};
+
+// v2 shape: HQ, branch and country-listing entries share the canonical record serializer
+exports.getSwiftCodeDetailsV2 = async (swiftCode, options = {}) => {
+  const found = await findSwiftCodeWithBranches(swiftCode, options);
+  
+  if (!found) {
+    return null;
+  }
+  
+  const response = serializeRecord(found.swiftCodeData);
+  
+  if (options.fallbackToHeadquarter) {
+    response.fallbackApplied = found.fallbackApplied;
+    if (found.fallbackApplied) {
+      response.requestedSwiftCode = found.requestedCode;
+    }
+  }
+  
+  if (found.branches) {
+    response.branches = found.branches.map(branch => (
+      withTimestamps(pickFields(serializeRecord(branch), options.fields), branch, options.timestamps)
+    ));
+  }
+  
+  if (found.branchPagination) {
+    response.branchPagination = found.branchPagination;
+  }
+  
+  return withTimestamps(
+    pickFields(response, options.fields, DETAIL_STRUCTURAL_KEYS),
+    found.swiftCodeData,
+    options.timestamps
+  );
+};
+
+exports.getBranches = async (swiftCode, { countryISO2, city, page, limit, pageSizes }) => {
+  const headquarter = await SwiftCode.findOne({ swiftCode: swiftCode.toUpperCase(), isHeadquarter: true });
+  
+  if (!headquarter) {
+    return null;
+  }
+  
+  const query = {
+    bic8: toBic8(headquarter.swiftCode),
+    isHeadquarter: false,
+    swiftCode: { $ne: headquarter.swiftCode }
+  };
+  
+  if (countryISO2) {
+    query.countryISO2 = countryISO2.toUpperCase();
+  }
+  if (city) {
+    query.city = city.trim().toUpperCase();
+  }
+  
+  const [totalCount, branches] = await Promise.all([
+    SwiftCode.countDocuments(query),
+    applyPage(SwiftCode.find(query).sort({ swiftCode: 1 }), page, limit)
+  ]);
+  
+  return {
+    swiftCode: headquarter.swiftCode,
+    branches: branches.map(branch => ({
+      address: branch.address,
+      bankName: branch.bankName,
+      countryISO2: branch.countryISO2,
+      isHeadquarter: branch.isHeadquarter,
+      swiftCode: branch.swiftCode
+    })),
+    pagination: buildPageInfo(page, limit, totalCount, pageSizes)
+  };
+};
+
+// Other active codes of the same institution (bank code) in the same country, for rerouting around a closed branch
+exports.getSiblings = async (swiftCode, { city, status, page, limit, pageSizes }) => {
+  const swiftCodeData = await SwiftCode.findOne({ swiftCode: swiftCode.toUpperCase() });
+  
+  if (!swiftCodeData) {
+    return null;
+  }
+  
+  const { bankCode } = decomposeBic(swiftCodeData.swiftCode);
+  const query = {
+    bankCode,
+    countryISO2: swiftCodeData.countryISO2,
+    swiftCode: { $ne: swiftCodeData.swiftCode },
+    status: statusFilter(status)
+  };
+  
+  if (city) {
+    query.city = city.trim().toUpperCase();
+  }
+  
+  const [totalCount, siblings] = await Promise.all([
+    SwiftCode.countDocuments(query),
+    applyPage(SwiftCode.find(query).sort({ isHeadquarter: -1, swiftCode: 1 }), page, limit)
+  ]);
+  
+  return {
+    swiftCode: swiftCodeData.swiftCode,
+    siblings: siblings.map(sibling => ({
+      address: sibling.address,
+      bankName: sibling.bankName,
+      countryISO2: sibling.countryISO2,
+      isHeadquarter: sibling.isHeadquarter,
+      swiftCode: sibling.swiftCode
+    })),
+    pagination: buildPageInfo(page, limit, totalCount, pageSizes)
+  };
+};
+
+// Interpretations of an 8-character code: its headquarter record (stored as BIC8 or BIC8 + XXX) when
+// there is exactly one, otherwise every code of the institution a caller could have meant
+exports.resolveBic8 = async (bic8) => {
+  const codes = await SwiftCode.find({ bic8: bic8.toUpperCase() }, 'swiftCode isHeadquarter')
+    .sort({ swiftCode: 1 })
+    .lean();
+  const headquarters = codes.filter(code => code.isHeadquarter);
+  
+  if (headquarters.length === 1) {
+    return { resolved: headquarters[0].swiftCode, candidates: [headquarters[0].swiftCode] };
+  }
+  
+  const candidates = (headquarters.length > 1 ? headquarters : codes).map(code => code.swiftCode);
+  return { resolved: null, candidates };
+};
+
+exports.getHeadquarter = async (swiftCode) => {
+  // The headquarter shares the first 8 characters and uses the XXX branch code
+  const headquarter = await SwiftCode.findOne({
+    swiftCode: `${swiftCode.toUpperCase().substring(0, 8)}XXX`,
+    isHeadquarter: true
+  });
+  
+  if (!headquarter) {
+    return null;
+  }
+  
+  return toRecord(headquarter);
+};
+
+// Each option is backed by a (countryISO2, ...) compound index
+const COUNTRY_SORTS = {
+  bankName: { bankName: 1, swiftCode: 1 },
+  swiftCode: { swiftCode: 1 },
+  headquarterFirst: { isHeadquarter: -1, swiftCode: 1 }
+};
+
+exports.COUNTRY_SORT_OPTIONS = Object.keys(COUNTRY_SORTS);
+
+// Served by the (countryISO2, isHeadquarter, swiftCode) compound index
+const COUNTRY_TYPES = {
+  headquarter: true,
+  branch: false
+};
+
+exports.COUNTRY_TYPE_OPTIONS = Object.keys(COUNTRY_TYPES);
+
+// Shared by the v1 and v2 listings; null when the country has no codes at all
+// Totals of the whole filtered listing, with page info when it is paged
+const countCountrySwiftCodes = async (filter, paging) => {
+  const groups = await SwiftCode.aggregate([
+    { $match: filter },
+    { $group: { _id: '$isHeadquarter', count: { $sum: 1 } } }
+  ]);
+  const countOf = (isHeadquarter) => (groups.find(group => group._id === isHeadquarter) || { count: 0 }).count;
+  const headquarterCount = countOf(true);
+  const branchCount = countOf(false);
+  const totalCount = headquarterCount + branchCount;
+  
+  return {
+    ...(paging ? buildPageInfo(paging.page, paging.limit, totalCount, paging.pageSizes) : { totalCount }),
+    headquarterCount,
+    branchCount
+  };
+};
+
+const findCountrySwiftCodes = async (countryISO2, options) => {
+  // Suspended records only show up when asked for explicitly
+  const filter = { countryISO2, status: statusFilter(options.status) };
+  
+  if (options.type) {
+    filter.isHeadquarter = COUNTRY_TYPES[options.type];
+  }
+  
+  if (options.connectivity) {
+    filter.connectivityStatus = options.connectivity;
+  }
+  
+  const query = SwiftCode.find(
+    filter,
+    toProjection(options.fields, options.timestamps ? ['countryName', ...TIMESTAMP_FIELDS] : ['countryName'])
+  );
+  
+  if (options.sort) {
+    query.sort(COUNTRY_SORTS[options.sort]);
+  } else if (options.paging) {
+    // Pages need a stable order
+    query.sort({ swiftCode: 1 });
+  }
+  
+  if (options.paging) {
+    applyPage(query, options.paging.page, options.paging.limit);
+  }
+  
+  const [swiftCodes, meta] = await Promise.all([query, countCountrySwiftCodes(filter, options.paging)]);
+  
+  if (swiftCodes.length > 0) {
+    return { countryName: swiftCodes[0].countryName, swiftCodes, meta };
+  }
+  
+  // Type and status filters can leave a known country empty, which is still a valid listing
+  const anyCode = await SwiftCode.findOne({ countryISO2 }, 'countryName');
+  return anyCode ? { countryName: anyCode.countryName, swiftCodes, meta } : null;
+};
+
+exports.getSwiftCodesByCountry = async (countryISO2, options = {}) => {
+  // Find all SWIFT codes for the given country
+  const found = await findCountrySwiftCodes(countryISO2.toUpperCase(), options);
+  
+  if (!found) {
+    return null;
+  }
+  
+  const { swiftCodes } = found;
+  
+  // Format the response
+  const response = {
+    countryISO2: countryISO2.toUpperCase(),
+    countryName: found.countryName, // All records for this country should have the same name
+    swiftCodes: swiftCodes.map(code => withTimestamps(pickFields({
+      address: code.address,
+      bankName: code.bankName,
+      countryISO2: code.countryISO2,
+      isHeadquarter: code.isHeadquarter,
+      swiftCode: code.swiftCode
+    }, options.fields), code, options.timestamps)),
+    meta: found.meta
+  };
+  
+  return response;
+};
+
+exports.getSwiftCodesByBic8 = async (bic8, options = {}) => {
+  // Every 11-character code of the institution, headquarter first
+  const swiftCodes = await SwiftCode.find({ bic8: toBic8(bic8), status: statusFilter(options.status) })
+    .sort({ isHeadquarter: -1, swiftCode: 1 });
+  
+  if (swiftCodes.length === 0) {
+    return null;
+  }
+  
+  return {
+    bic8: bic8.toUpperCase(),
+    swiftCodes: swiftCodes.map(code => ({
+      address: code.address,
+      bankName: code.bankName,
+      countryISO2: code.countryISO2,
+      isHeadquarter: code.isHeadquarter,
+      swiftCode: code.swiftCode
+    }))
+  };
+};
+
+// Every country an institution operates in, from the 4-letter bank code shared across its BIC8s
+exports.getInstitutionByBankCode = async (bankCode, options = {}) => {
+  const code = bankCode.toUpperCase();
+  
+  const countries = await SwiftCode.aggregate([
+    { $match: { bankCode: code, status: statusFilter(options.status) } },
+    { $sort: { countryISO2: 1, isHeadquarter: -1, swiftCode: 1 } },
+    {
+      $group: {
+        _id: '$countryISO2',
+        countryName: { $first: '$countryName' },
+        bic8s: { $addToSet: '$bic8' },
+        swiftCodes: {
+          $push: {
+            address: '$address',
+            bankName: '$bankName',
+            countryISO2: '$countryISO2',
+            isHeadquarter: '$isHeadquarter',
+            swiftCode: '$swiftCode'
+          }
+        }
+      }
+    },
+    { $sort: { _id: 1 } }
+  ]);
+  
+  if (countries.length === 0) {
+    return null;
+  }
+  
+  return {
+    bankCode: code,
+    countryCount: countries.length,
+    codeCount: countries.reduce((count, country) => count + country.swiftCodes.length, 0),
+    countries: countries.map(country => ({
+      countryISO2: country._id,
+      countryName: country.countryName,
+      bic8s: country.bic8s.sort(),
+      swiftCodes: country.swiftCodes
+    }))
+  };
+};
+
+exports.getSwiftCodesByCity = async (city, countryISO2, options = {}) => {
+  const query = { city: city.trim().toUpperCase(), status: statusFilter(options.status) };
+  
+  if (countryISO2) {
+    query.countryISO2 = countryISO2.toUpperCase();
+  }
+  
+  const swiftCodes = await SwiftCode.find(query).sort({ bankName: 1, swiftCode: 1 });
+  
+  if (swiftCodes.length === 0) {
+    return null;
+  }
+  
+  return {
+    city: query.city,
+    swiftCodes: swiftCodes.map(code => ({
+      address: code.address,
+      bankName: code.bankName,
+      countryISO2: code.countryISO2,
+      isHeadquarter: code.isHeadquarter,
+      swiftCode: code.swiftCode
+    }))
+  };
+};
+
+exports.getSwiftCodesByLei = async (lei) => {
+  const swiftCodes = await SwiftCode.find({ lei: lei.toUpperCase() }).sort({ isHeadquarter: -1, swiftCode: 1 });
+  
+  if (swiftCodes.length === 0) {
+    return null;
+  }
+  
+  return {
+    lei: lei.toUpperCase(),
+    swiftCodes: swiftCodes.map(code => ({
+      address: code.address,
+      bankName: code.bankName,
+      countryISO2: code.countryISO2,
+      isHeadquarter: code.isHeadquarter,
+      swiftCode: code.swiftCode
+    }))
+  };
+};
+
+exports.getSwiftCodesByNationalId = async (scheme, value) => {
+  const swiftCodes = await SwiftCode.find({ nationalIds: { $elemMatch: { scheme, value } } })
+    .sort({ isHeadquarter: -1, swiftCode: 1 });
+  
+  if (swiftCodes.length === 0) {
+    return null;
+  }
+  
+  return {
+    scheme,
+    value,
+    swiftCodes: swiftCodes.map(code => ({
+      address: code.address,
+      bankName: code.bankName,
+      countryISO2: code.countryISO2,
+      isHeadquarter: code.isHeadquarter,
+      swiftCode: code.swiftCode
+    }))
+  };
+};
+
+// Prefix match over the primary and localized names of every bank; the anchored regex lets MongoDB
+// walk the searchNames index instead of scanning every record
+exports.searchByBankName = async (query, options = {}) => {
+  const { countryISO2, status, page, limit, pageSizes } = options;
+  const pattern = `^${escapeRegex(toSearchName(query))}`;
+  const filter = {
+    // Records stored before searchNames existed are matched on bankName alone
+    $or: [
+      { searchNames: { $regex: pattern } },
+      { searchNames: { $exists: false }, bankName: { $regex: pattern, $options: 'i' } }
+    ],
+    status: statusFilter(status)
+  };
+  
+  if (countryISO2) {
+    filter.countryISO2 = countryISO2.toUpperCase();
+  }
+  
+  const [swiftCodes, totalCount] = await Promise.all([
+    applyPage(SwiftCode.find(filter).sort({ bankName: 1, swiftCode: 1 }), page, limit),
+    SwiftCode.countDocuments(filter)
+  ]);
+  
+  return {
+    query,
+    swiftCodes: swiftCodes.map(code => ({
+      address: code.address,
+      bankName: code.bankName,
+      countryISO2: code.countryISO2,
+      isHeadquarter: code.isHeadquarter,
+      swiftCode: code.swiftCode,
+      ...(code.localizedNames && code.localizedNames.size > 0 && {
+        localizedNames: Object.fromEntries(code.localizedNames)
+      })
+    })),
+    pagination: buildPageInfo(page, limit, totalCount, pageSizes)
+  };
+};
+
+// Nearest records first; $geoNear needs the 2dsphere index on location
+exports.getSwiftCodesNear = async (latitude, longitude, radiusKm, options = {}) => {
+  const { status, limit } = options;
+  
+  const swiftCodes = await SwiftCode.aggregate([
+    {
+      $geoNear: {
+        near: toPoint(latitude, longitude),
+        distanceField: 'distanceMeters',
+        maxDistance: radiusKm * 1000,
+        spherical: true,
+        query: { status: statusFilter(status) }
+      }
+    },
+    { $limit: limit }
+  ]);
+  
+  return {
+    latitude,
+    longitude,
+    radiusKm,
+    swiftCodes: swiftCodes.map(code => ({
+      address: code.address,
+      bankName: code.bankName,
+      countryISO2: code.countryISO2,
+      isHeadquarter: code.isHeadquarter,
+      swiftCode: code.swiftCode,
+      latitude: code.location.coordinates[1],
+      longitude: code.location.coordinates[0],
+      distanceKm: Math.round(code.distanceMeters) / 1000
+    }))
+  };
+};
+
+// Collation matching the bankName index, so lookups ignore case
+const BANK_NAME_COLLATION = { locale: 'en', strength: 2 };
+
+exports.normalizeBankName = (bankName) => bankName.trim().replace(/\s+/g, ' ');
+
+exports.getSwiftCodesByBankName = async (bankName, options = {}) => {
+  const normalized = exports.normalizeBankName(bankName);
+  
+  const swiftCodes = await SwiftCode.find({ bankName: normalized, status: statusFilter(options.status) })
+    .collation(BANK_NAME_COLLATION)
+    .sort({ bankName: 1, swiftCode: 1 });
+  
+  if (swiftCodes.length === 0) {
+    return null;
+  }
+  
+  return {
+    bankName: normalized,
+    swiftCodes: swiftCodes.map(code => ({
+      address: code.address,
+      bankName: code.bankName,
+      countryISO2: code.countryISO2,
+      isHeadquarter: code.isHeadquarter,
+      swiftCode: code.swiftCode
+    }))
+  };
+};
+
+exports.countSwiftCodes = async (filter) => {
+  const query = { ...filter };
+  
+  if (query.status) {
+    query.status = statusFilter(query.status);
+  }
+  
+  return await SwiftCode.countDocuments(query);
+};
+
+// Country listing bucketed by the structured city field; records without a city share a null bucket
+exports.getSwiftCodesByCountryGroupedByCity = async (countryISO2, options = {}) => {
+  const match = { countryISO2: countryISO2.toUpperCase(), status: statusFilter(options.status) };
+  
+  if (options.type) {
+    match.isHeadquarter = COUNTRY_TYPES[options.type];
+  }
+  if (options.connectivity) {
+    match.connectivityStatus = options.connectivity;
+  }
+  
+  const cities = await SwiftCode.aggregate([
+    { $match: match },
+    { $sort: { city: 1, ...(COUNTRY_SORTS[options.sort] || COUNTRY_SORTS.swiftCode) } },
+    {
+      $group: {
+        _id: { $ifNull: ['$city', null] },
+        countryName: { $first: '$countryName' },
+        swiftCodes: {
+          $push: {
+            address: '$address',
+            bankName: '$bankName',
+            countryISO2: '$countryISO2',
+            isHeadquarter: '$isHeadquarter',
+            swiftCode: '$swiftCode'
+          }
+        }
+      }
+    },
+    { $sort: { _id: 1 } }
+  ]);
+  
+  if (cities.length === 0) {
+    return null;
+  }
+  
+  return {
+    countryISO2: match.countryISO2,
+    countryName: cities[0].countryName,
+    cities: cities.map(group => ({ city: group._id, swiftCodes: group.swiftCodes }))
+  };
+};
+
+exports.getBankCatalogue = async (bic8) => {
+  // Find every code of the institution, headquarter included
+  const swiftCodes = await SwiftCode.find({ bic8: toBic8(bic8) })
+    .sort({ swiftCode: 1 });
+  
+  if (swiftCodes.length === 0) {
+    return null;
+  }
+  
+  const headquarter = swiftCodes.find(code => code.isHeadquarter) || swiftCodes[0];
+  
+  return {
+    bic8: bic8.toUpperCase(),
+    bankName: headquarter.bankName,
+    countryISO2: headquarter.countryISO2,
+    countryName: headquarter.countryName,
+    branches: swiftCodes.map(code => ({
+      swiftCode: code.swiftCode,
+      branchCode: code.swiftCode.substring(8) || 'XXX',
+      isHeadquarter: code.isHeadquarter,
+      address: code.address,
+      branchDescription: code.branchDescription || null
+    }))
+  };
+};
+
+exports.getSwiftCodesByCountryV2 = async (countryISO2, options = {}) => {
+  const found = await findCountrySwiftCodes(countryISO2.toUpperCase(), options);
+  
+  if (!found) {
+    return null;
+  }
+  
+  return {
+    countryISO2: countryISO2.toUpperCase(),
+    countryName: found.countryName,
+    swiftCodes: found.swiftCodes.map(code => (
+      withTimestamps(pickFields(serializeRecord(code), options.fields), code, options.timestamps)
+    )),
+    meta: found.meta
+  };
+};
+
+exports.addSwiftCode = async (swiftCodeData) => {
+  const created = await SwiftCode.create(swiftCodeData);
+  await bankService.syncBanks([created.bic8]);
+  return created;
+};
+
+exports.addSwiftCodesBulk = async (records) => {
+  const outcomes = new Array(records.length);
+  const documents = [];
+  const positions = [];
+  
+  // Run schema validation first so insertMany error indexes line up with the documents sent;
+  // validate() rather than validateSync() so the hooks deriving isHeadquarter and friends run
+  const validated = records.map(record => new SwiftCode(record));
+  const validationErrors = await Promise.all(validated.map(document => document.validate().then(() => null, error => error)));
+  
+  validated.forEach((document, index) => {
+    if (validationErrors[index]) {
+      outcomes[index] = { status: 'invalid', errorCode: 'INVALID_RECORD', reason: validationErrors[index].message };
+    } else {
+      documents.push(document);
+      positions.push(index);
+    }
+  });
+  
+  let writeErrors = [];
+  if (documents.length > 0) {
+    try {
+      // Unordered so one duplicate doesn't abort the rest of the batch
+      await SwiftCode.insertMany(documents, { ordered: false });
+    } catch (error) {
+      if (!error.writeErrors) {
+        throw error;
+      }
+      writeErrors = [].concat(error.writeErrors);
+    }
+  }
+  
+  const failed = new Map(writeErrors.map(writeError => [writeError.index, writeError]));
+  await bankService.syncBanks(documents.filter((document, i) => !failed.has(i)).map(document => document.bic8));
+  
+  documents.forEach((document, i) => {
+    const writeError = failed.get(i);
+    
+    if (!writeError) {
+      outcomes[positions[i]] = { status: 'created' };
+    } else if (writeError.code === 11000) { // MongoDB duplicate key error
+      outcomes[positions[i]] = { status: 'duplicate', errorCode: 'DUPLICATE_SWIFT_CODE', reason: 'SWIFT code already exists' };
+    } else {
+      outcomes[positions[i]] = { status: 'invalid', errorCode: 'INVALID_RECORD', reason: writeError.errmsg };
+    }
+  });
+  
+  return outcomes;
+};
+
+// Update a single record and keep a revision of its values before and after
+const updateWithHistory = async (filter, update, action, actor) => {
+  const previous = await SwiftCode.findOneAndUpdate(filter, update, { new: false });
+  
+  if (!previous) {
+    return null;
+  }
+  
+  const current = await SwiftCode.findById(previous._id).setOptions({ withDeleted: true });
+  await historyService.recordRevision({ action, actor, previous, current });
+  await bankService.syncBanks([current.bic8]);
+  
+  return current;
+};
+
+exports.deleteSwiftCode = async (swiftCode, actor) => {
+  // Soft delete - the record stays restorable
+  const deleted = await updateWithHistory(
+    { swiftCode: swiftCode.toUpperCase() },
+    { $set: { deletedAt: new Date() } },
+    'delete',
+    actor
+  );
+  
+  return { deletedCount: deleted ? 1 : 0 };
+};
+
+exports.restoreSwiftCode = async (swiftCode, actor) => {
+  const restored = await updateWithHistory(
+    { swiftCode: swiftCode.toUpperCase(), deletedAt: { $ne: null } },
+    { $set: { deletedAt: null } },
+    'restore',
+    actor
+  );
+  
+  return { restoredCount: restored ? 1 : 0 };
+};
+
+// Names are the only attributes edited in place; search names are derived again from the result
+exports.updateNames = async (swiftCode, { bankName, localizedNames }, actor) => {
+  const code = swiftCode.toUpperCase();
+  const existing = await SwiftCode.findOne({ swiftCode: code });
+  
+  if (!existing) {
+    return null;
+  }
+  
+  const names = {
+    bankName: bankName !== undefined ? bankName : existing.bankName,
+    localizedNames: localizedNames !== undefined ? localizedNames : existing.localizedNames
+  };
+  
+  const updated = await updateWithHistory(
+    { swiftCode: code },
+    { $set: { ...names, searchNames: toSearchNames(names.bankName, names.localizedNames) } },
+    'update-names',
+    actor
+  );
+  
+  return updated ? serializeRecord(updated) : null;
+};
+
+// Hand a code over to another institution from effectiveDate on; attributes of the previous holder
+// the request doesn't replace are dropped. Returns { conflict } when the date would overlap the
+// current holder's window
+exports.reassignSwiftCode = async (swiftCode, { effectiveDate, bankName, address, city, lei }, actor) => {
+  const code = swiftCode.toUpperCase();
+  const existing = await SwiftCode.findOne({ swiftCode: code });
+  
+  if (!existing) {
+    return null;
+  }
+  
+  const effective = new Date(effectiveDate);
+  const open = await assignmentService.findOpenAssignment(code);
+  if (open && open.validFrom && effective <= open.validFrom) {
+    return { conflict: `The current holder has had ${code} since ${open.validFrom.toISOString()}, reassign it after that` };
+  }
+  
+  const holder = { bankName, address, city, lei };
+  // Normalized like any other write; the country comes with the code and stays
+  const replaced = normalizeSwiftCodeData({
+    ...Object.fromEntries(Object.entries(holder).filter(([, value]) => value !== undefined)),
+    countryISO2: existing.countryISO2
+  });
+  const dropped = ['city', 'lei', 'originalAddress', 'localizedNames', 'nationalIds'].filter(field => !(field in replaced));
+  
+  const updated = await updateWithHistory(
+    { swiftCode: code },
+    {
+      $set: { ...replaced, searchNames: toSearchNames(bankName) },
+      $unset: Object.fromEntries(dropped.map(field => [field, '']))
+    },
+    'reassign',
+    actor
+  );
+  await assignmentService.recordReassignment(existing, updated, effective);
+  
+  return { record: serializeRecord(updated), assignments: await assignmentService.listAssignments(code, updated) };
+};
+
+// Holders of a code over time, or the one holding it on a given date; null for unknown codes
+exports.getAssignments = async (swiftCode, at) => {
+  const code = swiftCode.toUpperCase();
+  const live = await SwiftCode.findOne({ swiftCode: code }).lean();
+  const assignments = await assignmentService.listAssignments(code, live);
+  
+  if (assignments.length === 0) {
+    return null;
+  }
+  
+  if (at) {
+    return { swiftCode: code, at, assignment: await assignmentService.findAssignmentAt(code, at, live) };
+  }
+  return { swiftCode: code, assignments };
+};
+
+exports.RECORD_STATUSES = SwiftCode.RECORD_STATUSES;
+exports.statusFilter = statusFilter;
+exports.CONNECTIVITY_STATUSES = CONNECTIVITY_STATUSES;
+
+// Flip a record's status, keeping the reason and time of the change
+exports.setStatus = async (swiftCode, status, reason, actor) => {
+  const updated = await updateWithHistory(
+    { swiftCode: swiftCode.toUpperCase() },
+    { $set: { status, statusReason: reason, statusChangedAt: new Date() } },
+    'status-change',
+    actor
+  );
+  
+  return updated ? serializeRecord(updated) : null;
+};
+
+exports.getHistory = async (swiftCode, pagination) => {
+  const history = await historyService.getHistory(swiftCode, pagination);
+  
+  // Deleted records keep their history, unknown codes have none
+  if (history.pagination.totalCount === 0
+    && !await SwiftCode.exists({ swiftCode: swiftCode.toUpperCase() }).setOptions({ withDeleted: true })) {
+    return null;
+  }
+  
+  return history;
+};
+
+exports.diffHistory = (swiftCode, from, to) => historyService.diffVersions(swiftCode, from, to);
+
+exports.isSoftDeleted = async (swiftCode) => {
+  return Boolean(await SwiftCode.exists({ swiftCode: swiftCode.toUpperCase(), deletedAt: { $ne: null } }));
+};
+
+exports.deleteSwiftCodesByCodes = async (swiftCodes, actor) => {
+  const codes = [...new Set(swiftCodes.map(code => code.toUpperCase()))];
+  
+  // Look up which codes exist so each one can be reported individually
+  const existing = await SwiftCode.find({ swiftCode: { $in: codes } });
+  const existingCodes = new Set(existing.map(code => code.swiftCode));
+  const changes = { deletedAt: new Date() };
+  
+  const result = await SwiftCode.updateMany(
+    { _id: { $in: existing.map(code => code._id) } },
+    { $set: changes }
+  );
+  await historyService.recordBulkRevisions(existing, changes, 'delete', actor);
+  await bankService.syncBanks(existing.map(code => code.bic8));
+  
+  return {
+    totalDeleted: result.modifiedCount,
+    deleted: codes.map(code => ({
+      criterion: 'swiftCode',
+      value: code,
+      deletedCount: existingCodes.has(code) ? 1 : 0
+    }))
+  };
+};
+
+// MongoDB query for a bulk delete filter, once the controller has checked its fields
+exports.toBulkDeleteQuery = (filter) => {
+  const query = {};
+  
+  if (filter.countryISO2 !== undefined) {
+    query.countryISO2 = filter.countryISO2.toUpperCase();
+  }
+  if (filter.isHeadquarter !== undefined) {
+    query.isHeadquarter = filter.isHeadquarter;
+  }
+  if (filter.bankName !== undefined) {
+    query.bankName = filter.bankName;
+  }
+  
+  return query;
+};
+
+exports.deleteSwiftCodesByFilter = async (filter, actor) => {
+  const query = exports.toBulkDeleteQuery(filter);
+  const existing = await SwiftCode.find(query);
+  const changes = { deletedAt: new Date() };
+  
+  const result = await SwiftCode.updateMany(
+    { _id: { $in: existing.map(code => code._id) } },
+    { $set: changes }
+  );
+  await historyService.recordBulkRevisions(existing, changes, 'delete', actor);
+  await bankService.syncBanks(existing.map(code => code.bic8));
+  
+  return {
+    totalDeleted: result.modifiedCount,
+    deleted: [{ criterion: 'filter', value: query, deletedCount: result.modifiedCount }]
+  };
+};
+
+exports.lookupSwiftCodes = async (swiftCodes) => {
+  const codes = [...new Set(swiftCodes.map(code => code.toUpperCase()))];
+  
+  // Resolve every code with a single query
+  const found = await SwiftCode.find({ swiftCode: { $in: codes } });
+  const byCode = new Map(found.map(code => [code.swiftCode, code]));
+  
+  const results = {};
+  for (const code of codes) {
+    const swiftCodeData = byCode.get(code);
+    
+    results[code] = swiftCodeData ? { found: true, ...toRecord(swiftCodeData) } : { found: false };
+  }
+  
+  return results;
+};
+
+exports.swiftCodeExists = async (swiftCode) => {
+  const code = swiftCode.toUpperCase();
+  
+  // A lookup entry proves the code exists; its absence only means the entry isn't built yet
+  if (appConfig.lookupReadModel && await lookupService.hasEntry(code)) {
+    return true;
+  }
+  return Boolean(await SwiftCode.exists({ swiftCode: code }));
+};
+
+exports.getSwiftCodesByExternalId = async (system, externalId) => {
+  const swiftCodes = await SwiftCode.find({ [`externalIds.${system}`]: externalId }).sort({ swiftCode: 1 });
+  return swiftCodes.map(toRecord);
+};
+
+exports.setExternalId = async (swiftCode, system, value, actor) => {
+  const updated = await updateWithHistory(
+    { swiftCode: swiftCode.toUpperCase() },
+    { $set: { [`externalIds.${system}`]: value } },
+    'set-external-id',
+    actor
+  );
+  
+  return updated ? Object.fromEntries(updated.externalIds || []) : null;
+};
+
+exports.removeExternalId = async (swiftCode, system, actor) => {
+  const updated = await updateWithHistory(
+    { swiftCode: swiftCode.toUpperCase() },
+    { $unset: { [`externalIds.${system}`]: '' } },
+    'remove-external-id',
+    actor
+  );
+  
+  return updated ? Object.fromEntries(updated.externalIds || []) : null;
+};
+
+// src/services/bankService.js
+const Bank = require('../models/bank');
+const SwiftCode = require('../models/swiftCode');
+const historyService = require('./historyService');
+const { buildPageInfo, applyPage } = require('../utils/pagination');
+const { toSearchNames } = require('../utils/bankNames');
+
+const BIC8_PATTERN = /^[A-Z]{6}[A-Z0-9]{2}$/;
+
+exports.isValidBic8 = (bic8) => BIC8_PATTERN.test(String(bic8).toUpperCase());
+
+const toBank = (bank) => ({
+  bic8: bank.bic8,
+  name: bank.name,
+  countryISO2: bank.countryISO2,
+  headquarter: bank.headquarter ? bank.headquarter.swiftCode : null,
+  branches: bank.branches.map(branch => branch.swiftCode),
+  createdAt: bank.createdAt,
+  updatedAt: bank.updatedAt
+});
+
+const findBank = (filter) => Bank.findOne(filter)
+  .populate('headquarter', 'swiftCode')
+  .populate({ path: 'branches', select: 'swiftCode', options: { sort: { swiftCode: 1 } } });
+
+// Recompute the references (and name) of the given banks from their current SWIFT codes
+exports.syncBanks = async (bic8s) => {
+  const unique = [...new Set(bic8s.filter(Boolean))];
+  
+  if (unique.length === 0) {
+    return;
+  }
+  
+  const codes = await SwiftCode.find({ bic8: { $in: unique } }, 'swiftCode bic8 bankName countryISO2 isHeadquarter')
+    .sort({ swiftCode: 1 });
+  const byBic8 = new Map(unique.map(bic8 => [bic8, []]));
+  codes.forEach(code => byBic8.get(code.bic8).push(code));
+  
+  const operations = [...byBic8].map(([bic8, bankCodes]) => {
+    // Banks whose last code went away keep their entry, just without references
+    if (bankCodes.length === 0) {
+      return { updateOne: { filter: { bic8 }, update: { $set: { headquarter: null, branches: [] } } } };
+    }
+    
+    const headquarter = bankCodes.find(code => code.isHeadquarter);
+    
+    return {
+      updateOne: {
+        filter: { bic8 },
+        update: {
+          $set: {
+            name: (headquarter || bankCodes[0]).bankName,
+            countryISO2: bankCodes[0].countryISO2,
+            headquarter: headquarter ? headquarter._id : null,
+            branches: bankCodes.filter(code => !code.isHeadquarter).map(code => code._id)
+          }
+        },
+        upsert: true
+      }
+    };
+  });
+  
+  await Bank.bulkWrite(operations, { ordered: false });
+};
+
+// Imports replace the whole collection, so every bank is recomputed
+exports.rebuildBanks = async () => {
+  const bic8s = [...await SwiftCode.distinct('bic8'), ...await Bank.distinct('bic8')];
+  await exports.syncBanks(bic8s);
+};
+
+exports.listBanks = async ({ countryISO2 }, { page, limit, pageSizes }) => {
+  const filter = countryISO2 ? { countryISO2: countryISO2.toUpperCase() } : {};
+  
+  const [banks, totalCount] = await Promise.all([
+    applyPage(Bank.find(filter).sort({ bic8: 1 }), page, limit)
+      .populate('headquarter', 'swiftCode')
+      .populate({ path: 'branches', select: 'swiftCode', options: { sort: { swiftCode: 1 } } }),
+    Bank.countDocuments(filter)
+  ]);
+  
+  return { banks: banks.map(toBank), pagination: buildPageInfo(page, limit, totalCount, pageSizes) };
+};
+
+exports.getBank = async (bic8) => {
+  const bank = await findBank({ bic8: bic8.toUpperCase() });
+  return bank ? toBank(bank) : null;
+};
+
+// Returns null when the bank already exists
+exports.createBank = async ({ bic8, name, countryISO2 }) => {
+  try {
+    await Bank.create({ bic8, name, countryISO2 });
+  } catch (error) {
+    if (error.code === 11000) {
+      return null;
+    }
+    throw error;
+  }
+  
+  // Codes stored before the bank was registered are attached straight away
+  await exports.syncBanks([bic8.toUpperCase()]);
+  return exports.getBank(bic8);
+};
+
+// Renaming a bank renames every one of its SWIFT codes too
+exports.renameBank = async (bic8, name, actor) => {
+  const code = bic8.toUpperCase();
+  const bank = await Bank.findOneAndUpdate({ bic8: code }, { $set: { name } });
+  
+  if (!bank) {
+    return null;
+  }
+  
+  const existing = await SwiftCode.find({ bic8: code });
+  const changes = { bankName: name };
+  
+  // Search names depend on each record's localized names, so every record gets its own update
+  if (existing.length > 0) {
+    await SwiftCode.bulkWrite(existing.map(swiftCode => ({
+      updateOne: {
+        filter: { _id: swiftCode._id },
+        update: { $set: { ...changes, searchNames: toSearchNames(name, swiftCode.localizedNames) } }
+      }
+    })));
+    await SwiftCode.invalidateInstitutions(code);
+  }
+  await historyService.recordBulkRevisions(existing, changes, 'rename-bank', actor);
+  
+  return exports.getBank(code);
+};
+
+// Banks can only be removed once none of their codes remain
+exports.deleteBank = async (bic8) => {
+  const code = bic8.toUpperCase();
+  
+  if (await SwiftCode.exists({ bic8: code })) {
+    return { deleted: false, hasCodes: true };
+  }
+  
+  const result = await Bank.deleteOne({ bic8: code });
+  return { deleted: result.deletedCount > 0, hasCodes: false };
+};
+
+// src/services/ibanService.js
+const SwiftCode = require('../models/swiftCode');
+const { parseIban } = require('../utils/iban');
+const { serializeRecord } = require('../serializers/swiftCodeSerializer');
+const { statusFilter } = require('./swiftCodeService');
+
+// National bank codes without a nationalIds equivalent on the records, mapped to the institution's BIC8;
+// keys are matched as prefixes of the IBAN bank code, e.g. the first four digits of a Polish settlement number
+const BANK_CODE_TABLE = {
+  AT: { '12000': 'BKAUATWW', '20111': 'GIBAATWW' },
+  BE: { '001': 'GEBABEBB', '310': 'BBRUBEBB' },
+  ES: { '0049': 'BSCHESMM', '2100': 'CAIXESBB' },
+  FR: { '30003': 'SOGEFRPP', '30004': 'BNPAFRPP' },
+  IT: { '02008': 'UNCRITMM', '03069': 'BCITITMM' },
+  PL: { '1020': 'BPKOPLPW', '1050': 'INGBPLPW', '1140': 'BREXPLPW' }
+};
+
+const findInTable = (countryISO2, bankCode) => {
+  const table = BANK_CODE_TABLE[countryISO2] || {};
+  const prefix = Object.keys(table)
+    .filter(key => bankCode.startsWith(key))
+    .sort((a, b) => b.length - a.length)[0];
+  return prefix ? table[prefix] : null;
+};
+
+// Try the clearing code stored on the records, then the BIC bank code, then the mapping table
+const findSwiftCode = async ({ countryISO2, bankCode, nationalId }) => {
+  if (nationalId) {
+    const match = await SwiftCode.findOne({
+      countryISO2,
+      nationalIds: { $elemMatch: nationalId },
+      status: statusFilter()
+    }).sort({ isHeadquarter: -1, swiftCode: 1 });
+    
+    if (match) {
+      return { resolvedBy: 'national-id', record: match };
+    }
+  }
+  
+  if (/^[A-Z]{4}$/.test(bankCode)) {
+    const match = await SwiftCode.findOne({ bankCode, countryISO2, isHeadquarter: true, status: statusFilter() })
+      .sort({ swiftCode: 1 });
+    
+    if (match) {
+      return { resolvedBy: 'bank-code', record: match };
+    }
+  }
+  
+  const bic8 = findInTable(countryISO2, bankCode);
+  if (bic8) {
+    const match = await SwiftCode.findOne({ bic8, isHeadquarter: true, status: statusFilter() });
+    
+    if (match) {
+      return { resolvedBy: 'mapping-table', record: match };
+    }
+  }
+  
+  return null;
+};
+
+// Map a valid IBAN to the SWIFT record of its bank, or null when none is known
+exports.resolveIban = async (iban) => {
+  const parsed = parseIban(iban);
+  const found = await findSwiftCode(parsed);
+  
+  if (!found) {
+    return null;
+  }
+  
+  return {
+    iban: parsed.iban,
+    countryISO2: parsed.countryISO2,
+    bankCode: parsed.bankCode,
+    branchCode: parsed.branchCode,
+    resolvedBy: found.resolvedBy,
+    record: serializeRecord(found.record)
+  };
+};
+
+// src/services/apiKeyService.js
+const crypto = require('crypto');
+const mongoose = require('mongoose');
+const ApiKey = require('../models/apiKey');
+const appConfig = require('../config/app');
+
+const KEY_PREFIX = 'swk_';
+
+const hashKey = (apiKey) => crypto.createHash('sha256').update(apiKey).digest('hex');
+
+// Not revoked and, for rotated keys, still within the grace period
+const liveFilter = (now = new Date()) => ({
+  revokedAt: null,
+  $or: [{ expiresAt: null }, { expiresAt: { $gt: now } }]
+});
+
+const toKeyInfo = (key) => ({
+  id: String(key._id),
+  prefix: key.prefix,
+  label: key.label,
+  createdAt: key.createdAt,
+  expiresAt: key.expiresAt,
+  revokedAt: key.revokedAt
+});
+
+const issueKey = async (owner, settings, label) => {
+  const apiKey = `${KEY_PREFIX}${crypto.randomBytes(24).toString('hex')}`;
+  const record = await ApiKey.create({
+    keyHash: hashKey(apiKey),
+    prefix: apiKey.substring(0, KEY_PREFIX.length + 6),
+    owner,
+    label,
+    role: settings.role,
+    redactionProfile: settings.redactionProfile,
+    strictness: settings.strictness,
+    keyLimit: settings.keyLimit,
+    parentKeyHash: settings.parentKeyHash
+  });
+  
+  return { apiKey, ...toKeyInfo(record) };
+};
+
+const isConfiguredKey = (keyHash) => Object.keys(appConfig.apiKeys).some(apiKey => hashKey(apiKey) === keyHash);
+
+// Consumer behind a configured key, remembering which entry it is so keys created with it can be traced back
+exports.fromConfiguredKey = (apiKey) => {
+  const consumer = appConfig.apiKeys[apiKey];
+  return consumer ? { ...consumer, parentKeyHash: hashKey(apiKey) } : null;
+};
+
+// Consumer behind a stored key, shaped like the API_KEYS configuration entries; keys created from a
+// configured key are rejected once that key has been removed from API_KEYS
+exports.findConsumer = async (apiKey) => {
+  const key = await ApiKey.findOne({ keyHash: hashKey(apiKey), ...liveFilter() }).lean();
+  
+  if (!key || (key.parentKeyHash && !isConfiguredKey(key.parentKeyHash))) {
+    return null;
+  }
+  
+  return {
+    name: key.owner,
+    role: key.role,
+    redactionProfile: key.redactionProfile,
+    strictness: key.strictness,
+    keyLimit: key.keyLimit,
+    parentKeyHash: key.parentKeyHash
+  };
+};
+
+exports.listKeys = async (owner) => {
+  const keys = await ApiKey.find({ owner }).sort({ createdAt: -1 }).lean();
+  return keys.map(toKeyInfo);
+};
+
+// Returns null once the owner holds as many live keys as the admin allows
+exports.createKey = async (consumer, label) => {
+  const limit = consumer.keyLimit || appConfig.apiKeyLimitPerConsumer;
+  const live = await ApiKey.countDocuments({ owner: consumer.name, ...liveFilter() });
+  
+  if (live >= limit) {
+    return null;
+  }
+  
+  return issueKey(consumer.name, consumer, label);
+};
+
+// Issue a replacement and let the old key keep working for the grace period
+exports.rotateKey = async (owner, id) => {
+  if (!mongoose.isValidObjectId(id)) {
+    return null;
+  }
+  
+  const expiresAt = new Date(Date.now() + appConfig.apiKeyRotationGraceMs);
+  const previous = await ApiKey.findOneAndUpdate(
+    { _id: id, owner, ...liveFilter() },
+    { $set: { expiresAt } },
+    { new: false }
+  );
+  
+  if (!previous) {
+    return null;
+  }
+  
+  const replacement = await issueKey(owner, previous, previous.label);
+  return { ...replacement, previousKeyExpiresAt: expiresAt };
+};
+
+exports.revokeKey = async (owner, id) => {
+  if (!mongoose.isValidObjectId(id)) {
+    return false;
+  }
+  
+  const result = await ApiKey.updateOne({ _id: id, owner, revokedAt: null }, { $set: { revokedAt: new Date() } });
+  return result.modifiedCount > 0;
+};
+
+// src/services/growthMonitor.js
+const SwiftCode = require('../models/swiftCode');
+const appConfig = require('../config/app');
+
+// Keep roughly a day of samples at the default five minute cadence
+const MAX_SAMPLES = 288;
+const MIN_DELTAS_FOR_ALERTS = 5;
+
+const samples = [];
+let timer = null;
+
+const mean = (values) => values.reduce((sum, value) => sum + value, 0) / values.length;
+
+const standardDeviation = (values) => {
+  const average = mean(values);
+  return Math.sqrt(mean(values.map(value => (value - average) ** 2)));
+};
+
+// Warn when the latest growth is far outside the recent norm, e.g. a runaway client
+const checkGrowth = () => {
+  const deltas = samples.slice(1).map((sample, i) => sample.count - samples[i].count);
+  
+  if (deltas.length <= MIN_DELTAS_FOR_ALERTS) {
+    return;
+  }
+  
+  const latest = deltas[deltas.length - 1];
+  const history = deltas.slice(0, -1);
+  const limit = mean(history) + appConfig.growthAlertThreshold * standardDeviation(history);
+  
+  if (latest > 0 && latest > limit) {
+    console.warn(`Abnormal SWIFT code collection growth: +${latest} records since last sample (expected at most ${Math.round(limit)})`);
+  }
+};
+
+exports.takeSample = async () => {
+  const count = await SwiftCode.estimatedDocumentCount();
+  
+  samples.push({ at: new Date(), count });
+  if (samples.length > MAX_SAMPLES) {
+    samples.shift();
+  }
+  
+  checkGrowth();
+  return count;
+};
+
+exports.getSamples = () => [...samples];
+
+exports.start = () => {
+  if (timer) {
+    return;
+  }
+  
+  const sample = () => exports.takeSample().catch(error => console.error('Failed to sample collection size', error));
+  
+  sample();
+  timer = setInterval(sample, appConfig.growthSampleIntervalMs);
+  timer.unref();
+};
+
+exports.stop = () => {
+  clearInterval(timer);
+  timer = null;
+};
+
+// src/services/integrityCheck.js
+const SwiftCode = require('../models/swiftCode');
+const metricsService = require('./metricsService');
+
+// Critical invariants break lookups outright, the others only degrade answers
+const INVARIANTS = [
+  {
+    name: 'unique-codes',
+    critical: true,
+    count: async () => {
+      const [result] = await SwiftCode.aggregate([
+        { $group: { _id: '$swiftCode', count: { $sum: 1 } } },
+        { $match: { count: { $gt: 1 } } },
+        { $count: 'duplicates' }
+      ]).option({ withDeleted: true });
+      return result ? result.duplicates : 0;
+    }
+  },
+  {
+    name: 'indexes-present',
+    critical: true,
+    count: async () => {
+      const existing = new Set((await SwiftCode.collection.indexes()).map(index => JSON.stringify(index.key)));
+      return SwiftCode.schema.indexes().filter(([fields]) => !existing.has(JSON.stringify(fields))).length;
+    }
+  },
+  {
+    name: 'uppercase-normalization',
+    critical: false,
+    count: () => SwiftCode.countDocuments({
+      $or: [{ swiftCode: /[a-z]/ }, { countryISO2: /[a-z]/ }, { countryName: /[a-z]/ }]
+    }).setOptions({ withDeleted: true })
+  },
+  {
+    name: 'headquarter-suffix',
+    critical: false,
+    count: () => SwiftCode.countDocuments({
+      $or: [
+        // 8-character codes denote the primary office as well
+        { isHeadquarter: true, swiftCode: { $not: /^.{8}(XXX)?$/ } },
+        { isHeadquarter: false, swiftCode: /^.{8}(XXX)?$/ }
+      ]
+    }).setOptions({ withDeleted: true })
+  }
+];
+
+// 'skipped' until a check is requested, then 'running', 'passed' or 'failed'
+let state = { status: 'skipped', violations: [] };
+
+exports.run = async () => {
+  state = { status: 'running', violations: [] };
+  
+  try {
+    const violations = [];
+    
+    for (const invariant of INVARIANTS) {
+      const count = await invariant.count();
+      
+      metricsService.recordIntegrityViolations(invariant.name, invariant.critical, count);
+      if (count > 0) {
+        const log = invariant.critical ? console.error : console.warn;
+        log(`Integrity check: ${count} violation(s) of ${invariant.name}`);
+        violations.push({ invariant: invariant.name, critical: invariant.critical, count });
+      }
+    }
+    
+    state = {
+      status: violations.some(violation => violation.critical) ? 'failed' : 'passed',
+      checkedAt: new Date(),
+      violations
+    };
+  } catch (error) {
+    // An unverifiable dataset is not ready either
+    console.error('Integrity check could not complete', error);
+    state = { status: 'failed', checkedAt: new Date(), violations: [], error: error.message };
+  }
+  
+  return exports.getState();
+};
+
+exports.getState = () => ({ ...state, violations: [...state.violations] });
+
+exports.isReady = () => state.status === 'skipped' || state.status === 'passed';
+
+// src/services/statsService.js
+const SwiftCode = require('../models/swiftCode');
+const ImportRun = require('../models/importRun');
+
+exports.getDatasetStats = async () => {
+  const [totals] = await SwiftCode.aggregate([
+    {
+      $group: {
+        _id: null,
+        totalCodes: { $sum: 1 },
+        headquarters: { $sum: { $cond: ['$isHeadquarter', 1, 0] } },
+        countries: { $addToSet: '$countryISO2' },
+        banks: { $addToSet: { $substrCP: ['$swiftCode', 0, 8] } }
+      }
+    },
+    {
+      $project: {
+        _id: 0,
+        totalCodes: 1,
+        headquarters: 1,
+        countries: { $size: '$countries' },
+        banks: { $size: '$banks' }
+      }
+    }
+  ]);
+  
+  const lastImport = await ImportRun.findOne(ImportRun.COMPLETED).sort({ completedAt: -1 });
+  const stats = totals || { totalCodes: 0, headquarters: 0, countries: 0, banks: 0 };
+  
+  return {
+    totalCodes: stats.totalCodes,
+    headquarters: stats.headquarters,
+    branches: stats.totalCodes - stats.headquarters,
+    countries: stats.countries,
+    banks: stats.banks,
+    lastImportAt: lastImport ? lastImport.completedAt : null
+  };
+};
+
+// Count institutions (distinct BIC8 prefixes) and locations (codes) per country
+exports.computeCountryRollup = async (countryISO2) => {
+  const match = countryISO2 ? { countryISO2 } : {};
+  
+  return await SwiftCode.aggregate([
+    { $match: match },
+    {
+      $group: {
+        _id: { countryISO2: '$countryISO2', bic8: { $substrCP: ['$swiftCode', 0, 8] } },
+        locations: { $sum: 1 }
+      }
+    },
+    {
+      $group: {
+        _id: '$_id.countryISO2',
+        institutions: { $sum: 1 },
+        locations: { $sum: '$locations' }
+      }
+    },
+    { $sort: { _id: 1 } },
+    { $project: { _id: 0, countryISO2: '$_id', institutions: 1, locations: 1 } }
+  ]);
+};
+
+exports.getCountryRollupTrend = async (countryISO2) => {
+  // Rollups captured at each import, oldest first
+  const runs = await ImportRun.find(
+    { 'countryRollup.countryISO2': countryISO2 },
+    { completedAt: 1, countryRollup: { $elemMatch: { countryISO2 } } }
+  ).sort({ completedAt: 1 });
+  
+  const [current] = await exports.computeCountryRollup(countryISO2);
+  
+  if (!current && runs.length === 0) {
+    return null;
+  }
+  
+  return {
+    countryISO2,
+    current: current || { countryISO2, institutions: 0, locations: 0 },
+    trend: runs.map(run => ({
+      importRunId: run._id,
+      importedAt: run.completedAt,
+      institutions: run.countryRollup[0].institutions,
+      locations: run.countryRollup[0].locations
+    }))
+  };
+};
+
+// src/services/reportService.js
+const SwiftCode = require('../models/swiftCode');
+const { buildPageInfo, pageStages } = require('../utils/pagination');
+
+// Branches whose BIC8 + XXX headquarter is missing (or soft-deleted), which HQ responses can't show
+exports.findOrphanBranches = async ({ countryISO2, page, limit, pageSizes }) => {
+  const match = { isHeadquarter: false };
+  
+  if (countryISO2) {
+    match.countryISO2 = countryISO2.toUpperCase();
+  }
+  
+  const [result] = await SwiftCode.aggregate([
+    { $match: match },
+    { $addFields: { expectedHeadquarter: { $concat: [{ $substrCP: ['$swiftCode', 0, 8] }, 'XXX'] } } },
+    {
+      $lookup: {
+        from: SwiftCode.collection.name,
+        localField: 'expectedHeadquarter',
+        foreignField: 'swiftCode',
+        pipeline: [{ $project: { deletedAt: 1 } }],
+        as: 'headquarter'
+      }
+    },
+    { $match: { headquarter: { $not: { $elemMatch: { deletedAt: null } } } } },
+    { $sort: { swiftCode: 1 } },
+    {
+      $facet: {
+        total: [{ $count: 'count' }],
+        headquarters: [{ $group: { _id: '$expectedHeadquarter' } }, { $count: 'count' }],
+        branches: [
+          ...pageStages(page, limit),
+          {
+            $project: {
+              _id: 0,
+              swiftCode: 1,
+              bankName: 1,
+              countryISO2: 1,
+              expectedHeadquarter: 1
+            }
+          }
+        ]
+      }
+    }
+  ]);
+  
+  const totalCount = result.total.length > 0 ? result.total[0].count : 0;
+  
+  return {
+    missingHeadquarterCount: result.headquarters.length > 0 ? result.headquarters[0].count : 0,
+    branches: result.branches,
+    pagination: buildPageInfo(page, limit, totalCount, pageSizes)
+  };
+};
+
+// src/services/dashboardService.js
+const SwiftCode = require('../models/swiftCode');
+const SwiftCodeRevision = require('../models/swiftCodeRevision');
+const ImportRun = require('../models/importRun');
+const ImportReject = require('../models/importReject');
+const { listCountries } = require('../utils/countries');
+
+// Aggregates behind the back-office dashboard, each answering one panel in a single call
+
+const RECENT_WINDOW_MS = 24 * 60 * 60 * 1000;
+
+// Latest changes to the reference data, with how many of each kind happened in the last day
+exports.getRecentChanges = async ({ limit }) => {
+  const since = new Date(Date.now() - RECENT_WINDOW_MS);
+  
+  const [changes, byAction] = await Promise.all([
+    SwiftCodeRevision.find({}, { swiftCode: 1, version: 1, action: 1, actor: 1, at: 1 }).sort({ at: -1 }).limit(limit).lean(),
+    SwiftCodeRevision.aggregate([
+      { $match: { at: { $gte: since } } },
+      { $group: { _id: '$action', count: { $sum: 1 } } },
+      { $sort: { count: -1, _id: 1 } }
+    ])
+  ]);
+  
+  return {
+    since,
+    changeCount: byAction.reduce((total, entry) => total + entry.count, 0),
+    byAction: byAction.map(entry => ({ action: entry._id, count: entry.count })),
+    changes: changes.map(({ swiftCode, version, action, actor, at }) => ({ swiftCode, version, action, actor, at }))
+  };
+};
+
+// What waits on a steward: records held in the pending status and rejected import rows nobody has resubmitted yet
+exports.getPendingApprovals = async ({ limit }) => {
+  const [pendingRecords, pendingRecordCount, openRejects] = await Promise.all([
+    SwiftCode.find({ status: 'pending' }, 'swiftCode bankName countryISO2 statusReason statusChangedAt')
+      .sort({ statusChangedAt: 1, swiftCode: 1 })
+      .limit(limit)
+      .lean(),
+    SwiftCode.countDocuments({ status: 'pending' }),
+    ImportReject.aggregate([
+      { $match: { resubmittedAt: null } },
+      { $group: { _id: '$importRun', count: { $sum: 1 } } },
+      { $sort: { _id: -1 } }
+    ])
+  ]);
+  
+  const runs = await ImportRun.find({ _id: { $in: openRejects.slice(0, limit).map(entry => entry._id) } }, 'source completedAt').lean();
+  const runsById = new Map(runs.map(run => [run._id.toString(), run]));
+  
+  return {
+    pendingRecordCount,
+    pendingRecords: pendingRecords.map(record => ({
+      swiftCode: record.swiftCode,
+      bankName: record.bankName,
+      countryISO2: record.countryISO2,
+      statusReason: record.statusReason || null,
+      statusChangedAt: record.statusChangedAt || null
+    })),
+    openRejectCount: openRejects.reduce((total, entry) => total + entry.count, 0),
+    openRejectsByImport: openRejects.slice(0, limit).map((entry) => {
+      const run = runsById.get(entry._id.toString());
+      
+      return {
+        importRunId: entry._id.toString(),
+        source: run ? run.source || null : null,
+        completedAt: run ? run.completedAt : null,
+        count: entry.count
+      };
+    })
+  };
+};
+
+// Latest imports that rejected rows, newest first, with how many of those rows are still open
+exports.getFailingImports = async ({ limit }) => {
+  const [runs, lastScheduled] = await Promise.all([
+    ImportRun.find(
+      { $or: [{ status: 'failed' }, { rejectedCount: { $gt: 0 } }] },
+      'status error trigger source mode startedAt completedAt recordCount rejectedCount'
+    )
+      .sort({ completedAt: -1 })
+      .limit(limit)
+      .lean(),
+    ImportRun.findOne({ trigger: 'schedule' }, 'status error source startedAt').sort({ completedAt: -1 }).lean()
+  ]);
+  
+  const open = await ImportReject.aggregate([
+    { $match: { importRun: { $in: runs.map(run => run._id) }, resubmittedAt: null } },
+    { $group: { _id: '$importRun', count: { $sum: 1 } } }
+  ]);
+  const openByRun = new Map(open.map(entry => [entry._id.toString(), entry.count]));
+  
+  return {
+    // The last scheduled import, only when it failed
+    scheduledImport: lastScheduled && lastScheduled.status === 'failed'
+      ? { status: 'failed', source: lastScheduled.source, startedAt: lastScheduled.startedAt, error: lastScheduled.error }
+      : null,
+    imports: runs.map(run => ({
+      importRunId: run._id.toString(),
+      status: run.status || 'completed',
+      error: run.error || null,
+      trigger: run.trigger || null,
+      source: run.source || null,
+      mode: run.mode || null,
+      completedAt: run.completedAt,
+      recordCount: run.recordCount === undefined ? null : run.recordCount,
+      rejectedCount: run.rejectedCount,
+      openRejectCount: openByRun.get(run._id.toString()) || 0
+    }))
+  };
+};
+
+const QUALITY_CHECKS = ['COUNTRY_MISMATCH', 'HEADQUARTER_MISMATCH', 'UNKNOWN_COUNTRY'];
+
+// Institutions with the most stored data-quality issues, using the rules of checkDataQuality
+exports.getQualityOffenders = async ({ limit }) => {
+  const knownCountries = listCountries().filter(country => !country.withdrawn).map(country => country.countryISO2);
+  const flag = (condition) => ({ $cond: [condition, 1, 0] });
+  
+  const offenders = await SwiftCode.aggregate([
+    {
+      $project: {
+        bic8: 1,
+        bankName: 1,
+        COUNTRY_MISMATCH: flag({ $ne: [{ $substrCP: ['$swiftCode', 4, 2] }, '$countryISO2'] }),
+        // 8-character codes are headquarters too
+        HEADQUARTER_MISMATCH: flag({ $ne: ['$isHeadquarter', { $in: [{ $substrCP: ['$swiftCode', 8, 3] }, ['XXX', '']] }] }),
+        UNKNOWN_COUNTRY: flag({ $not: [{ $in: ['$countryISO2', knownCountries] }] })
+      }
+    },
+    { $addFields: { issueCount: { $add: QUALITY_CHECKS.map(check => `$${check}`) } } },
+    { $match: { issueCount: { $gt: 0 } } },
+    {
+      $group: {
+        _id: '$bic8',
+        bankName: { $first: '$bankName' },
+        codeCount: { $sum: 1 },
+        issueCount: { $sum: '$issueCount' },
+        ...Object.fromEntries(QUALITY_CHECKS.map(check => [check, { $sum: `$${check}` }]))
+      }
+    },
+    { $sort: { issueCount: -1, _id: 1 } },
+    { $limit: limit }
+  ]);
+  
+  return offenders.map(offender => ({
+    bic8: offender._id,
+    bankName: offender.bankName,
+    codeCount: offender.codeCount,
+    issueCount: offender.issueCount,
+    issues: Object.fromEntries(QUALITY_CHECKS.map(check => [check, offender[check]]))
+  }));
+};
+
+// src/services/importRejectService.js
+const mongoose = require('mongoose');
+const ImportReject = require('../models/importReject');
+const swiftCodeService = require('./swiftCodeService');
+const { swiftCodeRecordSchema, normalizeSwiftCodeData, VALIDATION_OPTIONS } = require('../utils/swiftCodeValidator');
+const { normalizeInput } = require('../utils/inputNormalizer');
+const { evaluateWrite } = require('../utils/dataQuality');
+const { buildPageInfo, applyPage } = require('../utils/pagination');
+
+exports.REJECT_REASONS = ImportReject.REJECT_REASONS;
+exports.REJECT_STATUSES = ['open', 'resubmitted'];
+
+const reasonCode = (fieldError) => {
+  if (fieldError.kind === 'required') {
+    return 'MISSING_FIELD';
+  }
+  if (fieldError.path === 'swiftCode') {
+    return 'INVALID_FORMAT';
+  }
+  if (fieldError.path === 'countryISO2') {
+    return 'UNKNOWN_COUNTRY';
+  }
+  return 'INVALID_RECORD';
+};
+
+// Reason codes of the record schema problems that block a resubmitted row
+const joiReasonCode = (detail) => {
+  if (detail.type === 'any.required' || detail.type === 'string.empty') {
+    return 'MISSING_FIELD';
+  }
+  return detail.path[0] === 'swiftCode' ? 'INVALID_FORMAT' : 'INVALID_RECORD';
+};
+
+// One reason per failing field of a mongoose validation error, ordered by field
+exports.toRejectReasons = (validationError) => Object.values(validationError.errors)
+  .map(fieldError => ({ code: reasonCode(fieldError), field: fieldError.path, message: fieldError.message }))
+  .sort((a, b) => a.field.localeCompare(b.field));
+
+const serializeReject = (reject) => ({
+  id: reject._id.toString(),
+  line: reject.line,
+  record: reject.record,
+  reasons: reject.reasons,
+  status: reject.resubmittedAt ? 'resubmitted' : 'open',
+  resubmittedAt: reject.resubmittedAt,
+  resubmittedBy: reject.resubmittedBy || null,
+  resubmittedSwiftCode: reject.resubmittedSwiftCode || null
+});
+
+// Rejected rows of one import in file order; null when the import doesn't exist
+exports.listRejects = async (importRunId, { reason, field, status, page, limit, pageSizes }) => {
+  if (!mongoose.isValidObjectId(importRunId)) {
+    return null;
+  }
+  
+  const filter = { importRun: importRunId };
+  
+  if (reason || field) {
+    filter.reasons = { $elemMatch: { ...(reason && { code: reason }), ...(field && { field }) } };
+  }
+  
+  if (status) {
+    filter.resubmittedAt = status === 'open' ? null : { $ne: null };
+  }
+  
+  const [rejects, totalCount, reasonCounts] = await Promise.all([
+    applyPage(ImportReject.find(filter).sort({ line: 1 }), page, limit).lean(),
+    ImportReject.countDocuments(filter),
+    ImportReject.aggregate([
+      { $match: { importRun: new mongoose.Types.ObjectId(importRunId) } },
+      { $unwind: '$reasons' },
+      { $group: { _id: '$reasons.code', count: { $sum: 1 } } },
+      { $sort: { _id: 1 } }
+    ])
+  ]);
+  
+  return {
+    importRunId,
+    // Over the whole import, so stewards see what is left to fix whatever the filter
+    reasonCounts: reasonCounts.map(entry => ({ code: entry._id, count: entry.count })),
+    rejects: rejects.map(serializeReject),
+    pagination: buildPageInfo(page, limit, totalCount, pageSizes)
+  };
+};
+
+// Apply a steward's corrections to a rejected row and store it, checked like POST /v1/swift-codes under
+// the caller's strictness; null when the reject doesn't exist, { conflict } when it was already
+// resubmitted and { reasons } when the corrected row is still invalid
+exports.resubmitReject = async (importRunId, rejectId, corrections, actor, strictness) => {
+  if (!mongoose.isValidObjectId(importRunId) || !mongoose.isValidObjectId(rejectId)) {
+    return null;
+  }
+  
+  const reject = await ImportReject.findOne({ _id: rejectId, importRun: importRunId });
+  
+  if (!reject) {
+    return null;
+  }
+  
+  if (reject.resubmittedAt) {
+    return { conflict: `Reject was already resubmitted as ${reject.resubmittedSwiftCode}` };
+  }
+  
+  // Stored rows are as read from the file, so they get the same normalization as request bodies
+  const corrected = normalizeInput({ ...reject.record, ...corrections });
+  const { error } = swiftCodeRecordSchema.validate(corrected, VALIDATION_OPTIONS);
+  
+  if (error) {
+    return {
+      reasons: error.details.map(detail => ({ code: joiReasonCode(detail), field: detail.path.join('.'), message: detail.message }))
+    };
+  }
+  
+  const swiftCodeData = normalizeSwiftCodeData(corrected);
+  const { errors, warnings } = evaluateWrite(swiftCodeData, strictness);
+  
+  if (errors.length > 0) {
+    return { reasons: errors };
+  }
+  
+  const created = await swiftCodeService.addSwiftCode(swiftCodeData);
+  
+  reject.resubmittedAt = new Date();
+  reject.resubmittedBy = actor;
+  reject.resubmittedSwiftCode = created.swiftCode;
+  await reject.save();
+  
+  return { reject: serializeReject(reject), record: created, warnings };
+};
+
+// src/services/importRunService.js
+const mongoose = require('mongoose');
+const ImportRun = require('../models/importRun');
+
+// Keep a failed import for the dashboard; the mode is left out when it was the reason for the failure
+exports.recordFailure = async ({ source, mode, trigger, startedAt }, error) => ImportRun.create({
+  status: 'failed',
+  error: error.message,
+  trigger,
+  source,
+  ...(ImportRun.schema.path('mode').enumValues.includes(mode) && { mode }),
+  startedAt,
+  completedAt: new Date()
+});
+
+// Changeset a delta import applied, with its counts; null for unknown runs and runs of other modes
+exports.findChangeset = async (importRunId) => {
+  if (!mongoose.isValidObjectId(importRunId)) {
+    return null;
+  }
+  
+  const run = await ImportRun.findOne({ _id: importRunId, mode: 'delta', ...ImportRun.COMPLETED }).lean();
+  if (!run) {
+    return null;
+  }
+  
+  return {
+    importRunId: run._id,
+    source: run.source || null,
+    completedAt: run.completedAt,
+    addedCount: run.addedCount,
+    modifiedCount: run.modifiedCount,
+    removedCount: run.removedCount,
+    unchangedCount: run.unchangedCount,
+    changeset: run.changeset || { added: [], modified: [], removed: [] }
+  };
+};
+
+// src/services/exportService.js
+const crypto = require('crypto');
+const { once } = require('events');
+const { Readable } = require('stream');
+const { finished } = require('stream/promises');
+const archiver = require('archiver');
+const ExcelJS = require('exceljs');
+const SwiftCode = require('../models/swiftCode');
+const ImportRun = require('../models/importRun');
+
+// 'zip' is the partner distribution bundle: CSV, NDJSON and a manifest
+const EXPORT_FORMATS = ['csv', 'json', 'zip', 'xlsx'];
+const EXPORT_FIELDS = ['swiftCode', 'bankName', 'address', 'countryISO2', 'countryName', 'isHeadquarter'];
+// 'nested' groups each institution's codes under its headquarter, JSON only
+const EXPORT_LAYOUTS = ['flat', 'nested'];
+
+// fields narrows the columns, e.g. to what the caller's redaction profile leaves
+const toExportRecord = (doc, fields = EXPORT_FIELDS) => Object.fromEntries(fields.map(field => [field, doc[field]]));
+
+const toCsvValue = (value) => {
+  const text = value === undefined || value === null ? '' : String(value);
+  return /[",\r\n]/.test(text) ? `"${text.replace(/"/g, '""')}"` : text;
+};
+
+const toCsvLine = (values) => `${values.map(toCsvValue).join(',')}\n`;
+
+// Iterate the directory, or one page of it, without loading it into memory
+const createExportCursor = (paging = null) => {
+  const query = SwiftCode.find({}).sort({ swiftCode: 1 }).lean();
+  
+  if (paging) {
+    query.skip((paging.page - 1) * paging.limit).limit(paging.limit);
+  }
+  return query.cursor();
+};
+
+// Write a chunk, waiting for the client to drain (or disconnect) when the socket buffer is full
+const write = async (stream, chunk) => {
+  if (!stream.write(chunk)) {
+    await Promise.race([once(stream, 'drain'), once(stream, 'close')]);
+  }
+};
+
+const streamCsv = async (cursor, res, { fields = EXPORT_FIELDS } = {}) => {
+  res.type('text/csv');
+  await write(res, toCsvLine(fields));
+  
+  for await (const doc of cursor) {
+    const record = toExportRecord(doc, fields);
+    await write(res, toCsvLine(fields.map(field => record[field])));
+  }
+};
+
+const streamJson = async (cursor, res, { fields = EXPORT_FIELDS } = {}) => {
+  res.type('application/json');
+  await write(res, '[');
+  
+  let first = true;
+  for await (const doc of cursor) {
+    await write(res, `${first ? '' : ','}\n${JSON.stringify(toExportRecord(doc, fields))}`);
+    first = false;
+  }
+  
+  await write(res, '\n]');
+};
+
+// The cursor is sorted by swiftCode, so all codes of an institution arrive consecutively
+const streamNestedJson = async (cursor, res, { fields = EXPORT_FIELDS } = {}) => {
+  res.type('application/json');
+  await write(res, '[');
+  
+  let first = true;
+  let institution = null;
+  
+  const flush = async () => {
+    if (institution) {
+      await write(res, `${first ? '' : ','}\n${JSON.stringify(institution)}`);
+      first = false;
+    }
+  };
+  
+  for await (const doc of cursor) {
+    const bic8 = doc.swiftCode.substring(0, 8);
+    
+    if (!institution || institution.bic8 !== bic8) {
+      await flush();
+      institution = { bic8, headquarter: null, branches: [] };
+    }
+    
+    if (doc.isHeadquarter) {
+      institution.headquarter = toExportRecord(doc, fields);
+    } else {
+      institution.branches.push(toExportRecord(doc, fields));
+    }
+  }
+  
+  await flush();
+  await write(res, '\n]');
+};
+
+// Codes and country codes are text cells, so Excel doesn't reinterpret values such as 0000 or TRUE
+const XLSX_COLUMNS = {
+  swiftCode: { width: 14, style: { numFmt: '@' } },
+  bankName: { width: 40, style: { numFmt: '@' } },
+  address: { width: 50, style: { numFmt: '@' } },
+  countryISO2: { width: 12, style: { numFmt: '@' } },
+  countryName: { width: 24, style: { numFmt: '@' } },
+  isHeadquarter: { width: 14 }
+};
+
+// One worksheet headed by the field names, the same headers the importer reads; rows are committed
+// as they arrive so the workbook is streamed rather than built in memory
+const streamXlsx = async (cursor, res, { fields = EXPORT_FIELDS } = {}) => {
+  res.type('application/vnd.openxmlformats-officedocument.spreadsheetml.sheet');
+  
+  const workbook = new ExcelJS.stream.xlsx.WorkbookWriter({ stream: res, useStyles: true });
+  const sheet = workbook.addWorksheet('SWIFT codes', { views: [{ state: 'frozen', ySplit: 1 }] });
+  sheet.columns = fields.map(field => ({ header: field, key: field, ...XLSX_COLUMNS[field] }));
+  sheet.getRow(1).font = { bold: true };
+  sheet.getRow(1).commit();
+  
+  for await (const doc of cursor) {
+    const record = toExportRecord(doc, fields);
+    // Booleans stay booleans; missing values are left as empty cells
+    sheet.addRow(Object.fromEntries(fields.map(field => [field, record[field] === undefined ? null : record[field]]))).commit();
+  }
+  
+  sheet.commit();
+  await workbook.commit();
+};
+
+const sha256 = (buffer) => crypto.createHash('sha256').update(buffer).digest('hex');
+
+// Describe bundled files ({ name, records, bytes, sha256 }) and the dataset version they were built from
+const buildManifest = async (files, recordCount) => {
+  const lastImport = await ImportRun.findOne(ImportRun.COMPLETED).sort({ completedAt: -1 }).lean();
+  
+  return {
+    generatedAt: new Date().toISOString(),
+    datasetVersion: lastImport ? { importRunId: lastImport._id, importedAt: lastImport.completedAt } : null,
+    recordCount,
+    files: files.map(({ name, records, bytes, sha256: checksum }) => ({ name, records, bytes, sha256: checksum }))
+  };
+};
+
+// Running checksum, size and record count of a file while its chunks go by
+const createDigest = (name) => {
+  const hash = crypto.createHash('sha256');
+  const file = { name, records: 0, bytes: 0 };
+  
+  return {
+    update: (chunk, records = 1) => {
+      hash.update(chunk);
+      file.bytes += chunk.length;
+      file.records += records;
+    },
+    file: () => ({ ...file, sha256: hash.digest('hex') })
+  };
+};
+
+// Lines of an export turned into a byte stream that feeds the digest as it is read
+const digestedStream = (lines, digest) => Readable.from((async function* () {
+  for await (const { line, records } of lines) {
+    const chunk = Buffer.from(line);
+    digest.update(chunk, records);
+    yield chunk;
+  }
+})(), { objectMode: false });
+
+async function* csvLines(cursor, fields = EXPORT_FIELDS) {
+  yield { line: toCsvLine(fields), records: 0 };
+  for await (const doc of cursor) {
+    const record = toExportRecord(doc, fields);
+    yield { line: toCsvLine(fields.map(field => record[field])), records: 1 };
+  }
+}
+
+async function* ndjsonLines(cursor, fields = EXPORT_FIELDS) {
+  for await (const doc of cursor) {
+    yield { line: `${JSON.stringify(toExportRecord(doc, fields))}\n`, records: 1 };
+  }
+}
+
+// Each file streams from its own pass over the directory and is hashed as archiver reads it; the
+// manifest goes in last, once both files have been read through
+const streamZip = async (cursor, res, { paging, fields = EXPORT_FIELDS } = {}) => {
+  const ndjsonCursor = createExportCursor(paging);
+  res.on('close', () => ndjsonCursor.close().catch(() => {}));
+  
+  const csvDigest = createDigest('swift-codes.csv');
+  const ndjsonDigest = createDigest('swift-codes.ndjson');
+  const csv = digestedStream(csvLines(cursor, fields), csvDigest);
+  const ndjson = digestedStream(ndjsonLines(ndjsonCursor, fields), ndjsonDigest);
+  
+  res.type('application/zip');
+  
+  const archive = archiver('zip');
+  archive.pipe(res);
+  archive.append(csv, { name: 'swift-codes.csv' });
+  archive.append(ndjson, { name: 'swift-codes.ndjson' });
+  
+  await Promise.all([finished(csv), finished(ndjson)]);
+  const files = [csvDigest.file(), ndjsonDigest.file()];
+  const manifest = await buildManifest(files, files[1].records);
+  
+  archive.append(JSON.stringify(manifest, null, 2), { name: 'manifest.json' });
+  await archive.finalize();
+};
+
+const WRITERS = {
+  csv: streamCsv,
+  json: streamJson,
+  zip: streamZip,
+  xlsx: streamXlsx
+};
+
+const NESTED_WRITERS = {
+  json: streamNestedJson
+};
+
+exports.EXPORT_FORMATS = EXPORT_FORMATS;
+exports.EXPORT_FIELDS = EXPORT_FIELDS;
+exports.EXPORT_LAYOUTS = EXPORT_LAYOUTS;
+exports.supportsLayout = (format, layout) => layout === 'flat' || Boolean(NESTED_WRITERS[format]);
+exports.toExportRecord = toExportRecord;
+exports.toCsvLine = toCsvLine;
+exports.writeChunk = write;
+exports.createExportCursor = createExportCursor;
+exports.buildManifest = buildManifest;
+exports.createDigest = createDigest;
+exports.digestedStream = digestedStream;
+exports.ndjsonLines = ndjsonLines;
+exports.sha256 = sha256;
+
+// Paged nested exports can split an institution across two pages
+exports.streamExport = async (format, res, options = {}) => {
+  const cursor = createExportCursor(options.paging);
+  const writers = options.layout === 'nested' ? NESTED_WRITERS : WRITERS;
+  
+  // Stop reading from MongoDB if the client goes away mid-download
+  res.on('close', () => cursor.close().catch(() => {}));
+  
+  await writers[format](cursor, res, options);
+  res.end();
+};
+
+// src/services/objectStorage.js
+const { pipeline } = require('stream/promises');
+
+// Minimal clients for the buckets snapshots are published to and imports are read from; the SDKs are
+// optional dependencies, loaded only for the provider that is configured, so deployments install just
+// the one they use. put() takes a Buffer or a Readable; streams are uploaded in parts without buffering.
+const PROVIDERS = {
+  s3: (bucket) => {
+    const { S3Client, GetObjectCommand } = require('@aws-sdk/client-s3');
+    const { Upload } = require('@aws-sdk/lib-storage');
+    const client = new S3Client({});
+    
+    return {
+      put: (key, body, contentType) => new Upload({
+        client,
+        params: { Bucket: bucket, Key: key, Body: body, ContentType: contentType }
+      }).done(),
+      // In Node the response body is a Readable that streams the object
+      get: async (key) => (await client.send(new GetObjectCommand({ Bucket: bucket, Key: key }))).Body
+    };
+  },
+  gs: (bucket) => {
+    const { Storage } = require('@google-cloud/storage');
+    const target = new Storage().bucket(bucket);
+    
+    return {
+      put: (key, body, contentType) => (Buffer.isBuffer(body)
+        ? target.file(key).save(body, { contentType, resumable: false })
+        : pipeline(body, target.file(key).createWriteStream({ contentType }))),
+      get: async (key) => target.file(key).createReadStream()
+    };
+  }
+};
+
+// "s3://bucket/prefix" -> { provider: 's3', bucket: 'bucket', prefix: 'prefix' }, or null when unsupported
+exports.parseTarget = (target) => {
+  const match = /^(\w+):\/\/([^/]+)\/?(.*)$/.exec(target || '');
+  
+  if (!match || !PROVIDERS[match[1]]) {
+    return null;
+  }
+  return { provider: match[1], bucket: match[2], prefix: match[3].replace(/\/+$/, '') };
+};
+
+// Storage client whose keys are relative to the target's prefix
+exports.fromTarget = (target) => {
+  const parsed = exports.parseTarget(target);
+  
+  if (!parsed) {
+    throw new Error(`Unsupported object storage target: ${target}`);
+  }
+  
+  const client = PROVIDERS[parsed.provider](parsed.bucket);
+  
+  return {
+    put: (key, body, contentType) => client.put(parsed.prefix ? `${parsed.prefix}/${key}` : key, body, contentType)
+  };
+};
+
+// Readable stream of a single object such as "s3://bucket/exports/swift_codes.csv"
+exports.openObject = (url) => {
+  const parsed = exports.parseTarget(url);
+  
+  if (!parsed || !parsed.prefix) {
+    throw new Error(`Unsupported object storage source: ${url}`);
+  }
+  
+  return PROVIDERS[parsed.provider](parsed.bucket).get(parsed.prefix);
+};
+
+// src/services/metricsService.js
+const client = require('prom-client');
+const SwiftCode = require('../models/swiftCode');
+const ImportRun = require('../models/importRun');
+
+const register = new client.Registry();
+client.collectDefaultMetrics({ register });
+
+// Request health
+const httpRequests = new client.Counter({
+  name: 'http_requests_total',
+  help: 'HTTP requests by method, route and status code',
+  labelNames: ['method', 'route', 'status'],
+  registers: [register]
+});
+
+const httpDuration = new client.Histogram({
+  name: 'http_request_duration_seconds',
+  help: 'HTTP request latency by method and route',
+  labelNames: ['method', 'route'],
+  buckets: [0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5],
+  registers: [register]
+});
+
+// Where request time goes, see stageTimings
+const stageDuration = new client.Histogram({
+  name: 'http_request_stage_duration_seconds',
+  help: 'Time spent per request in validation, cache, db and serialization, by route',
+  labelNames: ['method', 'route', 'stage'],
+  buckets: [0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1],
+  registers: [register]
+});
+
+// Data health, computed from MongoDB at scrape time
+const lastImport = () => ImportRun.findOne(ImportRun.COMPLETED).sort({ completedAt: -1 }).lean();
+
+new client.Gauge({
+  name: 'swift_dataset_age_hours',
+  help: 'Hours since the last completed import, -1 when nothing was imported yet',
+  registers: [register],
+  async collect() {
+    const run = await lastImport();
+    this.set(run ? (Date.now() - run.completedAt.getTime()) / 3600000 : -1);
+  }
+});
+
+new client.Gauge({
+  name: 'swift_last_import_rejected_rows',
+  help: 'Rows rejected by the last completed import',
+  registers: [register],
+  async collect() {
+    const run = await lastImport();
+    this.set(run ? run.rejectedCount || 0 : 0);
+  }
+});
+
+new client.Gauge({
+  name: 'swift_records_per_country',
+  help: 'Stored SWIFT codes per country',
+  labelNames: ['country'],
+  registers: [register],
+  async collect() {
+    const counts = await SwiftCode.aggregate([{ $group: { _id: '$countryISO2', count: { $sum: 1 } } }]);
+    
+    this.reset();
+    counts.forEach(({ _id, count }) => this.set({ country: _id }, count));
+  }
+});
+
+// Set by the startup integrity check
+const integrityViolations = new client.Gauge({
+  name: 'swift_integrity_violations',
+  help: 'Violations found by the last integrity check, by invariant',
+  labelNames: ['invariant', 'critical'],
+  registers: [register]
+});
+
+exports.register = register;
+
+exports.recordIntegrityViolations = (invariant, critical, count) => {
+  integrityViolations.set({ invariant, critical: String(critical) }, count);
+};
+
+// Label by route pattern rather than URL so codes don't explode the label cardinality
+const routeLabel = (req) => (req.route ? `${req.baseUrl}${req.route.path}` : 'unmatched');
+
+exports.observeRequest = (req, res, durationSeconds) => {
+  const route = routeLabel(req);
+  
+  httpRequests.inc({ method: req.method, route, status: res.statusCode });
+  httpDuration.observe({ method: req.method, route }, durationSeconds);
+};
+
+exports.observeStages = (req, timings) => {
+  const route = routeLabel(req);
+  
+  Object.entries(timings).forEach(([stage, ms]) => {
+    stageDuration.observe({ method: req.method, route, stage }, ms / 1000);
+  });
+};
+
+// src/services/auditService.js
+const AuditLog = require('../models/auditLog');
+const { buildPageInfo, applyPage } = require('../utils/pagination');
+const { toCsvLine, writeChunk } = require('./exportService');
+
+const AUDIT_CSV_FIELDS = ['at', 'actor', 'action', 'method', 'path', 'statusCode', 'clientIp', 'swiftCodes', 'countries'];
+
+const toAuditEntry = (entry) => ({
+  at: entry.at.toISOString(),
+  actor: entry.actor,
+  action: entry.action,
+  method: entry.method,
+  path: entry.path,
+  statusCode: entry.statusCode,
+  clientIp: entry.clientIp,
+  swiftCodes: entry.swiftCodes,
+  countries: entry.countries
+});
+
+// Translate actor/action/country/from/to query parameters into a MongoDB filter
+exports.buildAuditFilter = (query) => {
+  const filter = {};
+  
+  if (query.actor) {
+    filter.actor = String(query.actor);
+  }
+  if (query.action) {
+    filter.action = String(query.action);
+  }
+  if (query.country) {
+    filter.countries = String(query.country).toUpperCase();
+  }
+  
+  for (const [param, operator] of [['from', '$gte'], ['to', '$lte']]) {
+    if (query[param] === undefined) {
+      continue;
+    }
+    
+    const date = new Date(query[param]);
+    if (Number.isNaN(date.getTime())) {
+      return { error: `Invalid ${param} date` };
+    }
+    
+    filter.at = { ...filter.at, [operator]: date };
+  }
+  
+  return { filter };
+};
+
+exports.findAuditEntries = async (filter, { page, limit, pageSizes }) => {
+  const [totalCount, entries] = await Promise.all([
+    AuditLog.countDocuments(filter),
+    applyPage(AuditLog.find(filter).sort({ at: -1 }), page, limit).lean()
+  ]);
+  
+  return {
+    entries: entries.map(toAuditEntry),
+    pagination: buildPageInfo(page, limit, totalCount, pageSizes)
+  };
+};
+
+// Every matching entry, newest first, for periodic access reviews
+exports.streamAuditCsv = async (filter, res) => {
+  const cursor = AuditLog.find(filter).sort({ at: -1 }).lean().cursor();
+  res.on('close', () => cursor.close().catch(() => {}));
+  
+  res.type('text/csv');
+  await writeChunk(res, toCsvLine(AUDIT_CSV_FIELDS));
+  
+  for await (const entry of cursor) {
+    const row = toAuditEntry(entry);
+    await writeChunk(res, toCsvLine(AUDIT_CSV_FIELDS.map(field => (
+      Array.isArray(row[field]) ? row[field].join(' ') : row[field]
+    ))));
+  }
+  
+  res.end();
+};
+
+// src/services/editLockService.js
+const EditLock = require('../models/editLock');
+const SwiftCode = require('../models/swiftCode');
+const appConfig = require('../config/app');
+
+const toLock = (lock) => ({
+  swiftCode: lock.swiftCode,
+  lockedBy: lock.holder,
+  acquiredAt: lock.acquiredAt,
+  expiresAt: lock.expiresAt
+});
+
+const expiry = () => new Date(Date.now() + appConfig.editLockTtlMs);
+
+exports.findActiveLock = async (swiftCode) => {
+  const lock = await EditLock.findOne({ swiftCode: swiftCode.toUpperCase(), expiresAt: { $gt: new Date() } });
+  return lock ? toLock(lock) : null;
+};
+
+// Live locks other holders have on the records a write spanning several codes would touch, given
+// as a SwiftCode filter; only a few records are locked at a time, so those are matched against it
+exports.findLocksHeldByOthers = async (filter, holder) => {
+  const locks = await EditLock.find({ holder: { $ne: holder }, expiresAt: { $gt: new Date() } }).lean();
+  
+  if (locks.length === 0) {
+    return [];
+  }
+  
+  const locked = await SwiftCode.distinct('swiftCode', { ...filter, swiftCode: { $in: locks.map(lock => lock.swiftCode) } });
+  return locks.filter(lock => locked.includes(lock.swiftCode)).map(toLock);
+};
+
+// Take the lock when it is free, expired or already ours; otherwise report who holds it
+exports.acquireLock = async (swiftCode, holder) => {
+  const code = swiftCode.toUpperCase();
+  const now = new Date();
+  
+  try {
+    const lock = await EditLock.findOneAndUpdate(
+      { swiftCode: code, $or: [{ holder }, { expiresAt: { $lte: now } }] },
+      { $set: { holder, acquiredAt: now, expiresAt: expiry() } },
+      { new: true, upsert: true }
+    );
+    return { acquired: true, lock: toLock(lock) };
+  } catch (error) {
+    // The upsert collides with the unique swiftCode when someone else holds a live lock
+    if (error.code !== 11000) {
+      throw error;
+    }
+    
+    const current = await exports.findActiveLock(code);
+    if (!current) {
+      // Released between our attempt and the lookup
+      return exports.acquireLock(code, holder);
+    }
+    return { acquired: false, lock: current };
+  }
+};
+
+// Extend a lock held by the caller; null when the caller doesn't hold a live lock
+exports.heartbeat = async (swiftCode, holder) => {
+  const lock = await EditLock.findOneAndUpdate(
+    { swiftCode: swiftCode.toUpperCase(), holder, expiresAt: { $gt: new Date() } },
+    { $set: { expiresAt: expiry() } },
+    { new: true }
+  );
+  
+  return lock ? toLock(lock) : null;
+};
+
+exports.releaseLock = async (swiftCode, holder) => {
+  const result = await EditLock.deleteOne({ swiftCode: swiftCode.toUpperCase(), holder });
+  return result.deletedCount === 1;
+};
+
+// src/services/leaseService.js
+const os = require('os');
+const crypto = require('crypto');
+const Lease = require('../models/lease');
+const appConfig = require('../config/app');
+
+// Take the named lease when it is free or expired and keep renewing it until released; null while
+// another process holds it
+exports.acquire = async (name, ttlMs = appConfig.leaseTtlMs) => {
+  const holder = `${os.hostname()}:${process.pid}:${crypto.randomUUID()}`;
+  const now = new Date();
+  
+  try {
+    await Lease.findOneAndUpdate(
+      { _id: name, expiresAt: { $lte: now } },
+      { $set: { holder, acquiredAt: now, expiresAt: new Date(now.getTime() + ttlMs) } },
+      { upsert: true }
+    );
+  } catch (error) {
+    // The upsert collides with the live lease of another holder
+    if (error.code === 11000) {
+      return null;
+    }
+    throw error;
+  }
+  
+  const timer = setInterval(() => {
+    Lease.updateOne({ _id: name, holder }, { $set: { expiresAt: new Date(Date.now() + ttlMs) } })
+      .catch(error => console.error(`Renewing the ${name} lease failed`, error));
+  }, Math.max(Math.floor(ttlMs / 3), 1000));
+  timer.unref();
+  
+  return {
+    holder,
+    release: async () => {
+      clearInterval(timer);
+      await Lease.deleteOne({ _id: name, holder });
+    }
+  };
+};
+
+exports.isHeld = async (name) => Boolean(await Lease.exists({ _id: name, expiresAt: { $gt: new Date() } }));
+
+// src/services/historyService.js
+const SwiftCodeRevision = require('../models/swiftCodeRevision');
+const { buildPageInfo, applyPage } = require('../utils/pagination');
+
+// Plain record values without MongoDB bookkeeping
+const snapshot = (record) => {
+  const { _id, __v, ...values } = record.toObject ? record.toObject({ flattenMaps: true }) : record;
+  return values;
+};
+
+// Append the next version for a code; a concurrent writer taking the same number makes us retry
+exports.recordRevision = async ({ action, actor, previous, current }, attempt = 1) => {
+  const swiftCode = (current || previous).swiftCode;
+  const latest = await SwiftCodeRevision.findOne({ swiftCode }, { version: 1 }).sort({ version: -1 });
+  
+  try {
+    return await SwiftCodeRevision.create({
+      swiftCode,
+      version: latest ? latest.version + 1 : 1,
+      action,
+      actor,
+      previous: previous ? snapshot(previous) : null,
+      current: current ? snapshot(current) : null
+    });
+  } catch (error) {
+    if (error.code === 11000 && attempt < 3) {
+      return exports.recordRevision({ action, actor, previous, current }, attempt + 1);
+    }
+    throw error;
+  }
+};
+
+// Revisions of many records in two round trips: one for their latest versions, one insertMany; numbers
+// a concurrent writer took first fall back to recordRevision and its retries
+exports.recordRevisions = async (changes) => {
+  if (changes.length === 0) {
+    return;
+  }
+  
+  const entries = changes.map(({ action, actor, previous, current }) => ({
+    swiftCode: (current || previous).swiftCode,
+    action,
+    actor,
+    previous: previous ? snapshot(previous) : null,
+    current: current ? snapshot(current) : null
+  }));
+  const latest = await SwiftCodeRevision.aggregate([
+    { $match: { swiftCode: { $in: [...new Set(entries.map(entry => entry.swiftCode))] } } },
+    { $group: { _id: '$swiftCode', version: { $max: '$version' } } }
+  ]);
+  const versions = new Map(latest.map(({ _id, version }) => [_id, version]));
+  
+  const documents = entries.map(entry => {
+    const version = (versions.get(entry.swiftCode) || 0) + 1;
+    versions.set(entry.swiftCode, version);
+    return { ...entry, version };
+  });
+  
+  try {
+    await SwiftCodeRevision.insertMany(documents, { ordered: false });
+  } catch (error) {
+    if (error.code !== 11000 || !error.writeErrors) {
+      throw error;
+    }
+    for (const { index } of [].concat(error.writeErrors)) {
+      await exports.recordRevision(entries[index]);
+    }
+  }
+};
+
+// Revisions for records changed together by one updateMany with a plain $set
+exports.recordBulkRevisions = (previousRecords, changes, action, actor) => exports.recordRevisions(
+  previousRecords.map(previous => {
+    const values = snapshot(previous);
+    return { action, actor, previous: values, current: { ...values, ...changes } };
+  })
+);
+
+// Bookkeeping that changes on every write and says nothing about the reference data
+const DIFF_IGNORED_FIELDS = ['createdAt', 'updatedAt'];
+
+// Record values at a version: version N is the state revision N left behind, version 0 the state
+// before the first recorded change; undefined when the version doesn't exist
+const valuesAt = (revisions, version) => {
+  if (version === 0) {
+    return revisions.has(1) ? revisions.get(1).previous || {} : undefined;
+  }
+  return revisions.has(version) ? revisions.get(version).current || {} : undefined;
+};
+
+// Fields whose values differ between two snapshots of a record, sorted by name, with null standing
+// in for a missing value
+exports.fieldChanges = (previous, current) => {
+  const before = previous || {};
+  const after = current || {};
+  
+  return [...new Set([...Object.keys(before), ...Object.keys(after)])]
+    .filter(field => !DIFF_IGNORED_FIELDS.includes(field))
+    .sort()
+    .filter(field => JSON.stringify(before[field]) !== JSON.stringify(after[field]))
+    .map(field => ({
+      field,
+      from: before[field] === undefined ? null : before[field],
+      to: after[field] === undefined ? null : after[field]
+    }));
+};
+
+// Fields whose values differ between two versions of a record, or null when either is missing
+exports.diffVersions = async (swiftCode, from, to) => {
+  const code = swiftCode.toUpperCase();
+  const revisions = await SwiftCodeRevision.find(
+    { swiftCode: code, version: { $in: [...new Set([from, to, 1])] } },
+    { version: 1, previous: 1, current: 1 }
+  ).lean();
+  const byVersion = new Map(revisions.map(revision => [revision.version, revision]));
+  
+  const before = valuesAt(byVersion, from);
+  const after = valuesAt(byVersion, to);
+  
+  if (!before || !after) {
+    return null;
+  }
+  
+  return { swiftCode: code, from, to, changes: exports.fieldChanges(before, after) };
+};
+
+exports.getHistory = async (swiftCode, { page, limit, pageSizes }) => {
+  const filter = { swiftCode: swiftCode.toUpperCase() };
+  
+  const [totalCount, revisions] = await Promise.all([
+    SwiftCodeRevision.countDocuments(filter),
+    applyPage(SwiftCodeRevision.find(filter).sort({ version: -1 }), page, limit).lean()
+  ]);
+  
+  return {
+    swiftCode: filter.swiftCode,
+    revisions: revisions.map(revision => ({
+      version: revision.version,
+      action: revision.action,
+      actor: revision.actor,
+      at: revision.at,
+      previous: revision.previous,
+      current: revision.current
+    })),
+    pagination: buildPageInfo(page, limit, totalCount, pageSizes)
+  };
+};
+
+// src/services/siemService.js
+const AuditLog = require('../models/auditLog');
+const SwiftCodeRevision = require('../models/swiftCodeRevision');
+const appConfig = require('../config/app');
+const historyService = require('./historyService');
+const { writeChunk } = require('./exportService');
+
+// Bumped only when a field changes meaning or goes away; new fields may appear within a version
+const SIEM_SCHEMA_VERSION = 1;
+const SIEM_EVENT_TYPES = ['audit', 'change'];
+
+// Every event carries every key, null where it doesn't apply, so parsers can rely on the layout
+const toSiemEvent = (type, entry, details) => ({
+  schemaVersion: SIEM_SCHEMA_VERSION,
+  eventId: `${type}:${entry._id}`,
+  eventType: type,
+  occurredAt: entry.at.toISOString(),
+  source: appConfig.siemSource,
+  actor: entry.actor,
+  action: entry.action,
+  http: null,
+  target: null,
+  change: null,
+  ...details
+});
+
+const fromAuditEntry = (entry) => toSiemEvent('audit', entry, {
+  http: {
+    method: entry.method || null,
+    path: entry.path || null,
+    statusCode: entry.statusCode || null,
+    clientIp: entry.clientIp || null
+  },
+  target: { swiftCodes: entry.swiftCodes || [], countries: entry.countries || [] }
+});
+
+const fromRevision = (revision) => {
+  const record = revision.current || revision.previous || {};
+  
+  return toSiemEvent('change', revision, {
+    target: { swiftCodes: [revision.swiftCode], countries: record.countryISO2 ? [record.countryISO2] : [] },
+    change: {
+      swiftCode: revision.swiftCode,
+      version: revision.version,
+      changes: historyService.fieldChanges(revision.previous, revision.current)
+    }
+  });
+};
+
+const SOURCES = {
+  audit: { model: AuditLog, toEvent: fromAuditEntry },
+  change: { model: SwiftCodeRevision, toEvent: fromRevision }
+};
+
+// Translate already validated from/to/types query parameters (schemas.siemEvents) into a time range
+// and the event types to deliver
+exports.buildEventFilter = (query) => {
+  const types = query.types ? query.types.split(',').map(type => type.trim()) : SIEM_EVENT_TYPES;
+  
+  const filter = {};
+  for (const [param, operator] of [['from', '$gte'], ['to', '$lte']]) {
+    if (query[param] !== undefined) {
+      filter.at = { ...filter.at, [operator]: new Date(query[param]) };
+    }
+  }
+  
+  return { filter, types };
+};
+
+// Audit entries and revisions merged into one stream, oldest first, so a shipper can resume from
+// the occurredAt of the last event it stored and drop the repeats by eventId
+exports.streamEvents = async ({ filter, types }, res) => {
+  const sources = types.map(type => ({
+    cursor: SOURCES[type].model.find(filter).sort({ at: 1, _id: 1 }).lean().cursor(),
+    toEvent: SOURCES[type].toEvent
+  }));
+  res.on('close', () => sources.forEach(({ cursor }) => cursor.close().catch(() => {})));
+  
+  res.type('application/x-ndjson');
+  
+  const heads = await Promise.all(sources.map(({ cursor }) => cursor.next()));
+  for (;;) {
+    let next = -1;
+    heads.forEach((head, index) => {
+      if (head && (next === -1 || head.at < heads[next].at)) {
+        next = index;
+      }
+    });
+    
+    if (next === -1) {
+      break;
+    }
+    
+    await writeChunk(res, `${JSON.stringify(sources[next].toEvent(heads[next]))}\n`);
+    heads[next] = await sources[next].cursor.next();
+  }
+  
+  res.end();
+};
+
+exports.SIEM_SCHEMA_VERSION = SIEM_SCHEMA_VERSION;
+exports.SIEM_EVENT_TYPES = SIEM_EVENT_TYPES;
+
+// src/services/countryService.js
+const Country = require('../models/country');
+const SwiftCode = require('../models/swiftCode');
+const historyService = require('./historyService');
+const appConfig = require('../config/app');
+const countries = require('../utils/countries');
+
+let timer = null;
+
+// Records renamed per updateMany and revision insert when a country name changes
+const SYNC_BATCH_SIZE = 1000;
+
+// Validation and display names read the registry synchronously, so it lives in memory and is
+// reloaded after every change and periodically to pick up changes made by other instances
+exports.loadCountries = async () => {
+  countries.setOverrides(await Country.find().lean());
+};
+
+exports.listCountries = () => countries.listCountries();
+
+// Stored countryName always follows the registry; the country's records are streamed and renamed in
+// batches, so a large country doesn't sit in memory for the length of the request
+const syncCountryName = async (countryISO2, previousName, actor) => {
+  const name = countries.getCountryName(countryISO2);
+  
+  if (!name || name === previousName) {
+    return;
+  }
+  
+  const changes = { countryName: name };
+  let batch = [];
+  
+  const flush = async () => {
+    if (batch.length === 0) {
+      return;
+    }
+    await SwiftCode.updateMany({ _id: { $in: batch.map(code => code._id) } }, { $set: changes });
+    await historyService.recordBulkRevisions(batch, changes, 'rename-country', actor);
+    await SwiftCode.invalidateInstitutions(batch.map(code => code.bic8));
+    batch = [];
+  };
+  
+  for await (const code of SwiftCode.find({ countryISO2 }).lean().cursor({ batchSize: SYNC_BATCH_SIZE })) {
+    batch.push(code);
+    if (batch.length >= SYNC_BATCH_SIZE) {
+      await flush();
+    }
+  }
+  await flush();
+};
+
+// Add a newly assigned code, or rename or withdraw an existing one; returns { error } when a new
+// code comes without a name
+exports.saveCountry = async (countryISO2, { name, withdrawn }, actor) => {
+  const code = countryISO2.toUpperCase();
+  const existing = await Country.findOne({ countryISO2: code });
+  const created = !existing && !countries.isBuiltIn(code);
+  
+  if (created && !name) {
+    return { error: 'Missing required field: name' };
+  }
+  
+  const changes = {};
+  if (name !== undefined) {
+    changes.name = name;
+  }
+  if (withdrawn !== undefined && withdrawn !== Boolean(existing && existing.withdrawn)) {
+    changes.withdrawn = withdrawn;
+    changes.withdrawnAt = withdrawn ? new Date() : null;
+  }
+  
+  if (Object.keys(changes).length > 0) {
+    const previousName = countries.getCountryName(code);
+    await Country.updateOne({ countryISO2: code }, { $set: changes }, { upsert: true, runValidators: true });
+    await exports.loadCountries();
+    await syncCountryName(code, previousName, actor);
+  }
+  
+  return { country: countries.describeCountry(code), created };
+};
+
+// Drop the change to a built-in code, or a code added through the API once no record uses it
+exports.resetCountry = async (countryISO2, actor) => {
+  const code = countryISO2.toUpperCase();
+  
+  if (!countries.isBuiltIn(code) && await SwiftCode.exists({ countryISO2: code }).setOptions({ withDeleted: true })) {
+    return { deleted: false, inUse: true };
+  }
+  
+  const previousName = countries.getCountryName(code);
+  const result = await Country.deleteOne({ countryISO2: code });
+  await exports.loadCountries();
+  await syncCountryName(code, previousName, actor);
+  
+  return { deleted: result.deletedCount > 0, inUse: false };
+};
+
+exports.start = () => {
+  if (timer) {
+    return;
+  }
+  
+  const load = () => exports.loadCountries().catch(error => console.error('Failed to load the country registry', error));
+  
+  load();
+  timer = setInterval(load, appConfig.countryRefreshIntervalMs);
+  timer.unref();
+};
+
+exports.stop = () => {
+  clearInterval(timer);
+  timer = null;
+};
+
+// src/services/mergerEventService.js
+const MergerEvent = require('../models/mergerEvent');
+const SwiftCode = require('../models/swiftCode');
+const historyService = require('./historyService');
+const swiftCodeService = require('./swiftCodeService');
+const { buildPageInfo, applyPage } = require('../utils/pagination');
+
+exports.MERGER_EVENT_TYPES = MergerEvent.MERGER_EVENT_TYPES;
+
+const toEvent = (event) => ({
+  id: event._id.toString(),
+  type: event.type,
+  effectiveDate: event.effectiveDate,
+  absorbingBic: event.absorbingBic,
+  absorbedBics: event.absorbedBics,
+  affectedCodes: event.affectedCodes,
+  ...(event.notes && { notes: event.notes }),
+  ...(event.recordedBy && { recordedBy: event.recordedBy }),
+  createdAt: event.createdAt
+});
+
+// A BIC8 stands for its headquarter
+const resolveSuccessor = async (absorbingBic) => {
+  if (absorbingBic.length === 8) {
+    return (await swiftCodeService.resolveBic8(absorbingBic)).resolved;
+  }
+  
+  return (await SwiftCode.exists({ swiftCode: absorbingBic })) ? absorbingBic : null;
+};
+
+// Record the event and deprecate the absorbed codes in favour of the absorbing one; returns
+// { missing } naming BICs without any stored code
+exports.recordMergerEvent = async ({ type, effectiveDate, absorbingBic, absorbedBics, notes }, actor) => {
+  const absorbing = absorbingBic.toUpperCase();
+  const absorbed = [...new Set(absorbedBics.map(bic => bic.toUpperCase()))];
+  const successor = await resolveSuccessor(absorbing);
+  
+  if (!successor) {
+    return { missing: [absorbing] };
+  }
+  
+  const existing = (await SwiftCode.find({
+    $or: absorbed.map(bic => (bic.length === 8 ? { bic8: bic } : { swiftCode: bic }))
+  }).sort({ swiftCode: 1 })).filter(code => code.swiftCode !== successor);
+  
+  const missing = absorbed.filter(bic => !existing.some(code => code.swiftCode === bic || code.bic8 === bic));
+  if (missing.length > 0) {
+    return { missing };
+  }
+  
+  const changes = {
+    successor,
+    deprecatedAt: new Date(effectiveDate),
+    status: 'retired',
+    statusReason: `${type === 'transfer' ? 'Transferred' : 'Merged'} into ${successor}`,
+    statusChangedAt: new Date()
+  };
+  
+  // Recorded first so a retired code always has the event that explains it
+  const event = await MergerEvent.create({
+    type,
+    effectiveDate,
+    absorbingBic: absorbing,
+    absorbedBics: absorbed,
+    notes,
+    affectedCodes: existing.map(code => code.swiftCode),
+    recordedBy: actor
+  });
+  
+  await SwiftCode.updateMany({ _id: { $in: existing.map(code => code._id) } }, { $set: changes });
+  await historyService.recordBulkRevisions(existing, changes, 'merge', actor);
+  
+  return { event: toEvent(event) };
+};
+
+// Timeline oldest first; bic matches the absorbing side, the absorbed side or a single affected code
+exports.listMergerEvents = async ({ from, to, bic }, { page, limit, pageSizes }) => {
+  const filter = {};
+  
+  if (from || to) {
+    filter.effectiveDate = {
+      ...(from && { $gte: new Date(from) }),
+      ...(to && { $lte: new Date(to) })
+    };
+  }
+  if (bic) {
+    const code = bic.toUpperCase();
+    filter.$or = [{ absorbingBic: code }, { absorbedBics: code }, { affectedCodes: code }];
+  }
+  
+  const [events, totalCount] = await Promise.all([
+    applyPage(MergerEvent.find(filter).sort({ effectiveDate: 1, _id: 1 }), page, limit).lean(),
+    MergerEvent.countDocuments(filter)
+  ]);
+  
+  return { events: events.map(toEvent), pagination: buildPageInfo(page, limit, totalCount, pageSizes) };
+};
+
+// src/services/assignmentService.js
+const BicAssignment = require('../models/bicAssignment');
+
+// What identifies the institution holding a code
+const HOLDER_FIELDS = ['bankName', 'address', 'city', 'countryISO2', 'countryName', 'lei'];
+
+const toHolder = (record) => Object.fromEntries(HOLDER_FIELDS
+  .filter(field => record[field] !== undefined && record[field] !== null)
+  .map(field => [field, record[field]]));
+
+// The current holder is read from the live record, which imports keep up to date
+const toAssignment = (assignment, live) => ({
+  holder: assignment.validTo === null && live ? toHolder(live) : assignment.holder,
+  validFrom: assignment.validFrom,
+  validTo: assignment.validTo
+});
+
+// Windows of a code, oldest first; a code never reassigned has a single open one
+exports.listAssignments = async (swiftCode, live) => {
+  const assignments = await BicAssignment.find({ swiftCode: swiftCode.toUpperCase() }).sort({ validFrom: 1 }).lean();
+  
+  if (assignments.length === 0) {
+    return live ? [toAssignment({ validFrom: null, validTo: null }, live)] : [];
+  }
+  return assignments.map(assignment => toAssignment(assignment, live));
+};
+
+// Window containing the date, or null when nobody held the code then (as far as we know)
+exports.findAssignmentAt = async (swiftCode, at, live) => {
+  const assignments = await exports.listAssignments(swiftCode, live);
+  
+  return assignments.find(assignment => (!assignment.validFrom || assignment.validFrom <= at)
+    && (!assignment.validTo || at < assignment.validTo)) || null;
+};
+
+exports.findOpenAssignment = (swiftCode) => BicAssignment.findOne({ swiftCode: swiftCode.toUpperCase(), validTo: null }).lean();
+
+// Close the current window at the effective date and open one for the new holder
+exports.recordReassignment = async (previous, current, effectiveDate) => {
+  const swiftCode = previous.swiftCode;
+  
+  await BicAssignment.updateOne(
+    { swiftCode, validTo: null },
+    { $set: { holder: toHolder(previous), validTo: effectiveDate }, $setOnInsert: { validFrom: null } },
+    { upsert: true }
+  );
+  await BicAssignment.create({ swiftCode, holder: toHolder(current), validFrom: effectiveDate });
+};
+
+// src/services/lookupService.js
+const SwiftCode = require('../models/swiftCode');
+const SwiftCodeLookup = require('../models/swiftCodeLookup');
+const appConfig = require('../config/app');
+const { toBic8 } = require('../utils/bic');
+const { buildPageInfo } = require('../utils/pagination');
+const { serializeDetail, serializeBranch } = require('../serializers/swiftCodeSerializer');
+
+// All an entry is built from; the rest of a record never reaches the hot path
+const SOURCE_PROJECTION = [
+  'swiftCode', 'bic8', 'bankName', 'address', 'countryISO2', 'countryName', 'isHeadquarter', 'status',
+  'statusReason', 'connectivityStatus', 'externalIds', 'isTemporary', 'expiresAt', 'deprecatedAt', 'successor'
+].join(' ');
+
+// Entries of one institution from its records sorted by code
+const toEntries = (records) => {
+  const headquarter = records.find(record => record.isHeadquarter);
+  const branches = records.filter(record => !record.isHeadquarter).map(serializeBranch);
+  
+  return records.map(record => ({
+    swiftCode: record.swiftCode,
+    bic8: record.bic8,
+    headquarter: !record.isHeadquarter && headquarter ? headquarter.swiftCode : null,
+    record: serializeDetail(record),
+    ...(record.isHeadquarter && { branches, branchCount: branches.length })
+  }));
+};
+
+// Upserts rather than inserts, so a lookup building an institution during a rebuild doesn't collide with it
+const writeEntries = async (entries) => {
+  if (entries.length === 0) {
+    return;
+  }
+  
+  const expiresAt = new Date(Date.now() + appConfig.lookupEntryTtlMs);
+  await SwiftCodeLookup.bulkWrite(entries.map(entry => ({
+    replaceOne: { filter: { swiftCode: entry.swiftCode }, replacement: { ...entry, expiresAt }, upsert: true }
+  })), { ordered: false });
+};
+
+// (Re)build the entries of one institution. A write landing while the records are read invalidates
+// before these entries exist, so entries built from records that changed meanwhile are dropped again
+exports.build = async (bic8) => {
+  const startedAt = new Date();
+  const records = await SwiftCode.find({ bic8 }, SOURCE_PROJECTION).sort({ swiftCode: 1 });
+  const entries = toEntries(records);
+  
+  await writeEntries(entries);
+  await SwiftCodeLookup.deleteMany({ bic8, swiftCode: { $nin: entries.map(entry => entry.swiftCode) } });
+  
+  if (await SwiftCode.exists({ bic8, updatedAt: { $gt: startedAt } }).setOptions({ withDeleted: true })) {
+    await SwiftCodeLookup.deleteMany({ bic8 });
+    return 0;
+  }
+  return entries.length;
+};
+
+// Rebuild every entry in one pass over the collection, as after an import
+exports.rebuild = async () => {
+  await SwiftCodeLookup.deleteMany({});
+  
+  const records = SwiftCode.find({}, SOURCE_PROJECTION).sort({ bic8: 1, swiftCode: 1 }).cursor();
+  let institution = [];
+  let pending = [];
+  let count = 0;
+  
+  const flush = async () => {
+    await writeEntries(pending);
+    count += pending.length;
+    pending = [];
+  };
+  
+  for await (const record of records) {
+    if (institution.length > 0 && institution[0].bic8 !== record.bic8) {
+      pending.push(...toEntries(institution));
+      institution = [];
+      if (pending.length >= appConfig.importBatchSize) {
+        await flush();
+      }
+    }
+    institution.push(record);
+  }
+  
+  pending.push(...toEntries(institution));
+  await flush();
+  return count;
+};
+
+const findEntry = (swiftCode, { page = 1, limit } = {}) => SwiftCodeLookup.findOne(
+  { swiftCode, expiresAt: { $gt: new Date() } },
+  limit ? { branches: { $slice: [(page - 1) * limit, limit] } } : {}
+).lean();
+
+// Detail record of a code with its page of branches ({ record, branches, branchPagination }), building
+// the institution's entries on a miss; null when the code has no record of its own
+exports.findDetails = async (swiftCode, branchPaging = {}) => {
+  const code = swiftCode.toUpperCase();
+  let entry = await findEntry(code, branchPaging);
+  
+  if (!entry) {
+    await exports.build(toBic8(code));
+    entry = await findEntry(code, branchPaging);
+  }
+  
+  if (!entry) {
+    return null;
+  }
+  
+  const { page = 1, limit, pageSizes } = branchPaging;
+  // Without a limit every branch is on the first page
+  const branches = entry.branches && !limit && page > 1 ? [] : entry.branches;
+  
+  return {
+    record: entry.record,
+    branches: branches || null,
+    branchPagination: entry.branches && limit ? buildPageInfo(page, limit, entry.branchCount, pageSizes) : null
+  };
+};
+
+exports.hasEntry = async (swiftCode) => Boolean(await SwiftCodeLookup.exists({ swiftCode, expiresAt: { $gt: new Date() } }));
+
+// src/serializers/swiftCodeSerializer.js
+const { fromPoint } = require('../utils/geo');
+const { connectivityOf } = require('../utils/bic');
+
+// v1 detail record of a code, without its branches
+exports.serializeDetail = (swiftCodeData) => {
+  const record = {
+    address: swiftCodeData.address,
+    bankName: swiftCodeData.bankName,
+    countryISO2: swiftCodeData.countryISO2,
+    countryName: swiftCodeData.countryName,
+    isHeadquarter: swiftCodeData.isHeadquarter,
+    swiftCode: swiftCodeData.swiftCode,
+    status: swiftCodeData.status || 'active',
+    connectivityStatus: swiftCodeData.connectivityStatus || connectivityOf(swiftCodeData.swiftCode)
+  };
+  
+  if (swiftCodeData.statusReason) {
+    record.statusReason = swiftCodeData.statusReason;
+  }
+  
+  if (swiftCodeData.externalIds && swiftCodeData.externalIds.size > 0) {
+    record.externalIds = Object.fromEntries(swiftCodeData.externalIds);
+  }
+  
+  if (swiftCodeData.isTemporary) {
+    record.isTemporary = true;
+    record.expiresAt = swiftCodeData.expiresAt;
+    record.deprecated = Boolean(swiftCodeData.deprecatedAt);
+  }
+  
+  if (swiftCodeData.successor) {
+    record.successor = swiftCodeData.successor;
+    record.deprecated = Boolean(swiftCodeData.deprecatedAt);
+  }
+  
+  return record;
+};
+
+// Branch entry of a v1 headquarter detail
+exports.serializeBranch = (branch) => ({
+  address: branch.address,
+  bankName: branch.bankName,
+  countryISO2: branch.countryISO2,
+  isHeadquarter: branch.isHeadquarter,
+  swiftCode: branch.swiftCode
+});
+
+// Canonical v2 representation of a record, identical for headquarters, branches and listings
+exports.serializeRecord = (swiftCodeData) => {
+  const record = {
+    swiftCode: swiftCodeData.swiftCode,
+    bic8: swiftCodeData.swiftCode ? swiftCodeData.swiftCode.substring(0, 8) : undefined,
+    bankName: swiftCodeData.bankName,
+    address: swiftCodeData.address,
+    countryISO2: swiftCodeData.countryISO2,
+    countryName: swiftCodeData.countryName,
+    isHeadquarter: swiftCodeData.isHeadquarter,
+    status: swiftCodeData.status || 'active',
+    connectivityStatus: swiftCodeData.connectivityStatus
+      || (swiftCodeData.swiftCode ? connectivityOf(swiftCodeData.swiftCode) : undefined)
+  };
+  
+  // Optional attributes are only present when set
+  if (swiftCodeData.localizedNames && swiftCodeData.localizedNames.size > 0) {
+    record.localizedNames = Object.fromEntries(swiftCodeData.localizedNames);
+  }
+  
+  if (swiftCodeData.originalAddress) {
+    record.originalAddress = swiftCodeData.originalAddress;
+  }
+  
+  if (swiftCodeData.city) {
+    record.city = swiftCodeData.city;
+  }
+  
+  if (swiftCodeData.lei) {
+    record.lei = swiftCodeData.lei;
+  }
+  
+  if (swiftCodeData.nationalIds && swiftCodeData.nationalIds.length > 0) {
+    record.nationalIds = swiftCodeData.nationalIds.map(({ scheme, value }) => ({ scheme, value }));
+  }
+  
+  if (swiftCodeData.location && swiftCodeData.location.coordinates) {
+    Object.assign(record, fromPoint(swiftCodeData.location));
+  }
+  
+  if (swiftCodeData.branchDescription) {
+    record.branchDescription = swiftCodeData.branchDescription;
+  }
+  
+  if (swiftCodeData.externalIds && swiftCodeData.externalIds.size > 0) {
+    record.externalIds = Object.fromEntries(swiftCodeData.externalIds);
+  }
+  
+  if (swiftCodeData.statusReason) {
+    record.statusReason = swiftCodeData.statusReason;
+  }
+  
+  if (swiftCodeData.isTemporary) {
+    record.isTemporary = true;
+    record.expiresAt = swiftCodeData.expiresAt;
+    record.deprecated = Boolean(swiftCodeData.deprecatedAt);
+  }
+  
+  if (swiftCodeData.successor) {
+    record.successor = swiftCodeData.successor;
+    record.deprecated = Boolean(swiftCodeData.deprecatedAt);
+  }
+  
+  return record;
+};
+
+// src/utils/dataParser.js
+const fs = require('fs');
+const path = require('path');
+const { Readable, pipeline } = require('stream');
+const { parseArgs } = require('util');
+const { StringDecoder } = require('string_decoder');
+const readline = require('readline');
+const csv = require('csv-parser');
+const mongoose = require('mongoose');
+const SwiftCode = require('../models/swiftCode');
+const ImportRun = require('../models/importRun');
+const ImportReject = require('../models/importReject');
+const statsService = require('../services/statsService');
+const bankService = require('../services/bankService');
+const lookupService = require('../services/lookupService');
+const historyService = require('../services/historyService');
+const leaseService = require('../services/leaseService');
+const assignmentService = require('../services/assignmentService');
+const importRunService = require('../services/importRunService');
+const { toRejectReasons } = require('../services/importRejectService');
+const config = require('../config/database');
+const appConfig = require('../config/app');
+const { createDuplicateFilter } = require('./importDedupe');
+const { checkDataQuality } = require('./dataQuality');
+const { validateCoordinates, toPoint } = require('./geo');
+const { normalizeRecordAddress } = require('./addressNormalizer');
+const { getCountryName } = require('./countries');
+const { normalizeInput } = require('./inputNormalizer');
+const { isHeadquarterCode } = require('./bic');
+const { toSearchNames } = require('./bankNames');
+const { readWorkbookRows } = require('./workbookReader');
+const { openSource, isRemoteSource, IMPORT_FORMATS } = require('./importSource');
+
+// Path to the CSV file - update this to match your file location
+const CSV_FILE_PATH = path.resolve(__dirname, '../../../data/swift_codes.csv');
+
+// Columns tried for each record field, in order; a mapping replaces them with a single column.
+// NDJSON exports key their lines by the record field names
+const DEFAULT_COLUMNS = {
+  swiftCode: ['SWIFT', 'swift_code', 'swiftCode'],
+  bankName: ['BANK_NAME', 'bank_name', 'bankName'],
+  address: ['ADDRESS', 'address'],
+  city: ['TOWN NAME', 'TOWN_NAME', 'town_name', 'city'],
+  countryISO2: ['COUNTRY_ISO', 'country_iso', 'countryISO2'],
+  countryName: ['COUNTRY_NAME', 'country_name', 'countryName'],
+  latitude: ['LATITUDE', 'latitude'],
+  longitude: ['LONGITUDE', 'longitude']
+};
+
+const MAPPABLE_FIELDS = Object.keys(DEFAULT_COLUMNS);
+
+const readColumn = (row, field, mapping) => {
+  const columns = mapping && mapping[field] ? [mapping[field]] : DEFAULT_COLUMNS[field];
+  const column = columns.find(name => row[name]);
+  return column ? row[column] : '';
+};
+
+// Map a CSV row to a SWIFT code record
+function parseSwiftCodeRow(row, mapping) {
+  const column = (field) => readColumn(row, field, mapping);
+  
+  // Same trimming and casing rules as API writes
+  const { swiftCode, bankName, address, city, countryISO2, countryName } = normalizeInput({
+    swiftCode: column('swiftCode'),
+    bankName: column('bankName'),
+    address: column('address'),
+    city: column('city'),
+    countryISO2: column('countryISO2'),
+    countryName: column('countryName')
+  });
+  const isHeadquarter = isHeadquarterCode(swiftCode);
+  
+  // Optional coordinate columns; unusable pairs are ignored rather than rejecting the row
+  const latitude = parseFloat(column('latitude'));
+  const longitude = parseFloat(column('longitude'));
+  const location = validateCoordinates(latitude, longitude) ? undefined : toPoint(latitude, longitude);
+  
+  // Create a SWIFT code record
+  return {
+    swiftCode: swiftCode,
+    bankName: bankName,
+    ...normalizeRecordAddress(address, countryISO2),
+    city: city || undefined,
+    location: location,
+    countryISO2: countryISO2,
+    // Prefer the ISO 3166-1 name over the file's spelling
+    countryName: getCountryName(countryISO2) || countryName,
+    isHeadquarter: isHeadquarter
+  };
+}
+
+// A workbook header row names at least the SWIFT code column
+const isHeaderRow = (mapping) => (values) => (mapping && mapping.swiftCode
+  ? values.includes(mapping.swiftCode)
+  : DEFAULT_COLUMNS.swiftCode.some(column => values.includes(column)));
+
+// Chunks decoded with the file's encoding
+async function* decodeChunks(chunks, encoding) {
+  const decoder = new StringDecoder(encoding);
+  for await (const chunk of chunks) {
+    yield decoder.write(chunk);
+  }
+  yield decoder.end();
+}
+
+// An NDJSON line as a row of strings like csv-parser's; error is set when the line isn't a JSON object
+const parseJsonRow = (text) => {
+  let value;
+  try {
+    value = JSON.parse(text);
+  } catch (error) {
+    return { row: {}, error: `Not valid JSON: ${error.message}` };
+  }
+  
+  if (!value || typeof value !== 'object' || Array.isArray(value)) {
+    return { row: {}, error: 'Not a JSON object' };
+  }
+  
+  // Nested values have no column to land in
+  const row = Object.fromEntries(Object.entries(value)
+    .filter(([, field]) => field !== null && typeof field !== 'object')
+    .map(([key, field]) => [key, String(field)]));
+  return { row };
+};
+
+// Raw rows of a CSV file, Excel workbook or NDJSON file with their line in the source (CSV line 1
+// holds the headers); NDJSON lines that can't be read come with an error instead of fields.
+// The source is a local path or an https://, s3:// or gs:// URL; format overrides the detected one
+async function* iterateRows(filePath, {
+  mapping,
+  format,
+  sheet = appConfig.importSheet,
+  headerRow = appConfig.importHeaderRow,
+  delimiter = appConfig.importDelimiter,
+  encoding = appConfig.importEncoding
+} = {}) {
+  const source = await openSource(filePath);
+  const { stream } = source;
+  
+  if ((format || source.format) === 'ndjson') {
+    const lines = readline.createInterface({ input: Readable.from(decodeChunks(stream, encoding)), crlfDelay: Infinity });
+    let line = 0;
+    
+    for await (const text of lines) {
+      line++;
+      if (text.trim()) {
+        yield { line, ...parseJsonRow(text) };
+      }
+    }
+    return;
+  }
+  
+  if ((format || source.format) === 'xlsx') {
+    // exceljs loads the workbook whole; directory workbooks are far smaller than the CSV extracts
+    yield* await readWorkbookRows(stream, { sheet, headerRow, isHeader: isHeaderRow(mapping), name: filePath });
+    return;
+  }
+  
+  // pipeline forwards read errors such as a failed download; async iteration pauses the source
+  // while the consumer is busy writing a batch
+  // Chunks are decoded with the file's encoding and reach csv-parser re-encoded as UTF-8
+  const rows = pipeline(stream, (chunks) => decodeChunks(chunks, encoding), csv({ separator: delimiter }), () => {});
+  let line = 1;
+  
+  for await (const row of rows) {
+    line++;
+    yield { line, row };
+  }
+}
+
+// Read every SWIFT code record from a CSV file or Excel workbook
+async function readSwiftCodes(filePath, options = {}) {
+  const swiftCodes = [];
+  
+  for await (const { row, error } of iterateRows(filePath, options)) {
+    if (!error) {
+      swiftCodes.push(parseSwiftCodeRow(row, options.mapping));
+    }
+  }
+  
+  return swiftCodes;
+}
+
+// Map the first rows of an uploaded CSV without storing anything, so stewards can check the mapping
+async function previewSwiftCodes(content, { mapping, rows = 20 } = {}) {
+  const parsedRows = [];
+  let columns = [];
+  
+  await new Promise((resolve, reject) => {
+    const parser = csv();
+    
+    parser
+      .on('headers', (headers) => {
+        columns = headers;
+      })
+      .on('data', (row) => {
+        parsedRows.push(row);
+        if (parsedRows.length >= rows) {
+          parser.destroy();
+          resolve();
+        }
+      })
+      .on('end', resolve)
+      .on('error', reject);
+    
+    Readable.from([content]).pipe(parser);
+  });
+  
+  const missingColumns = Object.values(mapping || {}).filter(column => !columns.includes(column));
+  
+  const records = await Promise.all(parsedRows.map(async (row, index) => {
+    const document = new SwiftCode(parseSwiftCodeRow(row, mapping));
+    const issues = [];
+    
+    try {
+      // validate() runs the hooks that derive bic8, connectivity and friends
+      await document.validate();
+    } catch (error) {
+      Object.values(error.errors || {}).forEach(fieldError => issues.push({
+        code: 'INVALID_RECORD',
+        field: fieldError.path,
+        message: fieldError.message
+      }));
+    }
+    
+    if (document.swiftCode && document.countryISO2) {
+      issues.push(...checkDataQuality(document));
+    }
+    
+    const record = document.toObject({ flattenMaps: true });
+    delete record._id;
+    delete record.searchNames;
+    
+    // Line 1 holds the headers
+    return { line: index + 2, record, issues };
+  }));
+  
+  return {
+    columns,
+    missingColumns,
+    records,
+    issueCount: records.reduce((count, record) => count + record.issues.length, 0)
+  };
+}
+
+// Parsed rows leave optional fields undefined, which a Mixed path would store as null
+const withoutUndefined = (record) => Object.fromEntries(
+  Object.entries(record).filter(([, value]) => value !== undefined)
+);
+
+// Why a row can't be imported: a line that couldn't be read, fields the schema refuses, or a code
+// an earlier row of the file already holds; firstLines tracks the line each code first appeared on
+const checkRow = (record, line, firstLines, readError) => {
+  if (readError) {
+    return [{ code: 'INVALID_RECORD', field: 'line', message: readError }];
+  }
+  
+  const validationError = new SwiftCode(record).validateSync();
+  const reasons = validationError ? toRejectReasons(validationError) : [];
+  
+  if (record.swiftCode && firstLines.has(record.swiftCode)) {
+    reasons.push({
+      code: 'DUPLICATE_IN_FILE',
+      field: 'swiftCode',
+      message: `SWIFT code ${record.swiftCode} already appears on line ${firstLines.get(record.swiftCode)}`
+    });
+  } else if (record.swiftCode) {
+    firstLines.set(record.swiftCode, line);
+  }
+  
+  return reasons;
+};
+
+const IMPORT_MODES = ['replace', 'upsert', 'delta'];
+
+// Fields the model derives from the file's values, written along with them by upserts
+const DERIVED_FIELDS = ['bic8', 'bankCode', 'countryCode', 'locationCode', 'branchCode', 'connectivityStatus', 'searchNames', 'countryName', 'isHeadquarter'];
+
+const MISSING_FROM_IMPORT = 'Missing from the latest import';
+const IMPORT_ACTOR = 'system:import';
+
+// File rows carry no localized names, so search names keep the variants of the stored ones
+const keepLocalizedSearchNames = (document, record, stored) => {
+  if (record.localizedNames === undefined && stored && stored.localizedNames) {
+    document.searchNames = toSearchNames(document.bankName, stored.localizedNames);
+  }
+};
+
+// Update existing codes with the file's values and insert new ones; lifecycle fields such as status,
+// deletedAt and externalIds set through the API are left alone, and so are localized names
+// A file naming another bank for a stored code hands the code over from the start of the import, as
+// POST /v1/swift-codes/:swiftCode/reassign would; pairs are { document, existing }
+const recordHolderChanges = (pairs, startedAt) => Promise.all(pairs
+  .filter(({ document, existing }) => existing && !existing.deletedAt && existing.bankName !== document.bankName)
+  .map(({ document, existing }) => assignmentService.recordReassignment(existing, document, startedAt)));
+
+const upsertBatch = async (records, importRunId, startedAt) => {
+  const documents = records.map(record => new SwiftCode(record));
+  // validate() runs the hooks that derive bic8, connectivity and friends
+  await Promise.all(documents.map(document => document.validate()));
+  
+  const stored = await SwiftCode.find(
+    { swiftCode: { $in: documents.map(document => document.swiftCode) } },
+    'swiftCode localizedNames deletedAt bankName address city countryISO2 countryName lei'
+  ).setOptions({ withDeleted: true }).lean();
+  const storedByCode = new Map(stored.map(code => [code.swiftCode, code]));
+  documents.forEach((document, index) => keepLocalizedSearchNames(document, records[index], storedByCode.get(document.swiftCode)));
+  
+  const operations = documents.map((document, index) => {
+    const fields = [...Object.keys(withoutUndefined(records[index])), ...DERIVED_FIELDS];
+    const values = Object.fromEntries(fields.map(field => [field, document.get(field)]));
+    
+    return {
+      updateOne: {
+        filter: { swiftCode: document.swiftCode },
+        update: { $set: { ...values, importRun: importRunId } },
+        upsert: true
+      }
+    };
+  });
+  
+  const result = await SwiftCode.bulkWrite(operations, { ordered: false });
+  await SwiftCode.invalidateInstitutions(documents.map(document => document.bic8));
+  await recordHolderChanges(documents.map(document => ({ document, existing: storedByCode.get(document.swiftCode) })), startedAt);
+  return { inserted: result.upsertedCount, updated: result.modifiedCount };
+};
+
+// Active codes an earlier import wrote; codes created through the API were never in a file
+const ACTIVE_IMPORTED = { importRun: { $exists: true }, status: { $in: ['active', null] } };
+
+// Set codes an earlier import wrote but this upsert's file doesn't list to inactive, and bring back the
+// ones an earlier import set inactive; seen holds every code the file mentions, rejected rows
+// included, so a bad row doesn't deactivate a live code
+const reconcileMissing = async (importRunId, seen) => {
+  const now = new Date();
+  const missing = (await SwiftCode.find({ ...ACTIVE_IMPORTED, importRun: { $exists: true, $ne: importRunId } }))
+    .filter(code => !seen.has(code.swiftCode));
+  const changes = { status: 'inactive', statusReason: MISSING_FROM_IMPORT, statusChangedAt: now };
+  
+  if (missing.length > 0) {
+    await SwiftCode.updateMany({ _id: { $in: missing.map(code => code._id) } }, { $set: changes });
+    await historyService.recordBulkRevisions(missing, changes, 'deactivate', IMPORT_ACTOR);
+  }
+  
+  const returned = await SwiftCode.find({ importRun: importRunId, status: 'inactive', statusReason: MISSING_FROM_IMPORT });
+  const reactivation = { status: 'active', statusReason: null, statusChangedAt: now };
+  
+  if (returned.length > 0) {
+    await SwiftCode.updateMany({ _id: { $in: returned.map(code => code._id) } }, { $set: reactivation });
+    await historyService.recordBulkRevisions(returned, reactivation, 'activate', IMPORT_ACTOR);
+  }
+  
+  return missing.length;
+};
+
+// Mongoose arrays and subdocuments compared and reported as plain values
+const plainValue = (value) => (value && typeof value.toObject === 'function' ? value.toObject() : value);
+
+// Sort a batch against the stored records, deleted ones included: codes the collection lacks (or has
+// deleted) are added, codes whose file or derived fields differ are modified with a from/to per field
+const diffBatch = async (records) => {
+  const documents = records.map(record => new SwiftCode(record));
+  await Promise.all(documents.map(document => document.validate()));
+  
+  const stored = await SwiftCode.find({ swiftCode: { $in: documents.map(document => document.swiftCode) } })
+    .setOptions({ withDeleted: true });
+  const storedByCode = new Map(stored.map(code => [code.swiftCode, code]));
+  const diff = { added: [], modified: [], unchanged: 0 };
+  
+  documents.forEach((document, index) => {
+    const existing = storedByCode.get(document.swiftCode);
+    const fields = [...new Set([...Object.keys(withoutUndefined(records[index])), ...DERIVED_FIELDS])];
+    keepLocalizedSearchNames(document, records[index], existing);
+    
+    if (!existing || existing.deletedAt) {
+      diff.added.push({ document, existing, fields });
+      return;
+    }
+    
+    const changes = {};
+    fields.forEach(field => {
+      const from = plainValue(existing.get(field));
+      const to = plainValue(document.get(field));
+      if (JSON.stringify(from ?? null) !== JSON.stringify(to ?? null)) {
+        changes[field] = { from: from ?? null, to: to ?? null };
+      }
+    });
+    
+    if (Object.keys(changes).length > 0) {
+      diff.modified.push({ document, existing, changes });
+    } else {
+      diff.unchanged++;
+    }
+  });
+  
+  return diff;
+};
+
+// Write only what diffBatch found: added codes in full, modified ones field by field, each with a revision
+const applyDelta = async ({ added, modified }, importRunId, startedAt) => {
+  const valuesOf = (document, fields) => Object.fromEntries(fields.map(field => [field, document.get(field)]));
+  const writes = [
+    ...added.map(({ document, existing, fields }) => ({
+      document,
+      existing,
+      values: { ...valuesOf(document, fields), deletedAt: null },
+      upsert: true
+    })),
+    ...modified.map(({ document, existing, changes }) => ({
+      document,
+      existing,
+      values: valuesOf(document, Object.keys(changes)),
+      upsert: false
+    }))
+  ];
+  
+  if (writes.length === 0) {
+    return;
+  }
+  
+  await SwiftCode.bulkWrite(writes.map(({ document, values, upsert }) => ({
+    updateOne: {
+      filter: { swiftCode: document.swiftCode },
+      update: { $set: { ...values, importRun: importRunId } },
+      upsert
+    }
+  })), { ordered: false });
+  await SwiftCode.invalidateInstitutions(writes.map(({ document }) => document.bic8));
+  await recordHolderChanges(modified, startedAt);
+  
+  await historyService.recordRevisions(writes.map(({ document, existing, values }) => ({
+    action: 'import',
+    actor: IMPORT_ACTOR,
+    previous: existing || null,
+    current: existing ? { ...existing.toObject({ flattenMaps: true }), ...values } : document
+  })));
+};
+
+// Codes an earlier import wrote that the file no longer mentions; codes created through the API were
+// never in a file and are left alone
+const findMissing = async (seen) => {
+  const missing = [];
+  for await (const { swiftCode } of SwiftCode.find({ importRun: { $exists: true } }, 'swiftCode').lean().cursor()) {
+    if (!seen.has(swiftCode)) {
+      missing.push(swiftCode);
+    }
+  }
+  return missing;
+};
+
+// Soft-delete the codes a delta import's file no longer lists, batchSize at a time
+const removeMissing = async (codes, batchSize) => {
+  const changes = { deletedAt: new Date() };
+  
+  for (let start = 0; start < codes.length; start += batchSize) {
+    const removed = await SwiftCode.find({ swiftCode: { $in: codes.slice(start, start + batchSize) } });
+    await SwiftCode.updateMany({ _id: { $in: removed.map(code => code._id) } }, { $set: changes });
+    await historyService.recordBulkRevisions(removed, changes, 'delete', IMPORT_ACTOR);
+  }
+};
+
+// What a delta import added, modified (with its field diffs) and removed
+const toChangeset = ({ added, modified }) => ({
+  added: added.map(({ document }) => document.swiftCode),
+  modified: modified.map(({ document, changes }) => ({ swiftCode: document.swiftCode, changes }))
+});
+
+// A reject as one NDJSON line of the rejects file
+const toRejectLine = ({ line, record, reasons }) => `${JSON.stringify({ line, swiftCode: record.swiftCode || null, reasons, record })}\n`;
+
+const countReasons = (rejects, counts) => {
+  rejects.forEach(reject => reject.reasons.forEach(({ code }) => {
+    counts[code] = (counts[code] || 0) + 1;
+  }));
+  return counts;
+};
+
+const IMPORT_LEASE = 'import';
+
+// Thrown by importSwiftCodes while another process, instance or the scheduler is importing
+class ImportRunningError extends Error {
+  constructor() {
+    super('An import is already running');
+    this.name = 'ImportRunningError';
+    this.code = 'IMPORT_RUNNING';
+  }
+}
+
+// Load a CSV, workbook or NDJSON file into the SWIFT codes using the current connection: 'replace' mode
+// rebuilds the collection from the file, 'upsert' merges the file into it and, with
+// deactivateMissing, sets codes the file no longer lists to inactive, and 'delta' writes only the
+// codes that differ from the stored records and removes the imported ones the file no longer lists,
+// returning the changeset, which is kept with the import run and also written to changesetFile when
+// set. Rejected rows are stored in the rejects collection and, with rejectsFile, also written to that file.
+// Upsert and delta imports record a reassignment for stored codes whose bankName the file changes;
+// replace imports rebuild the collection and don't. Imports run one at a time across every instance,
+// the scheduler and the CLI. A failing import is stored as a failed run, whose id is set on the
+// rethrown error as importRunId. With dryRun the file goes through the same steps but each batch is
+// classified against the collection instead of written, and a report of what the import would do
+// is returned; dry runs write nothing, so they don't wait for the lease
+async function importSwiftCodes(filePath = CSV_FILE_PATH, options = {}) {
+  if (options.dryRun) {
+    return applyImport(filePath, options);
+  }
+  
+  const lease = await leaseService.acquire(IMPORT_LEASE);
+  if (!lease) {
+    throw new ImportRunningError();
+  }
+  
+  const startedAt = new Date();
+  try {
+    return await applyImport(filePath, options);
+  } catch (error) {
+    const run = await importRunService.recordFailure({
+      source: options.source || filePath,
+      mode: options.mode || appConfig.importMode,
+      trigger: options.trigger || 'cli',
+      startedAt
+    }, error).catch(recordError => console.error('Failed to record the failed import', recordError));
+    error.importRunId = run && run._id;
+    throw error;
+  } finally {
+    await lease.release();
+  }
+}
+
+async function applyImport(filePath, options) {
+  const {
+    mode = appConfig.importMode,
+    deactivateMissing = appConfig.importDeactivateMissing,
+    dedupe = appConfig.importDedupe,
+    dedupeThreshold = appConfig.importDedupeThreshold,
+    batchSize = appConfig.importBatchSize,
+    source = filePath,
+    sourceHash,
+    trigger = 'cli',
+    dryRun = false,
+    // Dry runs write nothing, files included
+    rejectsFile = dryRun ? '' : appConfig.importRejectsFile,
+    changesetFile
+  } = options;
+  
+  if (!IMPORT_MODES.includes(mode)) {
+    throw new Error(`Unknown import mode ${mode}, expected one of: ${IMPORT_MODES.join(', ')}`);
+  }
+  
+  // Truncated up front, so a clean import leaves an empty file rather than the last import's rows
+  if (rejectsFile) {
+    await fs.promises.writeFile(rejectsFile, '');
+  }
+  
+  const startedAt = new Date();
+  // Known up front so rejects and upserted codes can point at it while the file streams
+  const importRunId = new mongoose.Types.ObjectId();
+  // Vendor files sometimes repeat a branch with trivially different addresses
+  const isDuplicate = dedupe ? createDuplicateFilter(dedupeThreshold) : () => false;
+  
+  const firstLines = new Map();
+  let batch = [];
+  let rejects = [];
+  let cleared = mode !== 'replace';
+  let rows = 0;
+  let imported = 0;
+  let inserted = 0;
+  let updated = 0;
+  let collapsedDuplicates = 0;
+  let rejectedCount = 0;
+  const rejectReasonCounts = {};
+  // Delta and deactivating upsert imports: every code the file mentions, rejected rows included, so a
+  // bad row isn't a removal
+  const seen = new Set();
+  const changeset = { added: [], modified: [], removed: [] };
+  let unchanged = 0;
+  // Dry runs: stored codes the batches matched, the rejected rows and the rows that would be stored
+  // with a warning
+  let existing = 0;
+  const dryRunRejects = [];
+  const warnings = [];
+  
+  const write = async () => {
+    // Cleared only once a batch holds a valid row, so a missing or unreadable file, or one whose rows
+    // are all rejected, doesn't wipe the collection; a file failing halfway through still leaves a
+    // partial dataset
+    if (!cleared && batch.length > 0) {
+      await SwiftCode.deleteMany({});
+      console.log('Cleared existing SWIFT code data');
+      cleared = true;
+    }
+    
+    if (batch.length > 0 && mode === 'upsert') {
+      const counts = await upsertBatch(batch, importRunId, startedAt);
+      inserted += counts.inserted;
+      updated += counts.updated;
+    } else if (batch.length > 0 && mode === 'delta') {
+      const diff = await diffBatch(batch);
+      await applyDelta(diff, importRunId, startedAt);
+      
+      const { added, modified } = toChangeset(diff);
+      changeset.added.push(...added);
+      changeset.modified.push(...modified);
+      unchanged += diff.unchanged;
+    } else if (batch.length > 0) {
+      await SwiftCode.insertMany(batch.map(record => ({ ...record, importRun: importRunId })));
+    }
+    
+    if (rejects.length > 0) {
+      await ImportReject.insertMany(rejects);
+    }
+    if (rejects.length > 0 && rejectsFile) {
+      await fs.promises.appendFile(rejectsFile, rejects.map(toRejectLine).join(''));
+    }
+  };
+  
+  // What write would do with the batch, reading the collection only; upserts match deleted records too
+  const classify = async () => {
+    if (batch.length > 0 && mode === 'upsert') {
+      existing += await SwiftCode.countDocuments({ swiftCode: { $in: batch.map(record => record.swiftCode) } }).setOptions({ withDeleted: true });
+    } else if (batch.length > 0 && mode === 'delta') {
+      const { added, modified } = toChangeset(await diffBatch(batch));
+      changeset.added.push(...added);
+      changeset.modified.push(...modified);
+    }
+    
+    dryRunRejects.push(...rejects.map(({ line, record, reasons }) => ({ line, swiftCode: record.swiftCode || null, reasons })));
+  };
+  
+  const flush = async () => {
+    await (dryRun ? classify() : write());
+    imported += batch.length;
+    countReasons(rejects, rejectReasonCounts);
+    batch = [];
+    rejects = [];
+  };
+  
+  for await (const { line, row, error } of iterateRows(filePath, options)) {
+    const record = parseSwiftCodeRow(row, options.mapping);
+    rows++;
+    if (record.swiftCode) {
+      seen.add(record.swiftCode);
+    }
+    
+    if (!error && isDuplicate(record)) {
+      collapsedDuplicates++;
+      continue;
+    }
+    
+    // Skip rows that can't be stored instead of failing the whole import, keeping them for correction
+    const reasons = checkRow(record, line, firstLines, error);
+    if (reasons.length > 0) {
+      rejectedCount++;
+      rejects.push({ importRun: importRunId, line, record: withoutUndefined(record), reasons });
+    } else {
+      batch.push(record);
+    }
+    
+    // Stored anyway, but usually a sign the file's columns are shifted
+    const issues = dryRun && reasons.length === 0 ? checkDataQuality(record).filter(issue => issue.code === 'COUNTRY_MISMATCH') : [];
+    if (issues.length > 0) {
+      warnings.push({ line, swiftCode: record.swiftCode, issues });
+    }
+    
+    if (batch.length + rejects.length >= batchSize) {
+      await flush();
+      if (!dryRun) {
+        console.log(`Imported ${imported} SWIFT code records so far`);
+      }
+    }
+  }
+  
+  await flush();
+  
+  // The collection was left alone, see write; a run without rows would read as an empty dataset
+  if (mode === 'replace' && imported === 0 && !dryRun) {
+    throw new Error(`Replace import of ${source} found no valid rows (${rejectedCount} rejected), the existing data was kept`);
+  }
+  
+  if (dryRun) {
+    return dryRunReport({ source, mode, rows, imported, collapsedDuplicates, rejects: dryRunRejects, warnings }, {
+      deactivateMissing, seen, changeset, existing
+    });
+  }
+  
+  if (collapsedDuplicates > 0) {
+    console.log(`Collapsed ${collapsedDuplicates} near-duplicate rows`);
+  }
+  if (rejectedCount > 0) {
+    const reasons = Object.entries(rejectReasonCounts).map(([code, count]) => `${code}: ${count}`).join(', ');
+    console.log(`Rejected ${rejectedCount} invalid rows (${reasons})${rejectsFile ? `, written to ${rejectsFile}` : ''}`);
+  }
+  console.log(imported > 0 ? `Successfully imported ${imported} SWIFT code records` : 'No data found to import');
+  
+  const upsertCounts = mode === 'upsert'
+    ? { insertedCount: inserted, updatedCount: updated, deactivatedCount: deactivateMissing ? await reconcileMissing(importRunId, seen) : 0 }
+    : {};
+  
+  if (upsertCounts.deactivatedCount > 0) {
+    console.log(`Set ${upsertCounts.deactivatedCount} codes missing from the file to inactive`);
+  }
+  
+  if (mode === 'delta') {
+    changeset.removed = await findMissing(seen);
+    await removeMissing(changeset.removed, batchSize);
+    console.log(`Delta: ${changeset.added.length} added, ${changeset.modified.length} modified, ${changeset.removed.length} removed, ${unchanged} unchanged`);
+  }
+  const deltaCounts = mode === 'delta'
+    ? { addedCount: changeset.added.length, modifiedCount: changeset.modified.length, removedCount: changeset.removed.length, unchangedCount: unchanged }
+    : {};
+  
+  await bankService.rebuildBanks();
+  await lookupService.rebuild();
+  
+  // Record the new dataset version with its country rollup for trend reporting
+  await ImportRun.create({
+    _id: importRunId,
+    trigger,
+    source,
+    sourceHash,
+    mode,
+    startedAt,
+    completedAt: new Date(),
+    recordCount: imported,
+    collapsedDuplicates,
+    rejectedCount,
+    ...upsertCounts,
+    ...deltaCounts,
+    ...(mode === 'delta' && { changeset }),
+    countryRollup: await statsService.computeCountryRollup()
+  });
+  
+  if (mode === 'delta' && changesetFile) {
+    await fs.promises.writeFile(changesetFile, JSON.stringify({ importRunId, source, startedAt, ...deltaCounts, changeset }, null, 2));
+    console.log(`Wrote changeset to ${changesetFile}`);
+  }
+  
+  return {
+    importRunId,
+    mode,
+    imported,
+    collapsedDuplicates,
+    rejectedCount,
+    rejectReasonCounts,
+    ...upsertCounts,
+    ...(mode === 'delta' && { ...deltaCounts, changeset }),
+    ...(rejectsFile && { rejectsFile }),
+    ...(mode === 'delta' && changesetFile && { changesetFile })
+  };
+}
+
+// What a dry run found: what the import would insert, update and remove or deactivate, and which rows
+// it would reject; delta dry runs also return the changeset the import would apply
+const dryRunReport = async ({ source, mode, rows, imported, collapsedDuplicates, rejects, warnings }, classified) => {
+  const { deactivateMissing, seen, changeset, existing } = classified;
+  let outcome;
+  
+  if (mode === 'replace') {
+    // Without a valid row the import fails before clearing anything
+    outcome = { wouldInsert: imported, wouldUpdate: 0, wouldRemove: imported > 0 ? await SwiftCode.estimatedDocumentCount() : 0 };
+  } else if (mode === 'delta') {
+    changeset.removed = await findMissing(seen);
+    outcome = {
+      wouldInsert: changeset.added.length,
+      wouldUpdate: changeset.modified.length,
+      wouldRemove: changeset.removed.length,
+      changeset
+    };
+  } else {
+    outcome = {
+      wouldInsert: imported - existing,
+      wouldUpdate: existing,
+      // As reconcileMissing counts them
+      wouldDeactivate: deactivateMissing
+        ? (await SwiftCode.find(ACTIVE_IMPORTED, 'swiftCode').lean()).filter(code => !seen.has(code.swiftCode)).length
+        : 0
+    };
+  }
+  
+  return {
+    source,
+    mode,
+    rows,
+    ...outcome,
+    collapsedDuplicates,
+    rejectedCount: rejects.length,
+    rejects,
+    warnings
+  };
+};
+
+const printDryRun = (report) => {
+  console.log(`Dry run of ${report.source} in ${report.mode} mode: ${report.rows} rows`);
+  console.log(`Would insert ${report.wouldInsert}, update ${report.wouldUpdate}${report.mode === 'upsert'
+    ? ` and deactivate ${report.wouldDeactivate} codes`
+    : ` and remove ${report.wouldRemove} existing records`}`);
+  console.log(`Would collapse ${report.collapsedDuplicates} near-duplicate rows and reject ${report.rejectedCount} rows`);
+  
+  report.rejects.forEach(reject => console.log(`  line ${reject.line}: ${reject.reasons.map(reason => reason.message).join('; ')}`));
+  report.warnings.forEach(warning => console.log(`  line ${warning.line} (warning): ${warning.issues.map(issue => issue.message).join('; ')}`));
+};
+
+const USAGE = `Usage: node src/utils/dataParser.js <file> [options]
+
+Loads a CSV file, Excel workbook or NDJSON file of SWIFT codes into MongoDB. The file is a local path or an
+https://, s3://bucket/key or gs://bucket/key URL, which is streamed down rather than saved first.
+
+Options:
+  --file <path|url>       File to import, instead of the positional argument
+  --mode <mode>           replace (default) rebuilds the collection, upsert merges the file into it,
+                          delta writes only what changed and removes codes the file no longer lists,
+                          dry-run reports what a replace would do without writing
+  --format <format>       csv, xlsx or ndjson; detected from the content by default
+  --dry-run               Report what the import would do without writing; combines with --mode
+  --report <path>         With a dry run, also write the report as JSON
+  --rejects <path>        Also write rejected rows, with their line and reasons, to this file as NDJSON
+  --changeset <path>      With delta, also write the added, modified and removed codes as JSON
+  --deactivate-missing    With upsert, set codes missing from the file to inactive
+  --delimiter <char>      CSV column separator (default ",")
+  --encoding <name>       CSV or NDJSON file encoding, e.g. latin1 (default utf8)
+  --batch-size <n>        Rows written per batch (default 1000)
+  --mongo-uri <uri>       Connect here instead of MONGODB_URI
+  --help                  Show this help`;
+
+// Invalid values throw, so automation fails instead of importing with defaults
+const parseCliOptions = (args) => {
+  const { values, positionals } = parseArgs({
+    args,
+    allowPositionals: true,
+    options: {
+      file: { type: 'string' },
+      mode: { type: 'string' },
+      format: { type: 'string' },
+      'dry-run': { type: 'boolean' },
+      report: { type: 'string' },
+      rejects: { type: 'string' },
+      changeset: { type: 'string' },
+      'deactivate-missing': { type: 'boolean' },
+      delimiter: { type: 'string' },
+      encoding: { type: 'string' },
+      'batch-size': { type: 'string' },
+      'mongo-uri': { type: 'string' },
+      help: { type: 'boolean' }
+    }
+  });
+  
+  const file = values.file || positionals[0];
+  if (values.help || !file) {
+    return { help: true };
+  }
+  
+  const dryRun = Boolean(values['dry-run']) || values.mode === 'dry-run';
+  const mode = values.mode === 'dry-run' ? undefined : values.mode;
+  if (mode && !IMPORT_MODES.includes(mode)) {
+    throw new Error(`Unknown mode ${mode}, expected one of: ${[...IMPORT_MODES, 'dry-run'].join(', ')}`);
+  }
+  
+  const batchSize = values['batch-size'] === undefined ? undefined : Number(values['batch-size']);
+  if (batchSize !== undefined && !(Number.isInteger(batchSize) && batchSize > 0)) {
+    throw new Error('--batch-size must be a positive integer');
+  }
+  
+  if (values.encoding && !Buffer.isEncoding(values.encoding)) {
+    throw new Error(`Unsupported encoding ${values.encoding}`);
+  }
+  
+  if (values.format && !IMPORT_FORMATS.includes(values.format)) {
+    throw new Error(`Unknown format ${values.format}, expected one of: ${IMPORT_FORMATS.join(', ')}`);
+  }
+  
+  if (values.delimiter !== undefined && values.delimiter.length !== 1) {
+    throw new Error('--delimiter must be a single character');
+  }
+  
+  return {
+    help: false,
+    filePath: isRemoteSource(file) ? file : path.resolve(file),
+    dryRun,
+    reportPath: values.report && path.resolve(values.report),
+    mongoURI: values['mongo-uri'] || config.mongoURI,
+    options: {
+      ...(mode && { mode }),
+      ...(values.format && { format: values.format }),
+      ...(values['deactivate-missing'] && { deactivateMissing: true }),
+      ...(values.rejects && { rejectsFile: path.resolve(values.rejects) }),
+      ...(values.changeset && { changesetFile: path.resolve(values.changeset) }),
+      ...(values.delimiter && { delimiter: values.delimiter }),
+      ...(values.encoding && { encoding: values.encoding }),
+      ...(batchSize && { batchSize })
+    }
+  };
+};
+
+// Parse SWIFT codes from CSV file
+async function parseAndStoreSwiftCodes(args = process.argv.slice(2)) {
+  let cli;
+  try {
+    cli = parseCliOptions(args);
+  } catch (error) {
+    console.error(`${error.message}\n\n${USAGE}`);
+    process.exit(1);
+  }
+  
+  if (cli.help) {
+    console.log(USAGE);
+    process.exit(args.includes('--help') ? 0 : 1);
+  }
+  
+  try {
+    const { filePath, dryRun, reportPath, mongoURI, options } = cli;
+    
+    // Connect to MongoDB
+    await mongoose.connect(mongoURI);
+    console.log('Connected to MongoDB');
+    
+    if (dryRun) {
+      const report = await importSwiftCodes(filePath, { ...options, dryRun: true });
+      
+      printDryRun(report);
+      if (reportPath) {
+        fs.writeFileSync(reportPath, JSON.stringify(report, null, 2));
+        console.log(`Wrote dry-run report to ${reportPath}`);
+      }
+    } else {
+      await importSwiftCodes(filePath, options);
+    }
+    
+    // Disconnect from MongoDB
+    await mongoose.disconnect();
+  } catch (error) {
+    console.error('Error parsing SWIFT codes:', error);
+    process.exit(1);
+  }
+}
+
+// Execute if this file is run directly
+if (require.main === module) {
+  parseAndStoreSwiftCodes();
+}
+
+module.exports = {
+  parseAndStoreSwiftCodes,
+  importSwiftCodes,
+  previewSwiftCodes,
+  readSwiftCodes,
+  parseSwiftCodeRow,
+  parseCliOptions,
+  IMPORT_MODES,
+  MAPPABLE_FIELDS,
+  ImportRunningError
+};
+
+// src/utils/workbookReader.js
+const fs = require('fs');
+const ExcelJS = require('exceljs');
+
+// .xlsx files are ZIP archives, so the content tells them apart from CSV whatever the file is called
+const ZIP_SIGNATURE = Buffer.from([0x50, 0x4b, 0x03, 0x04]);
+
+// Official directory spreadsheets put a title block above the headers
+const HEADER_SEARCH_ROWS = 20;
+
+exports.hasZipSignature = (head) => head.length >= ZIP_SIGNATURE.length && head.subarray(0, ZIP_SIGNATURE.length).equals(ZIP_SIGNATURE);
+
+exports.isWorkbook = async (filePath) => {
+  const handle = await fs.promises.open(filePath, 'r');
+  
+  try {
+    const { buffer, bytesRead } = await handle.read(Buffer.alloc(ZIP_SIGNATURE.length), 0, ZIP_SIGNATURE.length, 0);
+    return bytesRead === ZIP_SIGNATURE.length && buffer.equals(ZIP_SIGNATURE);
+  } finally {
+    await handle.close();
+  }
+};
+
+const rowValues = (row) => {
+  const values = [];
+  row.eachCell({ includeEmpty: true }, (cell, column) => {
+    values[column] = cell.text.trim();
+  });
+  return values;
+};
+
+// First row (1-based) among the top of the sheet that isHeader accepts, or null
+const findHeaderRow = (worksheet, isHeader) => {
+  const last = Math.min(worksheet.rowCount, HEADER_SEARCH_ROWS);
+  
+  for (let number = 1; number <= last; number++) {
+    if (isHeader(rowValues(worksheet.getRow(number)).filter(Boolean))) {
+      return number;
+    }
+  }
+  return null;
+};
+
+const selectWorksheet = (workbook, sheet) => {
+  if (sheet === undefined || sheet === null || sheet === '') {
+    return null;
+  }
+  // Sheets are picked by name, or by 1-based position for --sheet=2 style options
+  return workbook.getWorksheet(sheet) || (/^\d+$/.test(String(sheet)) ? workbook.worksheets[Number(sheet) - 1] : null);
+};
+
+// Rows of a workbook, given as a path or a stream, as { line, row } objects keyed by header, like
+// csv-parser produces. Without an explicit sheet or header row, the first sheet with a row isHeader accepts is used
+exports.readWorkbookRows = async (input, { sheet, headerRow, isHeader = () => true, name = input } = {}) => {
+  const workbook = new ExcelJS.Workbook();
+  await (typeof input === 'string' ? workbook.xlsx.readFile(input) : workbook.xlsx.read(input));
+  
+  const chosen = selectWorksheet(workbook, sheet);
+  if (sheet && !chosen) {
+    throw new Error(`Worksheet ${sheet} not found in ${name}`);
+  }
+  
+  const candidates = chosen ? [chosen] : workbook.worksheets;
+  let worksheet = null;
+  let headerNumber = null;
+  
+  for (const candidate of candidates) {
+    headerNumber = headerRow || findHeaderRow(candidate, isHeader);
+    if (headerNumber) {
+      worksheet = candidate;
+      break;
+    }
+  }
+  
+  if (!worksheet) {
+    throw new Error(`No header row found in ${name}`);
+  }
+  
+  const headers = rowValues(worksheet.getRow(headerNumber));
+  const rows = [];
+  
+  worksheet.eachRow((row, number) => {
+    if (number <= headerNumber) {
+      return;
+    }
+    
+    const values = rowValues(row);
+    if (!values.some(Boolean)) {
+      return;
+    }
+    
+    const record = {};
+    headers.forEach((header, column) => {
+      if (header) {
+        record[header] = values[column] || '';
+      }
+    });
+    rows.push({ line: number, row: record });
+  });
+  
+  return rows;
+};
+
+// src/utils/importSource.js
+const fs = require('fs');
+const https = require('https');
+const { Readable } = require('stream');
+const { openObject } = require('../services/objectStorage');
+const { hasZipSignature } = require('./workbookReader');
+const appConfig = require('../config/app');
+
+const MAX_REDIRECTS = 5;
+
+exports.IMPORT_FORMATS = ['csv', 'xlsx', 'ndjson'];
+
+// Told by content rather than file name: workbooks are ZIP archives, NDJSON lines are JSON objects
+const detectFormat = (head) => {
+  if (hasZipSignature(head)) {
+    return 'xlsx';
+  }
+  return head.toString('latin1').replace(/^\uFEFF|^\xEF\xBB\xBF/, '').trimStart().startsWith('{') ? 'ndjson' : 'csv';
+};
+
+// Import sources are local paths, https:// URLs or s3:// / gs:// objects
+exports.isRemoteSource = (source) => /^[a-z][a-z0-9+.-]*:\/\//i.test(source);
+
+// The socket timeout also covers the body, so a server stalling mid-download errors the stream
+const openUrl = (url, redirects = 0) => new Promise((resolve, reject) => {
+  const timeout = appConfig.importDownloadTimeoutMs;
+  const request = https.get(url, { timeout }, (response) => {
+    const { statusCode, headers } = response;
+    
+    if (statusCode >= 300 && statusCode < 400 && headers.location && redirects < MAX_REDIRECTS) {
+      response.resume();
+      resolve(openUrl(new URL(headers.location, url).toString(), redirects + 1));
+      return;
+    }
+    
+    if (statusCode !== 200) {
+      response.resume();
+      reject(new Error(`Downloading ${url} failed with HTTP ${statusCode}`));
+      return;
+    }
+    
+    resolve(response);
+  });
+  
+  request.on('timeout', () => request.destroy(new Error(`Downloading ${url} timed out after ${timeout} ms`)));
+  request.on('error', reject);
+});
+
+// Byte stream of a source; remote files are streamed down rather than saved to disk first
+exports.openStream = async (source) => {
+  if (!exports.isRemoteSource(source)) {
+    return fs.createReadStream(source);
+  }
+  
+  if (source.startsWith('https://')) {
+    return openUrl(source);
+  }
+  
+  if (/^(s3|gs):\/\//.test(source)) {
+    return openObject(source);
+  }
+  
+  throw new Error(`Unsupported import source: ${source}`);
+};
+
+// The stream of a source and its format; the first bytes are read to tell, then handed back so the
+// returned stream still starts at the beginning
+exports.openSource = async (source) => {
+  const chunks = (await exports.openStream(source))[Symbol.asyncIterator]();
+  const head = [];
+  let length = 0;
+  let done = false;
+  
+  while (length < 4 && !done) {
+    const next = await chunks.next();
+    done = next.done;
+    if (!done) {
+      head.push(next.value);
+      length += next.value.length;
+    }
+  }
+  
+  async function* replay() {
+    yield* head;
+    if (!done) {
+      yield* { [Symbol.asyncIterator]: () => chunks };
+    }
+  }
+  
+  return {
+    stream: Readable.from(replay(), { objectMode: false }),
+    format: detectFormat(Buffer.concat(head))
+  };
+};
+
+// src/utils/fields.js
+// Record fields callers may select with ?fields=
+const SELECTABLE_FIELDS = [
+  'swiftCode',
+  'bankName',
+  'address',
+  'countryISO2',
+  'countryName',
+  'isHeadquarter',
+  'status',
+  'connectivityStatus'
+];
+
+// Parse "swiftCode,bankName" into a field list; null means every field
+exports.parseFields = (value) => {
+  if (value === undefined || value === '') {
+    return { fields: null, unknown: [] };
+  }
+  
+  const fields = [...new Set(String(value).split(',').map(field => field.trim()).filter(Boolean))];
+  const unknown = fields.filter(field => !SELECTABLE_FIELDS.includes(field));
+  
+  return { fields, unknown };
+};
+
+// MongoDB projection for the requested fields plus any the caller needs internally
+exports.toProjection = (fields, required = []) => {
+  if (!fields) {
+    return null;
+  }
+  return Object.fromEntries([...new Set([...fields, ...required])].map(field => [field, 1]));
+};
+
+// Drop record fields that weren't requested, keeping structural keys such as branches
+exports.pickFields = (record, fields, keep = []) => {
+  if (!fields) {
+    return record;
+  }
+  return Object.fromEntries(Object.entries(record).filter(([key]) => fields.includes(key) || keep.includes(key)));
+};
+
+exports.SELECTABLE_FIELDS = SELECTABLE_FIELDS;
+
+// src/utils/importDedupe.js
+// Uppercase, drop punctuation and collapse whitespace so trivial differences disappear
+const normalizeAddress = (address) => (address || '')
+  .toUpperCase()
+  .replace(/[^\p{L}\p{N}]+/gu, ' ')
+  .trim();
+
+const bigrams = (value) => {
+  const counts = new Map();
+  for (let i = 0; i < value.length - 1; i++) {
+    const bigram = value.substring(i, i + 2);
+    counts.set(bigram, (counts.get(bigram) || 0) + 1);
+  }
+  return counts;
+};
+
+// Dice coefficient over character bigrams: 1 for identical strings, 0 for nothing in common
+const similarity = (a, b) => {
+  if (a === b) {
+    return 1;
+  }
+  if (a.length < 2 || b.length < 2) {
+    return 0;
+  }
+  
+  const first = bigrams(a);
+  const second = bigrams(b);
+  let overlap = 0;
+  
+  for (const [bigram, count] of first) {
+    overlap += Math.min(count, second.get(bigram) || 0);
+  }
+  
+  return (2 * overlap) / (a.length - 1 + b.length - 1);
+};
+
+// Row-by-row variant for streamed imports: true for a row repeating the BIC of an earlier one with a
+// near-identical address; only the normalized addresses are kept between calls
+const createDuplicateFilter = (threshold) => {
+  const seen = new Map();
+  
+  return (record) => {
+    const address = normalizeAddress(record.address);
+    const previous = seen.get(record.swiftCode);
+    
+    if (previous && previous.some(other => similarity(other, address) >= threshold)) {
+      return true;
+    }
+    
+    if (previous) {
+      previous.push(address);
+    } else {
+      seen.set(record.swiftCode, [address]);
+    }
+    return false;
+  };
+};
+
+// Keep the first of every group of rows with the same BIC and near-identical addresses
+const collapseNearDuplicates = (records, threshold) => {
+  const isDuplicate = createDuplicateFilter(threshold);
+  const kept = records.filter(record => !isDuplicate(record));
+  
+  return { records: kept, collapsed: records.length - kept.length };
+};
+
+module.exports = { normalizeAddress, similarity, createDuplicateFilter, collapseNearDuplicates };
+
+// src/utils/addressNormalizer.js
+const appConfig = require('../config/app');
+
+// Street-type abbreviations; ST is left alone because it also means SAINT
+const ABBREVIATIONS = {
+  AVE: 'AVENUE',
+  BLVD: 'BOULEVARD',
+  RD: 'ROAD',
+  SQ: 'SQUARE'
+};
+
+const COUNTRY_ABBREVIATIONS = {
+  DE: { STR: 'STRASSE' },
+  FR: { AV: 'AVENUE', BD: 'BOULEVARD' },
+  PL: { AL: 'ALEJA', UL: 'ULICA' }
+};
+
+// Postal code layouts, applied after uppercasing
+const COUNTRY_FORMATTERS = {
+  // 00950 -> 00-950
+  PL: (address) => address.replace(/\b(\d{2})\s?(\d{3})\b/g, '$1-$2'),
+  // 1234AB -> 1234 AB
+  NL: (address) => address.replace(/\b(\d{4})\s?([A-Z]{2})\b/g, '$1 $2')
+};
+
+const expandAbbreviations = (address, { countryISO2 }) => {
+  const table = { ...ABBREVIATIONS, ...COUNTRY_ABBREVIATIONS[countryISO2] };
+  return address.replace(/\b([A-Z]+)\.?(?=[\s,]|$)/g, (match, word) => table[word] || match);
+};
+
+// Each step takes the address and the record's context and returns the new address
+const STEPS = {
+  whitespace: (address) => address.replace(/\s+/g, ' ').trim(),
+  uppercase: (address) => address.toUpperCase(),
+  abbreviations: expandAbbreviations,
+  countryFormat: (address, { countryISO2 }) => (COUNTRY_FORMATTERS[countryISO2] ? COUNTRY_FORMATTERS[countryISO2](address) : address)
+};
+
+exports.registerStep = (name, step) => {
+  STEPS[name] = step;
+};
+
+// Run the configured steps in order; unknown step names are skipped
+exports.normalizeAddress = (address, countryISO2, steps = appConfig.addressNormalizationSteps) => steps
+  .filter(name => STEPS[name])
+  .reduce((value, name) => STEPS[name](value, { countryISO2: (countryISO2 || '').toUpperCase() }), address);
+
+// Normalized address fields of a record, keeping what was submitted when it changed
+exports.normalizeRecordAddress = (address, countryISO2) => {
+  const normalized = exports.normalizeAddress(address, countryISO2);
+  return normalized === address ? { address } : { address: normalized, originalAddress: address };
+};
+
+// src/utils/inputNormalizer.js
+// Code and country fields compared case-insensitively everywhere, stored uppercase
+const UPPERCASE_FIELDS = [
+  'swiftCode',
+  'swiftCodes',
+  'bic4',
+  'bic8',
+  'countryISO2',
+  'countryName',
+  'country',
+  'city',
+  'lei',
+  'iban'
+];
+
+const normalizeString = (value, uppercase) => {
+  const collapsed = value.trim().replace(/\s+/g, ' ');
+  return uppercase ? collapsed.toUpperCase() : collapsed;
+};
+
+// Trim and collapse whitespace in every string, uppercasing the ones held by UPPERCASE_FIELDS
+exports.normalizeValue = (field, value) => {
+  const uppercase = UPPERCASE_FIELDS.includes(field);
+  
+  if (typeof value === 'string') {
+    return normalizeString(value, uppercase);
+  }
+  if (Array.isArray(value)) {
+    return value.map(item => exports.normalizeValue(field, item));
+  }
+  if (value && typeof value === 'object' && !(value instanceof Date)) {
+    return exports.normalizeInput(value);
+  }
+  return value;
+};
+
+// Normalized copy of a record, a list of records or any nested request body
+exports.normalizeInput = (input) => {
+  if (Array.isArray(input)) {
+    return input.map(item => exports.normalizeInput(item));
+  }
+  if (!input || typeof input !== 'object') {
+    return input;
+  }
+  
+  return Object.fromEntries(Object.entries(input).map(([field, value]) => [field, exports.normalizeValue(field, value)]));
+};
+
+exports.UPPERCASE_FIELDS = UPPERCASE_FIELDS;
+
+// src/utils/pagination.js
+const appConfig = require('../config/app');
+
+// Configured page sizes of an endpoint family ('country', 'search', 'branches', 'export', 'dashboard'), see pageSizes
+const pageSizesFor = (profile) => appConfig.pageSizes[profile] || appConfig.pageSizes.default;
+
+// Parse page/limit query parameters, clamping the limit to the profile's maximum (0 means no maximum);
+// limit=0 asks for everything, which only profiles without a maximum grant
+exports.parsePagination = (query, profile = 'default') => {
+  const { defaultLimit, maxLimit } = pageSizesFor(profile);
+  const page = parseInt(query.page, 10);
+  const requested = parseInt(query.limit, 10);
+  const limit = Number.isNaN(requested) || requested < 0 ? defaultLimit : requested;
+  
+  return {
+    page: Number.isNaN(page) || page < 1 ? 1 : page,
+    limit: maxLimit ? Math.min(limit || defaultLimit, maxLimit) : limit,
+    pageSizes: { defaultLimit, maxLimit }
+  };
+};
+
+// Limit 0 is a single page holding everything, so the pages after it are empty
+const NOTHING = { _id: { $exists: false } };
+
+// Narrow a find() query to a page of results
+exports.applyPage = (query, page, limit) => {
+  if (limit > 0) {
+    return query.skip((page - 1) * limit).limit(limit);
+  }
+  return page > 1 ? query.where(NOTHING) : query;
+};
+
+// The same as aggregation stages
+exports.pageStages = (page, limit) => {
+  if (limit > 0) {
+    return [{ $skip: (page - 1) * limit }, { $limit: limit }];
+  }
+  return page > 1 ? [{ $match: NOTHING }] : [];
+};
+
+// pageSizes from parsePagination are echoed so clients can tell a clamped limit from the one they sent
+exports.buildPageInfo = (page, limit, totalCount, pageSizes) => ({
+  page,
+  limit,
+  totalCount,
+  totalPages: limit > 0 ? Math.ceil(totalCount / limit) : Math.min(totalCount, 1),
+  ...pageSizes
+});
+
+// src/utils/regex.js
+// Escape user input so it can be embedded in a MongoDB $regex literally
+exports.escapeRegex = (value) => value.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+
+// src/utils/actor.js
+// Principals trusted to act on behalf of the people behind them, e.g. a back office relaying its users
+const DELEGATING_ROLES = ['admin', 'service'];
+
+// Who performed a request: the X-Actor an admin or service principal declares, else the principal
+// itself; anonymous callers can't name themselves
+exports.resolveActor = (req) => {
+  if (!req.consumer || !req.consumer.name) {
+    return 'anonymous';
+  }
+  
+  const declared = DELEGATING_ROLES.includes(req.consumer.role) && (req.get('X-Actor') || '').trim();
+  return declared || req.consumer.name;
+};
+
+// src/utils/clientIp.js
+// req.ip honours the trust proxy setting; IPv4 clients of dual-stack sockets show up as ::ffff:a.b.c.d
+exports.resolveClientIp = (req) => (req.ip || '').replace(/^::ffff:/, '');
+
+// src/utils/bic.js
+// Bank code, country code, location code and optional branch code
+exports.ISO_9362_PATTERN = /^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$/;
+
+// Split a BIC into its ISO 9362 components; 8-character codes denote the primary office (XXX)
+exports.decomposeBic = (swiftCode) => {
+  const code = swiftCode.trim().toUpperCase();
+  
+  return {
+    bankCode: code.substring(0, 4),
+    countryCode: code.substring(4, 6),
+    locationCode: code.substring(6, 8),
+    branchCode: code.length === 11 ? code.substring(8, 11) : 'XXX'
+  };
+};
+
+// Headquarters are the primary office, i.e. branch code XXX or an 8-character code
+exports.isHeadquarterCode = (swiftCode) => exports.decomposeBic(swiftCode).branchCode === 'XXX';
+
+// Institution identifier shared by a headquarter and all of its branches
+exports.toBic8 = (swiftCode) => swiftCode.trim().toUpperCase().substring(0, 8);
+
+// The last character of the location code flags test BICs ('0'), passive participants
+// without a network connection ('1') and reverse billing ('2'); anything else is connected
+const CONNECTIVITY_BY_FLAG = { 0: 'test', 1: 'not-connected', 2: 'reverse-billing' };
+
+exports.CONNECTIVITY_STATUSES = ['connected', 'not-connected', 'test', 'reverse-billing'];
+
+exports.connectivityOf = (swiftCode) => CONNECTIVITY_BY_FLAG[swiftCode.trim().toUpperCase().charAt(7)] || 'connected';
+
+// src/utils/branchCache.js
+const appConfig = require('../config/app');
+const stageTimer = require('./stageTimer');
+
+// Computed branch lists keyed by BIC8, then by the lookup that produced them (HQ, projection, page),
+// so a write to any code of an institution drops every cached variant at once
+const entries = new Map();
+
+let size = 0;
+
+const dropBic8 = (bic8) => {
+  const variants = entries.get(bic8);
+  if (variants) {
+    size -= variants.size;
+    entries.delete(bic8);
+  }
+};
+
+const lookup = (bic8, key) => {
+  const variants = entries.get(bic8);
+  const entry = variants && variants.get(key);
+  
+  if (!entry) {
+    return undefined;
+  }
+  
+  if (entry.expiresAt <= Date.now()) {
+    variants.delete(key);
+    size--;
+    return undefined;
+  }
+  
+  return entry.value;
+};
+
+exports.get = (bic8, key) => {
+  const start = stageTimer.start();
+  const value = lookup(bic8, key);
+  stageTimer.record('cache', start);
+  return value;
+};
+
+exports.set = (bic8, key, value) => {
+  if (!appConfig.branchCache) {
+    return;
+  }
+  
+  // Evict whole institutions, oldest first, once the cache is full
+  while (size >= appConfig.branchCacheMaxEntries && entries.size > 0) {
+    dropBic8(entries.keys().next().value);
+  }
+  
+  if (!entries.has(bic8)) {
+    entries.set(bic8, new Map());
+  }
+  
+  const variants = entries.get(bic8);
+  if (!variants.has(key)) {
+    size++;
+  }
+  variants.set(key, { value, expiresAt: Date.now() + appConfig.branchCacheTtlMs });
+};
+
+exports.invalidate = (bic8s) => {
+  [].concat(bic8s).filter(Boolean).forEach(dropBic8);
+};
+
+exports.clear = () => {
+  entries.clear();
+  size = 0;
+};
+
+// src/utils/logLevel.js
+const LOG_LEVELS = ['error', 'warn', 'info', 'debug'];
+
+// The codebase logs through console, so levels are applied to its methods
+const METHOD_LEVELS = { error: 'error', warn: 'warn', info: 'info', log: 'info', debug: 'debug' };
+
+const originals = Object.fromEntries(Object.keys(METHOD_LEVELS).map(method => [method, console[method]]));
+const silent = () => {};
+
+// Returns false, changing nothing, for an unknown level
+exports.applyLogLevel = (level) => {
+  const threshold = LOG_LEVELS.indexOf(level);
+  
+  if (threshold === -1) {
+    originals.warn(`Ignoring unknown log level ${level}, expected one of: ${LOG_LEVELS.join(', ')}`);
+    return false;
+  }
+  
+  Object.entries(METHOD_LEVELS).forEach(([method, methodLevel]) => {
+    console[method] = LOG_LEVELS.indexOf(methodLevel) <= threshold ? originals[method] : silent;
+  });
+  return true;
+};
+
+exports.LOG_LEVELS = LOG_LEVELS;
+
+// src/utils/stageTimer.js
+const { AsyncLocalStorage } = require('async_hooks');
+
+// Stages a request's handling time is broken into; concurrent queries of one request add up, so db
+// can exceed the wall-clock time
+const STAGES = ['validation', 'cache', 'db', 'serialization'];
+
+// Timings of the request being handled, reachable from services and model hooks without passing req around
+const storage = new AsyncLocalStorage();
+
+const QUERY_OPERATIONS = [
+  'find', 'findOne', 'countDocuments', 'estimatedDocumentCount', 'distinct', 'findOneAndUpdate',
+  'updateOne', 'updateMany', 'replaceOne', 'deleteOne', 'deleteMany', 'findOneAndDelete'
+];
+
+const QUERY_START = Symbol('stageTimerStart');
+
+exports.STAGES = STAGES;
+
+// Run a request's handling with fresh timings in milliseconds
+exports.run = (fn) => storage.run(Object.fromEntries(STAGES.map(stage => [stage, 0])), fn);
+
+exports.current = () => storage.getStore();
+
+exports.start = () => process.hrtime.bigint();
+
+// Charge the time since start to a stage of the current request, if any
+exports.record = (stage, start) => {
+  const timings = storage.getStore();
+  
+  if (timings) {
+    timings[stage] += Number(process.hrtime.bigint() - start) / 1e6;
+  }
+};
+
+// Schema plugin charging a model's queries and aggregations to the db stage
+exports.timeQueries = (schema) => {
+  function startQuery() {
+    this[QUERY_START] = exports.start();
+  }
+  
+  function endQuery() {
+    if (this[QUERY_START]) {
+      exports.record('db', this[QUERY_START]);
+    }
+  }
+  
+  schema.pre([...QUERY_OPERATIONS, 'aggregate'], startQuery);
+  schema.post([...QUERY_OPERATIONS, 'aggregate'], endQuery);
+};
+
+// src/utils/geo.js
+// Returns a message describing what is wrong with a coordinate pair, or null when it is usable
+exports.validateCoordinates = (latitude, longitude) => {
+  if (typeof latitude !== 'number' || !Number.isFinite(latitude) || latitude < -90 || latitude > 90) {
+    return 'Latitude must be a number between -90 and 90';
+  }
+  if (typeof longitude !== 'number' || !Number.isFinite(longitude) || longitude < -180 || longitude > 180) {
+    return 'Longitude must be a number between -180 and 180';
+  }
+  return null;
+};
+
+// GeoJSON lists longitude first
+exports.toPoint = (latitude, longitude) => ({ type: 'Point', coordinates: [longitude, latitude] });
+
+exports.fromPoint = (location) => ({ latitude: location.coordinates[1], longitude: location.coordinates[0] });
+
+// src/utils/lei.js
+// ISO 17442: 18 alphanumeric characters followed by two check digits
+const LEI_PATTERN = /^[A-Z0-9]{18}[0-9]{2}$/;
+
+// ISO 7064 MOD 97-10 remainder of an alphanumeric string, letters counting as 10-35
+exports.mod97 = (value) => {
+  let remainder = 0;
+  for (const character of value.toUpperCase()) {
+    for (const digit of String(parseInt(character, 36))) {
+      remainder = (remainder * 10 + Number(digit)) % 97;
+    }
+  }
+  return remainder;
+};
+
+// The whole number must leave a remainder of 1 modulo 97 (as for IBANs)
+exports.isValidLei = (lei) => {
+  if (typeof lei !== 'string' || !LEI_PATTERN.test(lei.toUpperCase())) {
+    return false;
+  }
+  
+  return exports.mod97(lei) === 1;
+};
+
+// src/utils/iban.js
+const { mod97 } = require('./lei');
+
+// Length and position of the national bank code (and branch code) per IBAN country; nationalIdScheme names
+// the scheme of SWIFT records' nationalIds whose value is the code, GB and NL use the BIC bank code itself
+const IBAN_FORMATS = {
+  AT: { length: 20, bankCode: [4, 9] },
+  BE: { length: 16, bankCode: [4, 7] },
+  CH: { length: 21, bankCode: [4, 9] },
+  DE: { length: 22, bankCode: [4, 12], nationalIdScheme: 'BLZ' },
+  ES: { length: 24, bankCode: [4, 8], branchCode: [8, 12] },
+  FR: { length: 27, bankCode: [4, 9], branchCode: [9, 14] },
+  GB: { length: 22, bankCode: [4, 8], branchCode: [8, 14], nationalIdScheme: 'SORT_CODE', nationalId: 'branchCode' },
+  IE: { length: 22, bankCode: [4, 8], branchCode: [8, 14] },
+  IT: { length: 27, bankCode: [5, 10], branchCode: [10, 15] },
+  NL: { length: 18, bankCode: [4, 8] },
+  PL: { length: 28, bankCode: [4, 12] }
+};
+
+// Printed IBANs are grouped in fours, e.g. "DE89 3704 0044 0532 0130 00"
+exports.toElectronicFormat = (iban) => String(iban).replace(/\s+/g, '').toUpperCase();
+
+// Returns a message describing what is wrong with the IBAN, or null when it is valid
+exports.validateIban = (iban) => {
+  if (typeof iban !== 'string' || !iban.trim()) {
+    return 'Missing required field: iban';
+  }
+  
+  const value = exports.toElectronicFormat(iban);
+  
+  if (!/^[A-Z]{2}\d{2}[A-Z0-9]{1,30}$/.test(value)) {
+    return 'IBAN must be a country code, two check digits and up to 30 letters or digits';
+  }
+  
+  const format = IBAN_FORMATS[value.substring(0, 2)];
+  if (!format) {
+    return `IBANs of ${value.substring(0, 2)} are not supported, expected one of: ${exports.IBAN_COUNTRIES.join(', ')}`;
+  }
+  if (value.length !== format.length) {
+    return `${value.substring(0, 2)} IBANs are ${format.length} characters, got ${value.length}`;
+  }
+  
+  // The check digits make the rearranged IBAN leave a remainder of 1
+  if (mod97(value.substring(4) + value.substring(0, 4)) !== 1) {
+    return 'IBAN check digits do not match';
+  }
+  
+  return null;
+};
+
+// Country, bank code and branch code of a valid IBAN
+exports.parseIban = (iban) => {
+  const value = exports.toElectronicFormat(iban);
+  const countryISO2 = value.substring(0, 2);
+  const format = IBAN_FORMATS[countryISO2];
+  const bankCode = value.substring(...format.bankCode);
+  const branchCode = format.branchCode ? value.substring(...format.branchCode) : null;
+  
+  return {
+    iban: value,
+    countryISO2,
+    bankCode,
+    branchCode,
+    nationalId: format.nationalIdScheme
+      ? { scheme: format.nationalIdScheme, value: format.nationalId === 'branchCode' ? branchCode : bankCode }
+      : null
+  };
+};
+
+exports.IBAN_COUNTRIES = Object.keys(IBAN_FORMATS);
+
+// src/utils/nationalIds.js
+// Domestic clearing schemes and the shape of their identifiers once separators are removed
+const NATIONAL_ID_SCHEMES = {
+  // US ABA routing transit number
+  ABA: /^\d{9}$/,
+  // German Bankleitzahl
+  BLZ: /^\d{8}$/,
+  // UK sort code, usually written 20-00-00
+  SORT_CODE: /^\d{6}$/
+};
+
+exports.NATIONAL_ID_SCHEMES = Object.keys(NATIONAL_ID_SCHEMES);
+
+exports.normalizeNationalId = ({ scheme, value }) => ({
+  scheme: scheme.trim().toUpperCase(),
+  value: value.replace(/[\s-]/g, '').toUpperCase()
+});
+
+// Returns a message describing what is wrong with the identifier, or null when it is usable
+exports.validateNationalId = (nationalId) => {
+  if (!nationalId || typeof nationalId.scheme !== 'string' || typeof nationalId.value !== 'string') {
+    return 'National ids must have a string scheme and value';
+  }
+  
+  const { scheme, value } = exports.normalizeNationalId(nationalId);
+  
+  if (!NATIONAL_ID_SCHEMES[scheme]) {
+    return `Unknown national id scheme ${scheme}, expected one of: ${exports.NATIONAL_ID_SCHEMES.join(', ')}`;
+  }
+  if (!NATIONAL_ID_SCHEMES[scheme].test(value)) {
+    return `Invalid ${scheme} identifier: ${nationalId.value}`;
+  }
+  return null;
+};
+
+// src/utils/bankNames.js
+// Case-folded forms of every name a bank is known by, so one index serves all scripts
+exports.toSearchName = (name) => name.normalize('NFKC').trim().replace(/\s+/g, ' ').toUpperCase();
+
+// Accepts localized names as a plain object or as the model's Map
+exports.toSearchNames = (bankName, localizedNames) => {
+  const variants = localizedNames instanceof Map ? [...localizedNames.values()] : Object.values(localizedNames || {});
+  return [...new Set([bankName, ...variants].filter(Boolean).map(exports.toSearchName))];
+};
+
+// src/utils/backfillBicFields.js
+const mongoose = require('mongoose');
+const SwiftCode = require('../models/swiftCode');
+const config = require('../config/database');
+
+// Derive bic8, the ISO 9362 components and connectivity for records stored before they existed
+async function backfillBicFields() {
+  const result = await SwiftCode.updateMany(
+    { bic8: { $exists: false } },
+    [{
+      $set: {
+        bic8: { $substrCP: ['$swiftCode', 0, 8] },
+        bankCode: { $substrCP: ['$swiftCode', 0, 4] },
+        countryCode: { $substrCP: ['$swiftCode', 4, 2] },
+        locationCode: { $substrCP: ['$swiftCode', 6, 2] },
+        branchCode: {
+          $cond: [{ $eq: [{ $strLenCP: '$swiftCode' }, 11] }, { $substrCP: ['$swiftCode', 8, 3] }, 'XXX']
+        }
+      }
+    }],
+    { withDeleted: true }
+  );
+  
+  // Connectivity arrived later than the components, so it has its own pass
+  const connectivity = await SwiftCode.updateMany(
+    { connectivityStatus: { $exists: false } },
+    [{
+      $set: {
+        connectivityStatus: {
+          $switch: {
+            branches: [
+              { case: { $eq: [{ $substrCP: ['$swiftCode', 7, 1] }, '0'] }, then: 'test' },
+              { case: { $eq: [{ $substrCP: ['$swiftCode', 7, 1] }, '1'] }, then: 'not-connected' },
+              { case: { $eq: [{ $substrCP: ['$swiftCode', 7, 1] }, '2'] }, then: 'reverse-billing' }
+            ],
+            default: 'connected'
+          }
+        }
+      }
+    }],
+    { withDeleted: true }
+  );
+  
+  return { updated: result.modifiedCount + connectivity.modifiedCount };
+}
+
+// Execute if this file is run directly
+if (require.main === module) {
+  mongoose.connect(config.mongoURI)
+    .then(backfillBicFields)
+    .then(({ updated }) => {
+      console.log(`Backfilled BIC fields on ${updated} records`);
+      return mongoose.disconnect();
+    })
+    .catch(error => {
+      console.error('Error backfilling BIC fields:', error);
+      process.exit(1);
+    });
+}
+
+module.exports = { backfillBicFields };
+
+// src/utils/replayMutations.js
+const fs = require('fs');
+const readline = require('readline');
+
+// Replay a mutation capture file against another instance, e.g. staging
+async function replayMutations(filePath, baseUrl, { includeFailed = false } = {}) {
+  const lines = readline.createInterface({ input: fs.createReadStream(filePath), crlfDelay: Infinity });
+  const summary = { replayed: 0, skipped: 0, mismatched: 0 };
+  
+  for await (const line of lines) {
+    if (!line.trim()) {
+      continue;
+    }
+    
+    const mutation = JSON.parse(line);
+    
+    // Requests that failed originally are skipped unless asked for
+    if (!includeFailed && mutation.statusCode >= 400) {
+      summary.skipped++;
+      continue;
+    }
+    
+    const response = await fetch(new URL(mutation.path, baseUrl), {
+      method: mutation.method,
+      headers: { 'Content-Type': 'application/json' },
+      body: mutation.body === undefined ? undefined : JSON.stringify(mutation.body)
+    });
+    
+    summary.replayed++;
+    
+    if (response.status !== mutation.statusCode) {
+      summary.mismatched++;
+      console.warn(`${mutation.method} ${mutation.path}: expected ${mutation.statusCode}, got ${response.status}`);
+    }
+  }
+  
+  return summary;
+}
+
+// Execute if this file is run directly
+if (require.main === module) {
+  const [filePath, baseUrl] = process.argv.slice(2);
+  
+  if (!filePath || !baseUrl) {
+    console.error('Usage: node src/utils/replayMutations.js <capture-file> <target-base-url> [--include-failed]');
+    process.exit(1);
+  }
+  
+  replayMutations(filePath, baseUrl, { includeFailed: process.argv.includes('--include-failed') })
+    .then(summary => console.log(`Replayed ${summary.replayed} mutations (${summary.mismatched} mismatched, ${summary.skipped} skipped)`))
+    .catch(error => {
+      console.error('Error replaying mutations:', error);
+      process.exit(1);
+    });
+}
+
+module.exports = { replayMutations };
+
+// src/utils/swiftCodeValidator.js
+const Joi = require('joi');
+const { isValidCountryCode, getCountryName } = require('./countries');
+const { validateCoordinates, toPoint } = require('./geo');
+const { isValidLei } = require('./lei');
+const { normalizeRecordAddress } = require('./addressNormalizer');
+const { validateNationalId, normalizeNationalId } = require('./nationalIds');
+
+// Map keys become field names in MongoDB, so keep them simple
+const EXTERNAL_ID_SYSTEM_PATTERN = /^[A-Za-z0-9_-]{1,50}$/;
+// BCP 47 language tags such as "ja", "zh-Hant" or "ar-SA"
+const LANGUAGE_CODE_PATTERN = /^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$/;
+
+// Shared by the validateRequest middleware and the per-item checks of bulk writes
+exports.VALIDATION_OPTIONS = {
+  abortEarly: false,
+  // Types are checked as sent, "true" is not a boolean
+  convert: false,
+  errors: { wrap: { label: false } },
+  messages: {
+    'any.required': 'Missing required field: {#label}',
+    'string.empty': 'Missing required field: {#label}',
+    'string.base': 'Field {#label} must be a string',
+    'boolean.base': 'Field {#label} must be a boolean',
+    'number.base': 'Field {#label} must be a number',
+    'array.base': 'Field {#label} must be an array',
+    'object.base': 'Record must be an object'
+  }
+};
+
+// Adapt one of the message-returning checks below to a Joi custom rule
+const rule = (check) => (value, helpers) => {
+  const error = check(value);
+  return error ? helpers.message(error) : value;
+};
+
+// Rules spanning several fields: coordinates come as a pair, temporary codes carry a future expiry
+const checkRecord = (data) => {
+  if (data.latitude !== undefined || data.longitude !== undefined) {
+    const coordinatesError = validateCoordinates(data.latitude, data.longitude);
+    if (coordinatesError) {
+      return coordinatesError;
+    }
+  }
+  
+  if (data.isTemporary) {
+    const expiresAt = new Date(data.expiresAt);
+    
+    if (!data.expiresAt || Number.isNaN(expiresAt.getTime())) {
+      return 'Temporary SWIFT codes require a valid expiresAt';
+    }
+    if (expiresAt <= new Date()) {
+      return 'Field expiresAt must be in the future';
+    }
+  }
+  
+  return null;
+};
+
+// countryName and isHeadquarter are derived from countryISO2 and the code, submitted ones are checked but not stored
+exports.swiftCodeRecordSchema = Joi.object({
+  swiftCode: Joi.string().required().custom(rule((swiftCode) => {
+    // Writes leave the country's existence to the data-quality strictness profiles
+    const formatErrors = exports.validateSwiftCodeFormat(swiftCode, { checkCountry: false });
+    return formatErrors.length > 0 ? `Invalid SWIFT code: ${formatErrors[0]}` : null;
+  })),
+  bankName: Joi.string().required(),
+  address: Joi.string().required(),
+  countryISO2: Joi.string().required(),
+  countryName: Joi.string(),
+  isHeadquarter: Joi.boolean(),
+  city: Joi.string(),
+  lei: Joi.string().custom(rule(lei => (isValidLei(lei) ? null : 'Field lei must be a valid 20-character LEI'))),
+  nationalIds: Joi.array().items(Joi.any().custom(rule(validateNationalId))),
+  latitude: Joi.number(),
+  longitude: Joi.number(),
+  isTemporary: Joi.boolean(),
+  localizedNames: Joi.any().custom(rule(localizedNames => exports.validateLocalizedNames(localizedNames))),
+  externalIds: Joi.any().custom(rule(externalIds => exports.validateExternalIds(externalIds)))
+})
+  .unknown(true)
+  .custom(rule(checkRecord));
+
+// Returns a message describing the first problem with the record, or null when it is valid
+exports.validateSwiftCodeData = (data) => {
+  if (!data || typeof data !== 'object' || Array.isArray(data)) {
+    return 'Record must be an object';
+  }
+  
+  const { error } = exports.swiftCodeRecordSchema.validate(data, { ...exports.VALIDATION_OPTIONS, abortEarly: true });
+  return error ? error.details[0].message : null;
+};
+
+// Returns a copy of the record with countryName taken from ISO 3166-1, the address normalized and
+// coordinates as a GeoJSON point; trimming and case are already handled by the normalizeInput middleware
+exports.normalizeSwiftCodeData = ({ latitude, longitude, ...data }) => ({
+  ...data,
+  ...normalizeRecordAddress(data.address, data.countryISO2),
+  countryName: getCountryName(data.countryISO2),
+  ...(Array.isArray(data.nationalIds) && { nationalIds: data.nationalIds.map(normalizeNationalId) }),
+  ...(latitude !== undefined && { location: toPoint(latitude, longitude) })
+});
+
+// Check a code against the ISO 9362 structure, returning every violation found; writes leave the
+// country's existence to the data-quality strictness profiles with checkCountry: false
+exports.validateSwiftCodeFormat = (swiftCode, { checkCountry = true } = {}) => {
+  if (typeof swiftCode !== 'string') {
+    return ['SWIFT code must be a string'];
+  }
+  
+  const code = swiftCode.trim().toUpperCase();
+  const errors = [];
+  
+  if (code.length !== 8 && code.length !== 11) {
+    errors.push(`SWIFT code must be 8 or 11 characters, got ${code.length}`);
+  }
+  
+  if (code.length >= 4 && !/^[A-Z]{4}$/.test(code.substring(0, 4))) {
+    errors.push('Bank code (characters 1-4) must be letters');
+  }
+  
+  if (code.length >= 6) {
+    const countryCode = code.substring(4, 6);
+    
+    if (!/^[A-Z]{2}$/.test(countryCode)) {
+      errors.push('Country code (characters 5-6) must be letters');
+    } else if (checkCountry && !isValidCountryCode(countryCode)) {
+      errors.push(`Country code ${countryCode} is not a valid ISO 3166-1 country`);
+    }
+  }
+  
+  if (code.length >= 8 && !/^[A-Z0-9]{2}$/.test(code.substring(6, 8))) {
+    errors.push('Location code (characters 7-8) must be letters or digits');
+  }
+  
+  if (code.length === 11 && !/^[A-Z0-9]{3}$/.test(code.substring(8, 11))) {
+    errors.push('Branch code (characters 9-11) must be letters or digits');
+  }
+  
+  return errors;
+};
+
+exports.validateLocalizedNames = (localizedNames) => {
+  if (!localizedNames || typeof localizedNames !== 'object' || Array.isArray(localizedNames)) {
+    return 'Field localizedNames must be an object keyed by language code';
+  }
+  
+  for (const [language, name] of Object.entries(localizedNames)) {
+    if (!LANGUAGE_CODE_PATTERN.test(language)) {
+      return `Invalid language code: ${language}`;
+    }
+    if (typeof name !== 'string' || !name.trim()) {
+      return `Localized name for ${language} must be a non-empty string`;
+    }
+  }
+  
+  return null;
+};
+
+exports.isValidExternalIdSystem = (system) => EXTERNAL_ID_SYSTEM_PATTERN.test(system);
+
+exports.validateExternalIds = (externalIds) => {
+  if (!externalIds || typeof externalIds !== 'object' || Array.isArray(externalIds)) {
+    return 'Field externalIds must be an object';
+  }
+  
+  for (const [system, value] of Object.entries(externalIds)) {
+    if (!exports.isValidExternalIdSystem(system)) {
+      return `Invalid external id system: ${system}`;
+    }
+    if (typeof value !== 'string' || !value.trim()) {
+      return `External id for ${system} must be a non-empty string`;
+    }
+  }
+  
+  return null;
+};
+
+// src/utils/dataQuality.js
+const { isValidCountryCode } = require('./countries');
+const { isHeadquarterCode } = require('./bic');
+
+// countryName is derived from the country code, so an unknown one is rejected by every profile
+const ALWAYS_REJECTED = ['UNKNOWN_COUNTRY'];
+
+// Issue codes each strictness profile rejects on top of ALWAYS_REJECTED; anything else is reported as a warning
+const STRICTNESS_PROFILES = {
+  lenient: [],
+  standard: [],
+  strict: ['COUNTRY_MISMATCH', 'HEADQUARTER_MISMATCH']
+};
+
+// Data-quality issues that don't make a record structurally invalid
+exports.checkDataQuality = (data) => {
+  const issues = [];
+  
+  if (!isValidCountryCode(data.countryISO2)) {
+    issues.push({
+      code: 'UNKNOWN_COUNTRY',
+      field: 'countryISO2',
+      message: `Country code ${data.countryISO2} is not a valid ISO 3166-1 country`
+    });
+  }
+  
+  const codeCountry = data.swiftCode.substring(4, 6);
+  if (codeCountry && codeCountry !== data.countryISO2) {
+    issues.push({
+      code: 'COUNTRY_MISMATCH',
+      field: 'countryISO2',
+      message: `SWIFT code country ${codeCountry} does not match countryISO2 ${data.countryISO2}`
+    });
+  }
+  
+  // The stored flag always follows the code, so a contradicting one is corrected unless the profile rejects it
+  if (typeof data.isHeadquarter === 'boolean' && data.isHeadquarter !== isHeadquarterCode(data.swiftCode)) {
+    issues.push({
+      code: 'HEADQUARTER_MISMATCH',
+      field: 'isHeadquarter',
+      message: `isHeadquarter ${data.isHeadquarter} contradicts SWIFT code ${data.swiftCode}, only XXX codes are headquarters`
+    });
+  }
+  
+  return issues;
+};
+
+// Split the issues of a normalized record into rejections and warnings for the given profile
+exports.evaluateWrite = (data, strictness) => {
+  const rejected = [...ALWAYS_REJECTED, ...(STRICTNESS_PROFILES[strictness] || STRICTNESS_PROFILES.standard)];
+  const issues = exports.checkDataQuality(data);
+  
+  return {
+    errors: issues.filter(issue => rejected.includes(issue.code)),
+    warnings: issues.filter(issue => !rejected.includes(issue.code))
+  };
+};
+
+exports.STRICTNESS_LEVELS = Object.keys(STRICTNESS_PROFILES);
+
+// src/utils/branchDescriptionParser.js
+const fs = require('fs');
+const path = require('path');
+const csv = require('csv-parser');
+const mongoose = require('mongoose');
+const SwiftCode = require('../models/swiftCode');
+const { toBic8 } = require('./bic');
+const config = require('../config/database');
+
+// Supplementary file with SWIFT,BRANCH_DESCRIPTION columns - pass a path to override
+const DESCRIPTIONS_FILE_PATH = process.argv[2] || path.resolve(__dirname, '../../../data/branch_descriptions.csv');
+
+// Read branch descriptions from a supplementary CSV file
+function readBranchDescriptions(filePath) {
+  return new Promise((resolve, reject) => {
+    const descriptions = [];
+    
+    fs.createReadStream(filePath)
+      .pipe(csv())
+      .on('data', (row) => {
+        const swiftCode = (row.SWIFT || row.swift_code || '').trim().toUpperCase();
+        const branchDescription = (row.BRANCH_DESCRIPTION || row.branch_description || '').trim();
+        
+        if (swiftCode && branchDescription) {
+          descriptions.push({ swiftCode, branchDescription });
+        }
+      })
+      .on('end', () => resolve(descriptions))
+      .on('error', reject);
+  });
+}
+
+// Attach branch descriptions to existing SWIFT code records
+async function importBranchDescriptions(filePath = DESCRIPTIONS_FILE_PATH) {
+  try {
+    await mongoose.connect(config.mongoURI);
+    console.log('Connected to MongoDB');
+    
+    const descriptions = await readBranchDescriptions(filePath);
+    
+    if (descriptions.length === 0) {
+      console.log('No branch descriptions found to import');
+    } else {
+      const result = await SwiftCode.bulkWrite(descriptions.map(({ swiftCode, branchDescription }) => ({
+        updateOne: {
+          filter: { swiftCode },
+          update: { $set: { branchDescription } }
+        }
+      })));
+      await SwiftCode.invalidateInstitutions(descriptions.map(({ swiftCode }) => toBic8(swiftCode)));
+      
+      console.log(`Matched ${result.matchedCount} of ${descriptions.length} branch descriptions`);
+    }
+    
+    await mongoose.disconnect();
+  } catch (error) {
+    console.error('Error importing branch descriptions:', error);
+    process.exit(1);
+  }
+}
+
+// Execute if this file is run directly
+if (require.main === module) {
+  importBranchDescriptions();
+}
+
+module.exports = { importBranchDescriptions, readBranchDescriptions };
+
+// src/errors/errorCodes.js
+// Stable, machine-readable error codes; clients branch on these, so never rename a published one
+const ERROR_CODES = {
+  INVALID_RECORD: { status: 400, description: 'The record is missing fields or has fields of the wrong type' },
+  MISSING_FIELD: { status: 400, description: 'A required field of the record is missing or empty' },
+  INVALID_FORMAT: { status: 400, description: 'The SWIFT code does not follow the ISO 9362 structure' },
+  UNKNOWN_COUNTRY: { status: 400, description: 'The country code is not a valid ISO 3166-1 country' },
+  COUNTRY_MISMATCH: { status: 400, description: 'The SWIFT code country differs from countryISO2' },
+  HEADQUARTER_MISMATCH: { status: 400, description: 'isHeadquarter contradicts the branch code of the SWIFT code' },
+  DUPLICATE_SWIFT_CODE: { status: 409, description: 'A record with this SWIFT code already exists' },
+  // Import rejects only: the row repeats a SWIFT code seen earlier in the same file
+  DUPLICATE_IN_FILE: { status: 409, description: 'The SWIFT code appears more than once in the imported file' },
+  NOT_FOUND: { status: 404, description: 'No record exists for this SWIFT code' }
+};
+
+// Per-item error of a multi-status response
+const itemError = (code, message) => ({ code, message: message || ERROR_CODES[code].description });
+
+// Catalogue served to clients, ordered by code
+const listErrorCodes = () => Object.keys(ERROR_CODES)
+  .sort()
+  .map(code => ({ code, ...ERROR_CODES[code] }));
+
+// 207 as soon as one item of a bulk request failed, so clients know to inspect the results
+const multiStatusCode = (results) => (results.some(result => result.error) ? 207 : 200);
+
+module.exports = {
+  ERROR_CODES,
+  itemError,
+  listErrorCodes,
+  multiStatusCode
+};
+
+// src/validation/requestSchemas.js
+const Joi = require('joi');
+const swiftCodeService = require('../services/swiftCodeService');
+const importRejectService = require('../services/importRejectService');
+const mergerEventService = require('../services/mergerEventService');
+const { SIEM_EVENT_TYPES } = require('../services/siemService');
+const { swiftCodeRecordSchema } = require('../utils/swiftCodeValidator');
+const { isValidLei } = require('../utils/lei');
+const { validateIban } = require('../utils/iban');
+const { IMPORT_MODES } = require('../utils/dataParser');
+
+// Enumerated query parameter, answering with the accepted values like the rest of the API
+const oneOf = (values, message) => Joi.string()
+  .valid(...values)
+  .messages({ 'any.only': message, 'string.base': message });
+
+const status = oneOf(
+  swiftCodeService.RECORD_STATUSES,
+  `Invalid status, expected one of: ${swiftCodeService.RECORD_STATUSES.join(', ')}`
+);
+
+const connectivity = oneOf(
+  swiftCodeService.CONNECTIVITY_STATUSES,
+  `Invalid connectivity, expected one of: ${swiftCodeService.CONNECTIVITY_STATUSES.join(', ')}`
+);
+
+// Revision number; 0 stands for the record before its first recorded change
+const version = (name) => Joi.string()
+  .pattern(/^\d+$/)
+  .required()
+  .messages({
+    'any.required': `${name} must be a version number`,
+    'string.empty': `${name} must be a version number`,
+    'string.pattern.base': `${name} must be a version number`,
+    'string.base': `${name} must be a version number`
+  });
+
+const bic = (name) => Joi.string()
+  .pattern(/^[A-Za-z]{6}[A-Za-z0-9]{2}([A-Za-z0-9]{3})?$/)
+  .messages({ 'string.pattern.base': `${name} must be a BIC8 or BIC11`, 'string.base': `${name} must be a BIC8 or BIC11` });
+
+const isoDate = (name) => Joi.date()
+  .iso()
+  .messages({ 'date.format': `${name} must be an ISO 8601 date`, 'date.base': `${name} must be an ISO 8601 date` });
+
+const countryISO2 = Joi.string()
+  .length(2)
+  .messages({ 'string.length': 'countryISO2 must be exactly 2 characters', 'string.base': 'countryISO2 must be exactly 2 characters' });
+
+// Shared by the v1 and v2 country listings; unknown parameters such as fields or page pass through
+const countryListingQuery = Joi.object({
+  sort: oneOf(
+    swiftCodeService.COUNTRY_SORT_OPTIONS,
+    `Invalid sort option, expected one of: ${swiftCodeService.COUNTRY_SORT_OPTIONS.join(', ')}`
+  ),
+  type: oneOf(
+    swiftCodeService.COUNTRY_TYPE_OPTIONS,
+    `Invalid type, expected one of: ${swiftCodeService.COUNTRY_TYPE_OPTIONS.join(', ')}`
+  ),
+  status,
+  connectivity
+}).unknown(true);
+
+module.exports = {
+  createSwiftCode: {
+    body: swiftCodeRecordSchema
+  },
+  countryListing: {
+    query: countryListingQuery.keys({
+      groupBy: oneOf(['city'], 'Invalid groupBy, expected: city')
+    })
+  },
+  countryListingV2: {
+    query: countryListingQuery
+  },
+  count: {
+    query: Joi.object({
+      countryISO2: Joi.string(),
+      isHeadquarter: oneOf(['true', 'false'], 'isHeadquarter must be true or false'),
+      bankName: Joi.string(),
+      status,
+      connectivity
+    }).unknown(true)
+  },
+  search: {
+    query: Joi.object({
+      q: Joi.string().min(2).required().messages({
+        'any.required': 'q must be at least 2 characters',
+        'string.empty': 'q must be at least 2 characters',
+        'string.min': 'q must be at least 2 characters',
+        'string.base': 'q must be at least 2 characters'
+      }),
+      countryISO2,
+      status
+    }).unknown(true)
+  },
+  assignments: {
+    query: Joi.object({
+      at: isoDate('at')
+    }).unknown(true)
+  },
+  // The country is part of the code, so a new holder can't change it
+  reassign: {
+    body: Joi.object({
+      effectiveDate: isoDate('effectiveDate').max('now').required().messages({ 'date.max': 'effectiveDate must not be in the future' }),
+      bankName: Joi.string().trim().min(1).required(),
+      address: Joi.string().trim().min(1).required(),
+      city: Joi.string(),
+      lei: Joi.string().custom((value, helpers) => (isValidLei(value) ? value : helpers.message('lei must be a valid ISO 17442 LEI')))
+    })
+  },
+  historyDiff: {
+    query: Joi.object({
+      from: version('from'),
+      to: version('to')
+    }).unknown(true)
+  },
+  smokeTest: {
+    body: Joi.object({
+      samples: Joi.number().integer().min(1).max(500).messages({
+        'number.base': 'samples must be an integer between 1 and 500',
+        'number.integer': 'samples must be an integer between 1 and 500',
+        'number.min': 'samples must be an integer between 1 and 500',
+        'number.max': 'samples must be an integer between 1 and 500'
+      })
+    })
+  },
+  // Multipart form fields arrive as strings
+  runImport: {
+    body: Joi.object({
+      mode: oneOf(IMPORT_MODES, `Invalid mode, expected one of: ${IMPORT_MODES.join(', ')}`),
+      deactivateMissing: oneOf(['true', 'false'], 'deactivateMissing must be true or false'),
+      dryRun: oneOf(['true', 'false'], 'dryRun must be true or false'),
+      mapping: Joi.string(),
+      sheet: Joi.string(),
+      delimiter: Joi.string().length(1).messages({ 'string.length': 'delimiter must be a single character' })
+    })
+  },
+  importRejects: {
+    query: Joi.object({
+      reason: oneOf(
+        importRejectService.REJECT_REASONS,
+        `Invalid reason, expected one of: ${importRejectService.REJECT_REASONS.join(', ')}`
+      ),
+      field: Joi.string(),
+      status: oneOf(
+        importRejectService.REJECT_STATUSES,
+        `Invalid status, expected one of: ${importRejectService.REJECT_STATUSES.join(', ')}`
+      )
+    }).unknown(true)
+  },
+  siemEvents: {
+    query: Joi.object({
+      from: isoDate('from'),
+      to: isoDate('to'),
+      types: Joi.string().custom((value, helpers) => {
+        const unknown = value.split(',').map(type => type.trim()).filter(type => !SIEM_EVENT_TYPES.includes(type));
+        return unknown.length > 0
+          ? helpers.message(`Unknown event types ${unknown.join(', ')}, expected: ${SIEM_EVENT_TYPES.join(', ')}`)
+          : value;
+      })
+    }).unknown(true)
+  },
+  mergerEvents: {
+    query: Joi.object({
+      from: isoDate('from'),
+      to: isoDate('to'),
+      bic: bic('bic')
+    }).unknown(true)
+  },
+  // Codes are retired as soon as the event is recorded, so it can't be scheduled ahead
+  recordMergerEvent: {
+    body: Joi.object({
+      type: oneOf(
+        mergerEventService.MERGER_EVENT_TYPES,
+        `Invalid type, expected one of: ${mergerEventService.MERGER_EVENT_TYPES.join(', ')}`
+      ),
+      effectiveDate: isoDate('effectiveDate').max('now').required().messages({ 'date.max': 'effectiveDate must not be in the future' }),
+      absorbingBic: bic('absorbingBic').required(),
+      absorbedBics: Joi.array().items(bic('absorbedBics')).min(1).required().messages({
+        'any.required': 'absorbedBics must list at least one BIC',
+        'array.min': 'absorbedBics must list at least one BIC',
+        'array.base': 'absorbedBics must list at least one BIC'
+      }),
+      notes: Joi.string().allow('')
+    })
+  },
+  resolveIban: {
+    body: Joi.object({
+      iban: Joi.string().required().custom((value, helpers) => {
+        const error = validateIban(value);
+        return error ? helpers.message(error) : value;
+      })
+    })
+  },
+  countryCode: {
+    params: Joi.object({
+      countryISO2: Joi.string().pattern(/^[A-Za-z]{2}$/).messages({ 'string.pattern.base': 'countryISO2 must be 2 letters' })
+    })
+  },
+  saveCountry: {
+    params: Joi.object({
+      countryISO2: Joi.string().pattern(/^[A-Za-z]{2}$/).messages({ 'string.pattern.base': 'countryISO2 must be 2 letters' })
+    }),
+    body: Joi.object({
+      name: Joi.string().trim().min(1),
+      withdrawn: Joi.boolean()
+    }).or('name', 'withdrawn').messages({ 'object.missing': 'Provide name or withdrawn' })
+  },
+  // Corrections replace the fields of the rejected row; anything else comes from the file
+  resubmitImportReject: {
+    body: Joi.object({
+      swiftCode: Joi.string(),
+      bankName: Joi.string(),
+      address: Joi.string(),
+      city: Joi.string(),
+      countryISO2,
+      latitude: Joi.number(),
+      longitude: Joi.number()
+    }).and('latitude', 'longitude')
+  }
+};
+
+// src/jobs/temporaryCodeExpiry.js
+const SwiftCode = require('../models/swiftCode');
+const historyService = require('../services/historyService');
+const appConfig = require('../config/app');
+
+let timer = null;
+
+// Deprecate and retire temporary codes whose expiry has passed
+exports.run = async () => {
+  const now = new Date();
+  const expired = await SwiftCode.find({ isTemporary: true, deprecatedAt: null, expiresAt: { $lte: now } });
+  
+  if (expired.length === 0) {
+    return 0;
+  }
+  
+  const changes = { deprecatedAt: now, status: 'retired', statusReason: 'Temporary code expired', statusChangedAt: now };
+  const result = await SwiftCode.updateMany({ _id: { $in: expired.map(code => code._id) } }, { $set: changes });
+  await historyService.recordBulkRevisions(expired, changes, 'expire', 'system:temporary-code-expiry');
+  
+  if (result.modifiedCount > 0) {
+    console.log(`Deprecated ${result.modifiedCount} expired temporary SWIFT codes`);
+  }
+  
+  return result.modifiedCount;
+};
+
+exports.start = () => {
+  if (timer) {
+    return;
+  }
+  
+  const run = () => exports.run().catch(error => console.error('Failed to deprecate expired temporary codes', error));
+  
+  run();
+  timer = setInterval(run, appConfig.temporaryExpiryIntervalMs);
+  timer.unref();
+};
+
+exports.stop = () => {
+  clearInterval(timer);
+  timer = null;
+};
+
+// src/jobs/snapshotPublisher.js
+const exportService = require('../services/exportService');
+const objectStorage = require('../services/objectStorage');
+const appConfig = require('../config/app');
+
+let timer = null;
+
+const SNAPSHOT_FILE = 'swift-codes.ndjson';
+
+// Publish the dataset as <date>/swift-codes.ndjson with a manifest and SHA256SUMS, for consumers without API access
+exports.run = async (storage = objectStorage.fromTarget(appConfig.snapshotTarget), now = new Date()) => {
+  const version = now.toISOString().substring(0, 10);
+  const digest = exportService.createDigest(SNAPSHOT_FILE);
+  const cursor = exportService.createExportCursor();
+  
+  // Streamed straight into the upload; the checksum is known once the last part has been sent
+  try {
+    const body = exportService.digestedStream(exportService.ndjsonLines(cursor), digest);
+    await storage.put(`${version}/${SNAPSHOT_FILE}`, body, 'application/x-ndjson');
+  } finally {
+    await cursor.close().catch(() => {});
+  }
+  
+  const data = digest.file();
+  const manifestContent = await exportService.buildManifest([data], data.records);
+  const manifest = { name: 'manifest.json', content: Buffer.from(JSON.stringify(manifestContent, null, 2)) };
+  manifest.sha256 = exportService.sha256(manifest.content);
+  // sha256sum -c compatible
+  const checksums = [data, manifest].map(file => `${file.sha256}  ${file.name}\n`).join('');
+  
+  await storage.put(`${version}/${manifest.name}`, manifest.content, 'application/json');
+  // Written last so a directory with SHA256SUMS is complete
+  await storage.put(`${version}/SHA256SUMS`, Buffer.from(checksums), 'text/plain');
+  
+  console.log(`Published snapshot ${version} with ${data.records} SWIFT codes`);
+  return { version, recordCount: data.records };
+};
+
+exports.start = () => {
+  if (timer || !appConfig.snapshotTarget) {
+    return;
+  }
+  
+  const storage = objectStorage.fromTarget(appConfig.snapshotTarget);
+  const run = () => exports.run(storage).catch(error => console.error('Failed to publish the dataset snapshot', error));
+  
+  run();
+  timer = setInterval(run, appConfig.snapshotIntervalMs);
+  timer.unref();
+};
+
+exports.stop = () => {
+  clearInterval(timer);
+  timer = null;
+};
+
+// src/jobs/normalizeRecords.js
+const SwiftCode = require('../models/swiftCode');
+const historyService = require('../services/historyService');
+const bankService = require('../services/bankService');
+const { normalizeValue } = require('../utils/inputNormalizer');
+const { normalizeRecordAddress } = require('../utils/addressNormalizer');
+const { getCountryName } = require('../utils/countries');
+const { toBic8 } = require('../utils/bic');
+
+// Fields rewritten to the form API writes and imports store today
+const CANONICAL_FIELDS = ['swiftCode', 'bankName', 'address', 'city', 'countryISO2', 'countryName'];
+
+// 'idle' until the first run, then 'running', 'completed' or 'failed'
+let state = { status: 'idle' };
+
+// Fields of a stored record that differ from their canonical form, with the canonical values
+const canonicalChanges = (record) => {
+  const changes = {};
+  
+  CANONICAL_FIELDS
+    .filter(field => typeof record[field] === 'string')
+    .forEach((field) => {
+      const value = normalizeValue(field, record[field]);
+      if (value !== record[field]) {
+        changes[field] = value;
+      }
+    });
+  
+  // Addresses go through the configured normalization steps as on write, keeping the address that
+  // was stored before unless an earlier write already recorded one
+  if (typeof record.address === 'string') {
+    const { address, originalAddress } = normalizeRecordAddress(changes.address || record.address, changes.countryISO2 || record.countryISO2);
+    if (originalAddress) {
+      changes.address = address;
+      changes.originalAddress = record.originalAddress || originalAddress;
+    }
+  }
+  
+  // The ISO 3166-1 name wins over whatever spelling older writes stored
+  const countryName = getCountryName(changes.countryISO2 || record.countryISO2);
+  if (countryName && countryName !== record.countryName) {
+    changes.countryName = countryName;
+  }
+  
+  if (changes.swiftCode) {
+    changes.bic8 = toBic8(changes.swiftCode);
+  }
+  
+  return changes;
+};
+
+// Rewrite every record, soft-deleted ones included, that isn't in canonical form; codes whose
+// canonical form is already taken by another record are reported instead of merged
+exports.run = async (actor = 'system:normalize') => {
+  const cursor = SwiftCode.find({}).setOptions({ withDeleted: true }).lean().cursor();
+  const bic8s = new Set();
+  const conflicts = [];
+  let scanned = 0;
+  let corrected = 0;
+  
+  for await (const record of cursor) {
+    scanned++;
+    const changes = canonicalChanges(record);
+    
+    if (Object.keys(changes).length === 0) {
+      continue;
+    }
+    
+    try {
+      // The code in the filter lets the update drop only its institution's cache entries
+      await SwiftCode.updateOne({ _id: record._id, swiftCode: record.swiftCode }, { $set: changes }, { withDeleted: true });
+    } catch (error) {
+      if (error.code !== 11000) { // MongoDB duplicate key error
+        throw error;
+      }
+      conflicts.push(record.swiftCode);
+      continue;
+    }
+    
+    await historyService.recordRevision({ action: 'normalize', actor, previous: record, current: { ...record, ...changes } });
+    bic8s.add(record.bic8).add(changes.bic8);
+    corrected++;
+  }
+  
+  await bankService.syncBanks([...bic8s]);
+  
+  if (corrected > 0) {
+    console.log(`Normalized ${corrected} of ${scanned} SWIFT code records`);
+  }
+  
+  return { scanned, corrected, conflicts };
+};
+
+// Start a run in the background and return its state, or null while one is still going on this
+// instance; getState reports on the latest run
+exports.start = (actor) => {
+  if (state.status === 'running') {
+    return null;
+  }
+  
+  const startedAt = new Date();
+  state = { status: 'running', actor, startedAt };
+  
+  exports.run(actor)
+    .then((result) => {
+      state = { status: 'completed', actor, startedAt, completedAt: new Date(), ...result };
+    })
+    .catch((error) => {
+      console.error('Normalization failed', error);
+      state = { status: 'failed', actor, startedAt, completedAt: new Date(), error: error.message };
+    });
+  
+  return exports.getState();
+};
+
+exports.getState = () => ({ ...state });
+
+// src/jobs/smokeTest.js
+const mongoose = require('mongoose');
+const SwiftCode = require('../models/swiftCode');
+const swiftCodeService = require('../services/swiftCodeService');
+const config = require('../config/database');
+const appConfig = require('../config/app');
+const { toBic8, isHeadquarterCode } = require('../utils/bic');
+const { getCountryName } = require('../utils/countries');
+
+const elapsedMs = (start) => Number(process.hrtime.bigint() - start) / 1e6;
+
+const percentile = (sorted, fraction) => sorted[Math.min(sorted.length - 1, Math.ceil(sorted.length * fraction) - 1)];
+
+const summarize = (path, latencies) => {
+  const sorted = [...latencies].sort((a, b) => a - b);
+  const round = (value) => Math.round(value * 100) / 100;
+  
+  return {
+    path,
+    samples: sorted.length,
+    p50Ms: sorted.length > 0 ? round(percentile(sorted, 0.5)) : null,
+    p95Ms: sorted.length > 0 ? round(percentile(sorted, 0.95)) : null,
+    maxMs: sorted.length > 0 ? round(sorted[sorted.length - 1]) : null
+  };
+};
+
+// Each read path takes a sampled record and returns the invariant violations it saw
+const READ_PATHS = {
+  details: async ({ swiftCode }) => {
+    const details = await swiftCodeService.getSwiftCodeDetails(swiftCode, { branchPaging: { page: 1, limit: 100 } });
+    
+    if (!details) {
+      return ['Sampled code not found'];
+    }
+    
+    const problems = [];
+    if (details.isHeadquarter !== isHeadquarterCode(swiftCode)) {
+      problems.push('isHeadquarter contradicts the branch code');
+    }
+    (details.branches || [])
+      .filter(branch => toBic8(branch.swiftCode) !== toBic8(swiftCode) || branch.swiftCode === swiftCode)
+      .forEach(branch => problems.push(`Branch ${branch.swiftCode} does not belong to the headquarter`));
+    return problems;
+  },
+  bic8: async ({ swiftCode }) => {
+    const bank = await swiftCodeService.getSwiftCodesByBic8(toBic8(swiftCode));
+    
+    return bank && bank.swiftCodes.some(code => code.swiftCode === swiftCode)
+      ? []
+      : ['Sampled code missing from its BIC8 listing'];
+  },
+  country: async ({ countryISO2 }) => {
+    const listing = await swiftCodeService.getSwiftCodesByCountry(countryISO2, { paging: { page: 1, limit: 100 } });
+    
+    if (!listing) {
+      return ['Sampled country has no listing'];
+    }
+    
+    const problems = listing.swiftCodes
+      .filter(code => code.countryISO2 !== countryISO2)
+      .map(code => `${code.swiftCode} is listed under ${countryISO2} but belongs to ${code.countryISO2}`);
+    if (getCountryName(countryISO2) && listing.countryName !== getCountryName(countryISO2)) {
+      problems.push(`Country name ${listing.countryName} differs from ${getCountryName(countryISO2)}`);
+    }
+    return problems;
+  },
+  search: async ({ bankName }) => {
+    const result = await swiftCodeService.searchByBankName(bankName, { page: 1, limit: 20 });
+    return result.pagination.totalCount > 0 ? [] : ['Searching the sampled bank name found nothing'];
+  }
+};
+
+// Exercise the main read paths with codes sampled from the live dataset, e.g. after a deployment;
+// passed is false as soon as one invariant is violated
+exports.run = async ({ samples = appConfig.smokeTestSamples } = {}) => {
+  const startedAt = new Date();
+  const start = process.hrtime.bigint();
+  const sampled = await SwiftCode.aggregate([
+    { $match: { status: swiftCodeService.statusFilter() } },
+    { $sample: { size: samples } },
+    { $project: { _id: 0, swiftCode: 1, countryISO2: 1, bankName: 1 } }
+  ]);
+  
+  // One country sample per distinct country, so large countries aren't listed over and over
+  const targets = {
+    details: sampled,
+    bic8: sampled,
+    country: [...new Map(sampled.map(record => [record.countryISO2, record])).values()],
+    search: sampled
+  };
+  
+  const mismatches = [];
+  const paths = [];
+  
+  for (const [path, check] of Object.entries(READ_PATHS)) {
+    const latencies = [];
+    
+    for (const record of targets[path]) {
+      const callStart = process.hrtime.bigint();
+      const problems = await check(record);
+      latencies.push(elapsedMs(callStart));
+      problems.forEach(message => mismatches.push({ path, swiftCode: record.swiftCode, message }));
+    }
+    
+    paths.push(summarize(path, latencies));
+  }
+  
+  return {
+    startedAt,
+    durationMs: Math.round(elapsedMs(start)),
+    sampledCodes: sampled.length,
+    passed: sampled.length > 0 && mismatches.length === 0,
+    paths,
+    mismatches
+  };
+};
+
+// Execute if this file is run directly, exiting non-zero so deployment pipelines can gate on it
+if (require.main === module) {
+  mongoose.connect(config.mongoURI)
+    .then(() => exports.run())
+    .then((report) => {
+      console.log(JSON.stringify(report, null, 2));
+      process.exitCode = report.passed ? 0 : 1;
+      return mongoose.disconnect();
+    })
+    .catch(error => {
+      console.error('Error running the smoke test:', error);
+      process.exit(1);
+    });
+}
+
+// src/jobs/scheduledImport.js
+const crypto = require('crypto');
+const { pipeline } = require('stream/promises');
+const cron = require('node-cron');
+const ImportRun = require('../models/importRun');
+const importRunService = require('../services/importRunService');
+const { importSwiftCodes, ImportRunningError } = require('../utils/dataParser');
+const { openStream } = require('../utils/importSource');
+const appConfig = require('../config/app');
+
+let task = null;
+
+// 'idle' until the first run, then 'running', 'imported', 'skipped' or 'failed'
+let state = { status: 'idle' };
+
+// Remote sources are downloaded once for the hash and again for the import, which only happens when it changed
+const hashFile = async (filePath) => {
+  const hash = crypto.createHash('sha256');
+  await pipeline(await openStream(filePath), hash);
+  return hash.digest('hex');
+};
+
+// Re-import the configured source unless the dataset already comes from this very file; a file
+// imported by hand in between counts as a change. A run still going on here leaves the next one out,
+// and an import holding the lease elsewhere (another instance, an upload, the CLI) skips it
+exports.run = async (source = appConfig.importScheduleSource) => {
+  if (state.status === 'running') {
+    console.log(`Scheduled import of ${source} left out, the previous run is still going`);
+    return exports.getState();
+  }
+  
+  const startedAt = new Date();
+  state = { status: 'running', source, startedAt };
+  
+  try {
+    const sourceHash = await hashFile(source);
+    const latest = await ImportRun.findOne(ImportRun.COMPLETED).sort({ completedAt: -1 }).lean();
+    
+    if (latest && latest.source === source && latest.sourceHash === sourceHash) {
+      console.log(`Scheduled import skipped, ${source} is unchanged`);
+      state = { status: 'skipped', source, startedAt, sourceHash, importRunId: latest._id.toString() };
+      return exports.getState();
+    }
+    
+    const summary = await importSwiftCodes(source, { mode: appConfig.importScheduleMode || undefined, sourceHash, trigger: 'schedule' });
+    state = { status: 'imported', source, startedAt, sourceHash, importRunId: summary.importRunId.toString(), imported: summary.imported };
+  } catch (error) {
+    if (error instanceof ImportRunningError) {
+      console.log(`Scheduled import skipped, ${error.message}`);
+      state = { status: 'skipped', source, startedAt, reason: error.message };
+      return exports.getState();
+    }
+    
+    console.error('Scheduled import failed', error);
+    state = { status: 'failed', source, startedAt, error: error.message };
+    // Failures before the import started, such as an unreachable source, aren't stored by importSwiftCodes
+    if (!error.importRunId) {
+      await importRunService.recordFailure({ source, mode: appConfig.importScheduleMode || appConfig.importMode, trigger: 'schedule', startedAt }, error)
+        .catch(recordError => console.error('Failed to record the failed import', recordError));
+    }
+  }
+  
+  return exports.getState();
+};
+
+exports.getState = () => ({ ...state });
+
+// Wall-clock cadence, so "nightly" stays nightly however often the instance restarts; a typo in the
+// expression fails startup rather than silently never importing
+exports.start = () => {
+  if (task || !appConfig.importScheduleSource) {
+    return;
+  }
+  
+  if (!cron.validate(appConfig.importScheduleCron)) {
+    throw new Error(`Invalid IMPORT_SCHEDULE_CRON expression: ${appConfig.importScheduleCron}`);
+  }
+  
+  task = cron.schedule(appConfig.importScheduleCron, () => exports.run(), { timezone: appConfig.importScheduleTimezone });
+};
+
+exports.stop = () => {
+  if (task) {
+    task.stop();
+  }
+  task = null;
+};
+
+// src/utils/countries.js
+// ISO 3166-1 alpha-2 codes with uppercase English names (XK is the user-assigned code SWIFT uses for Kosovo)
+const COUNTRIES = {
+  AD: 'ANDORRA',
+  AE: 'UNITED ARAB EMIRATES',
+  AF: 'AFGHANISTAN',
+  AG: 'ANTIGUA AND BARBUDA',
+  AI: 'ANGUILLA',
+  AL: 'ALBANIA',
+  AM: 'ARMENIA',
+  AO: 'ANGOLA',
+  AQ: 'ANTARCTICA',
+  AR: 'ARGENTINA',
+  AS: 'AMERICAN SAMOA',
+  AT: 'AUSTRIA',
+  AU: 'AUSTRALIA',
+  AW: 'ARUBA',
+  AX: 'ÅLAND ISLANDS',
+  AZ: 'AZERBAIJAN',
+  BA: 'BOSNIA AND HERZEGOVINA',
+  BB: 'BARBADOS',
+  BD: 'BANGLADESH',
+  BE: 'BELGIUM',
+  BF: 'BURKINA FASO',
+  BG: 'BULGARIA',
+  BH: 'BAHRAIN',
+  BI: 'BURUNDI',
+  BJ: 'BENIN',
+  BL: 'SAINT BARTHÉLEMY',
+  BM: 'BERMUDA',
+  BN: 'BRUNEI DARUSSALAM',
+  BO: 'BOLIVIA, PLURINATIONAL STATE OF',
+  BQ: 'BONAIRE, SINT EUSTATIUS AND SABA',
+  BR: 'BRAZIL',
+  BS: 'BAHAMAS',
+  BT: 'BHUTAN',
+  BV: 'BOUVET ISLAND',
+  BW: 'BOTSWANA',
+  BY: 'BELARUS',
+  BZ: 'BELIZE',
+  CA: 'CANADA',
+  CC: 'COCOS (KEELING) ISLANDS',
+  CD: 'CONGO, THE DEMOCRATIC REPUBLIC OF THE',
+  CF: 'CENTRAL AFRICAN REPUBLIC',
+  CG: 'CONGO',
+  CH: 'SWITZERLAND',
+  CI: 'CÔTE D\'IVOIRE',
+  CK: 'COOK ISLANDS',
+  CL: 'CHILE',
+  CM: 'CAMEROON',
+  CN: 'CHINA',
+  CO: 'COLOMBIA',
+  CR: 'COSTA RICA',
+  CU: 'CUBA',
+  CV: 'CABO VERDE',
+  CW: 'CURAÇAO',
+  CX: 'CHRISTMAS ISLAND',
+  CY: 'CYPRUS',
+  CZ: 'CZECHIA',
+  DE: 'GERMANY',
+  DJ: 'DJIBOUTI',
+  DK: 'DENMARK',
+  DM: 'DOMINICA',
+  DO: 'DOMINICAN REPUBLIC',
+  DZ: 'ALGERIA',
+  EC: 'ECUADOR',
+  EE: 'ESTONIA',
+  EG: 'EGYPT',
+  EH: 'WESTERN SAHARA',
+  ER: 'ERITREA',
+  ES: 'SPAIN',
+  ET: 'ETHIOPIA',
+  FI: 'FINLAND',
+  FJ: 'FIJI',
+  FK: 'FALKLAND ISLANDS (MALVINAS)',
+  FM: 'MICRONESIA, FEDERATED STATES OF',
+  FO: 'FAROE ISLANDS',
+  FR: 'FRANCE',
+  GA: 'GABON',
+  GB: 'UNITED KINGDOM',
+  GD: 'GRENADA',
+  GE: 'GEORGIA',
+  GF: 'FRENCH GUIANA',
+  GG: 'GUERNSEY',
+  GH: 'GHANA',
+  GI: 'GIBRALTAR',
+  GL: 'GREENLAND',
+  GM: 'GAMBIA',
+  GN: 'GUINEA',
+  GP: 'GUADELOUPE',
+  GQ: 'EQUATORIAL GUINEA',
+  GR: 'GREECE',
+  GS: 'SOUTH GEORGIA AND THE SOUTH SANDWICH ISLANDS',
+  GT: 'GUATEMALA',
+  GU: 'GUAM',
+  GW: 'GUINEA-BISSAU',
+  GY: 'GUYANA',
+  HK: 'HONG KONG',
+  HM: 'HEARD ISLAND AND MCDONALD ISLANDS',
+  HN: 'HONDURAS',
+  HR: 'CROATIA',
+  HT: 'HAITI',
+  HU: 'HUNGARY',
+  ID: 'INDONESIA',
+  IE: 'IRELAND',
+  IL: 'ISRAEL',
+  IM: 'ISLE OF MAN',
+  IN: 'INDIA',
+  IO: 'BRITISH INDIAN OCEAN TERRITORY',
+  IQ: 'IRAQ',
+  IR: 'IRAN, ISLAMIC REPUBLIC OF',
+  IS: 'ICELAND',
+  IT: 'ITALY',
+  JE: 'JERSEY',
+  JM: 'JAMAICA',
+  JO: 'JORDAN',
+  JP: 'JAPAN',
+  KE: 'KENYA',
+  KG: 'KYRGYZSTAN',
+  KH: 'CAMBODIA',
+  KI: 'KIRIBATI',
+  KM: 'COMOROS',
+  KN: 'SAINT KITTS AND NEVIS',
+  KP: 'KOREA, DEMOCRATIC PEOPLE\'S REPUBLIC OF',
+  KR: 'KOREA, REPUBLIC OF',
+  KW: 'KUWAIT',
+  KY: 'CAYMAN ISLANDS',
+  KZ: 'KAZAKHSTAN',
+  LA: 'LAO PEOPLE\'S DEMOCRATIC REPUBLIC',
+  LB: 'LEBANON',
+  LC: 'SAINT LUCIA',
+  LI: 'LIECHTENSTEIN',
+  LK: 'SRI LANKA',
+  LR: 'LIBERIA',
+  LS: 'LESOTHO',
+  LT: 'LITHUANIA',
+  LU: 'LUXEMBOURG',
+  LV: 'LATVIA',
+  LY: 'LIBYA',
+  MA: 'MOROCCO',
+  MC: 'MONACO',
+  MD: 'MOLDOVA, REPUBLIC OF',
+  ME: 'MONTENEGRO',
+  MF: 'SAINT MARTIN (FRENCH PART)',
+  MG: 'MADAGASCAR',
+  MH: 'MARSHALL ISLANDS',
+  MK: 'NORTH MACEDONIA',
+  ML: 'MALI',
+  MM: 'MYANMAR',
+  MN: 'MONGOLIA',
+  MO: 'MACAO',
+  MP: 'NORTHERN MARIANA ISLANDS',
+  MQ: 'MARTINIQUE',
+  MR: 'MAURITANIA',
+  MS: 'MONTSERRAT',
+  MT: 'MALTA',
+  MU: 'MAURITIUS',
+  MV: 'MALDIVES',
+  MW: 'MALAWI',
+  MX: 'MEXICO',
+  MY: 'MALAYSIA',
+  MZ: 'MOZAMBIQUE',
+  NA: 'NAMIBIA',
+  NC: 'NEW CALEDONIA',
+  NE: 'NIGER',
+  NF: 'NORFOLK ISLAND',
+  NG: 'NIGERIA',
+  NI: 'NICARAGUA',
+  NL: 'NETHERLANDS',
+  NO: 'NORWAY',
+  NP: 'NEPAL',
+  NR: 'NAURU',
+  NU: 'NIUE',
+  NZ: 'NEW ZEALAND',
+  OM: 'OMAN',
+  PA: 'PANAMA',
+  PE: 'PERU',
+  PF: 'FRENCH POLYNESIA',
+  PG: 'PAPUA NEW GUINEA',
+  PH: 'PHILIPPINES',
+  PK: 'PAKISTAN',
+  PL: 'POLAND',
+  PM: 'SAINT PIERRE AND MIQUELON',
+  PN: 'PITCAIRN',
+  PR: 'PUERTO RICO',
+  PS: 'PALESTINE, STATE OF',
+  PT: 'PORTUGAL',
+  PW: 'PALAU',
+  PY: 'PARAGUAY',
+  QA: 'QATAR',
+  RE: 'RÉUNION',
+  RO: 'ROMANIA',
+  RS: 'SERBIA',
+  RU: 'RUSSIAN FEDERATION',
+  RW: 'RWANDA',
+  SA: 'SAUDI ARABIA',
+  SB: 'SOLOMON ISLANDS',
+  SC: 'SEYCHELLES',
+  SD: 'SUDAN',
+  SE: 'SWEDEN',
+  SG: 'SINGAPORE',
+  SH: 'SAINT HELENA, ASCENSION AND TRISTAN DA CUNHA',
+  SI: 'SLOVENIA',
+  SJ: 'SVALBARD AND JAN MAYEN',
+  SK: 'SLOVAKIA',
+  SL: 'SIERRA LEONE',
+  SM: 'SAN MARINO',
+  SN: 'SENEGAL',
+  SO: 'SOMALIA',
+  SR: 'SURINAME',
+  SS: 'SOUTH SUDAN',
+  ST: 'SAO TOME AND PRINCIPE',
+  SV: 'EL SALVADOR',
+  SX: 'SINT MAARTEN (DUTCH PART)',
+  SY: 'SYRIAN ARAB REPUBLIC',
+  SZ: 'ESWATINI',
+  TC: 'TURKS AND CAICOS ISLANDS',
+  TD: 'CHAD',
+  TF: 'FRENCH SOUTHERN TERRITORIES',
+  TG: 'TOGO',
+  TH: 'THAILAND',
+  TJ: 'TAJIKISTAN',
+  TK: 'TOKELAU',
+  TL: 'TIMOR-LESTE',
+  TM: 'TURKMENISTAN',
+  TN: 'TUNISIA',
+  TO: 'TONGA',
+  TR: 'TÜRKIYE',
+  TT: 'TRINIDAD AND TOBAGO',
+  TV: 'TUVALU',
+  TW: 'TAIWAN, PROVINCE OF CHINA',
+  TZ: 'TANZANIA, UNITED REPUBLIC OF',
+  UA: 'UKRAINE',
+  UG: 'UGANDA',
+  UM: 'UNITED STATES MINOR OUTLYING ISLANDS',
+  US: 'UNITED STATES',
+  UY: 'URUGUAY',
+  UZ: 'UZBEKISTAN',
+  VA: 'HOLY SEE (VATICAN CITY STATE)',
+  VC: 'SAINT VINCENT AND THE GRENADINES',
+  VE: 'VENEZUELA, BOLIVARIAN REPUBLIC OF',
+  VG: 'VIRGIN ISLANDS, BRITISH',
+  VI: 'VIRGIN ISLANDS, U.S.',
+  VN: 'VIET NAM',
+  VU: 'VANUATU',
+  WF: 'WALLIS AND FUTUNA',
+  WS: 'SAMOA',
+  XK: 'KOSOVO',
+  YE: 'YEMEN',
+  YT: 'MAYOTTE',
+  ZA: 'SOUTH AFRICA',
+  ZM: 'ZAMBIA',
+  ZW: 'ZIMBABWE'
+};
+
+// Entries managed through the admin API, layered over the built-in list: newly assigned codes,
+// withdrawn codes and preferred display names
+let overrides = new Map();
+
+const isBuiltIn = (countryISO2) => Object.prototype.hasOwnProperty.call(COUNTRIES, countryISO2);
+
+exports.COUNTRIES = COUNTRIES;
+
+exports.isBuiltIn = isBuiltIn;
+
+exports.setOverrides = (entries) => {
+  overrides = new Map(entries.map(entry => [entry.countryISO2, entry]));
+};
+
+// Withdrawn codes no longer validate, so records can't be created or moved into them
+exports.isValidCountryCode = (countryISO2) => {
+  const override = overrides.get(countryISO2);
+  return override ? !override.withdrawn && Boolean(override.name || isBuiltIn(countryISO2)) : isBuiltIn(countryISO2);
+};
+
+exports.getCountryName = (countryISO2) => {
+  const override = overrides.get(countryISO2);
+  return (override && override.name) || COUNTRIES[countryISO2] || null;
+};
+
+exports.describeCountry = (countryISO2) => {
+  const override = overrides.get(countryISO2);
+  const name = exports.getCountryName(countryISO2);
+  
+  if (!name) {
+    return null;
+  }
+  
+  return {
+    countryISO2,
+    name,
+    builtIn: isBuiltIn(countryISO2),
+    withdrawn: Boolean(override && override.withdrawn),
+    ...(override && override.withdrawnAt && { withdrawnAt: override.withdrawnAt })
+  };
+};
+
+exports.listCountries = () => [...new Set([...Object.keys(COUNTRIES), ...overrides.keys()])]
+  .sort()
+  .map(exports.describeCountry)
+  .filter(Boolean);
+
+// tests/integration/swiftCodes.test.js
+const fs = require('fs');
+const os = require('os');
+const crypto = require('crypto');
+const http = require('http');
+const https = require('https');
+const readline = require('readline');
+const { Readable } = require('stream');
+const path = require('path');
+const mongoose = require('mongoose');
+const request = require('supertest');
+const jwt = require('jsonwebtoken');
+const ExcelJS = require('exceljs');
+const AdmZip = require('adm-zip');
+const { MongoMemoryServer } = require('mongodb-memory-server');
+
+// Fail loudly on response drift while the suite runs
+process.env.RESPONSE_VALIDATION = 'fail';
+process.env.API_KEYS = JSON.stringify({
+  'partner-key': { name: 'partner-x', role: 'consumer' },
+  'external-key': { name: 'partner-y', role: 'consumer', redactionProfile: 'external' },
+  'admin-key': { name: 'ops', role: 'admin' }
+});
+
+const app = require('../../src/app');
+const appConfig = require('../../src/config/app');
+const SwiftCode = require('../../src/models/swiftCode');
+const SwiftCodeLookup = require('../../src/models/swiftCodeLookup');
+const AuditLog = require('../../src/models/auditLog');
+const { importSwiftCodes, parseCliOptions } = require('../../src/utils/dataParser');
+const integrityCheck = require('../../src/services/integrityCheck');
+const snapshotPublisher = require('../../src/jobs/snapshotPublisher');
+const scheduledImport = require('../../src/jobs/scheduledImport');
+const temporaryCodeExpiry = require('../../src/jobs/temporaryCodeExpiry');
+const branchCache = require('../../src/utils/branchCache');
+const countryService = require('../../src/services/countryService');
+const configReload = require('../../src/config/reload');
+const leaseService = require('../../src/services/leaseService');
+const { openStream } = require('../../src/utils/importSource');
+
+const headquarter = {
+  swiftCode: 'BPKOPLPWXXX',
+  bankName: 'PKO BANK POLSKI S.A.',
+  address: 'PULAWSKA 15 WARSZAWA',
+  countryISO2: 'PL',
+  countryName: 'POLAND',
+  isHeadquarter: true
+};
+
+const branch = {
+  swiftCode: 'BPKOPLPWKRK',
+  bankName: 'PKO BANK POLSKI S.A.',
+  address: 'WIELOPOLE 19 KRAKOW',
+  countryISO2: 'PL',
+  countryName: 'POLAND',
+  isHeadquarter: false
+};
+
+// Shares the first seven characters with the HQ but belongs to another institution
+const otherBank = {
+  swiftCode: 'BPKOPLPXABC',
+  bankName: 'OTHER BANK',
+  address: 'MARSZALKOWSKA 1 WARSZAWA',
+  countryISO2: 'PL',
+  countryName: 'POLAND',
+  isHeadquarter: false
+};
+
+let mongoServer;
+
+beforeAll(async () => {
+  mongoServer = await MongoMemoryServer.create();
+  await mongoose.connect(mongoServer.getUri());
+  await SwiftCode.init();
+});
+
+// Clear every collection so history, audit and lock documents don't leak between tests; the raw
+// driver calls bypass the model hooks that keep the branch cache in sync
+afterEach(async () => {
+  await Promise.all(Object.values(mongoose.connection.collections).map(collection => collection.deleteMany({})));
+  branchCache.clear();
+  await countryService.loadCountries();
+});
+
+afterAll(async () => {
+  await mongoose.disconnect();
+  await mongoServer.stop();
+});
+
+describe('GET /v1/swift-codes/:swiftCode', () => {
+  beforeEach(async () => {
+    await SwiftCode.create([headquarter, branch, otherBank]);
+  });
+  
+  it('returns a headquarter with its branches only', async () => {
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPWXXX');
+    
+    expect(res.status).toBe(200);
+    expect(res.body.swiftCode).toBe('BPKOPLPWXXX');
+    expect(res.body.countryName).toBe('POLAND');
+    expect(res.body.branches.map(b => b.swiftCode)).toEqual(['BPKOPLPWKRK']);
+  });
+  
+  it('resolves a BIC8 to its headquarter', async () => {
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPW');
+    
+    expect(res.status).toBe(200);
+    expect(res.body.swiftCode).toBe('BPKOPLPWXXX');
+  });
+  
+  it('lists the candidates of a BIC8 without a single headquarter', async () => {
+    const res = await request(app).delete('/v1/swift-codes/BPKOPLPX');
+    
+    expect(res.status).toBe(409);
+    expect(res.body.candidates).toEqual(['BPKOPLPXABC']);
+  });
+  
+  it('drops cached branches when a code of the same BIC8 changes', async () => {
+    await request(app).get('/v1/swift-codes/BPKOPLPWXXX');
+    await SwiftCode.create({ ...branch, swiftCode: 'BPKOPLPWGDA', address: 'DLUGA 1, GDANSK' });
+    
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPWXXX');
+    
+    expect(res.body.branches.map(b => b.swiftCode)).toEqual(['BPKOPLPWGDA', 'BPKOPLPWKRK']);
+  });
+  
+  it('reports per-stage timings in Server-Timing when enabled', async () => {
+    appConfig.stageTimingHeaders = true;
+    
+    try {
+      const res = await request(app).get('/v1/swift-codes/BPKOPLPWXXX');
+      
+      expect(res.status).toBe(200);
+      expect(res.headers['server-timing']).toMatch(/^validation;dur=[\d.]+, cache;dur=[\d.]+, db;dur=[\d.]+, serialization;dur=[\d.]+, total;dur=[\d.]+$/);
+    } finally {
+      appConfig.stageTimingHeaders = false;
+    }
+    
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPWXXX');
+    expect(res.headers['server-timing']).toBeUndefined();
+  });
+  
+  it('answers repeat lookups from the lookup entries of the institution', async () => {
+    const first = await request(app).get('/v1/swift-codes/BPKOPLPWXXX');
+    
+    const entry = await SwiftCodeLookup.findOne({ swiftCode: 'BPKOPLPWKRK' }).lean();
+    expect(entry).toMatchObject({ bic8: 'BPKOPLPW', headquarter: 'BPKOPLPWXXX' });
+    expect(await SwiftCodeLookup.countDocuments({ bic8: 'BPKOPLPX' })).toBe(0);
+    
+    const second = await request(app).get('/v1/swift-codes/BPKOPLPWXXX');
+    expect(second.body).toEqual(first.body);
+    
+    await SwiftCode.updateOne({ swiftCode: 'BPKOPLPWKRK' }, { address: 'WIELOPOLE 20 KRAKOW' });
+    expect(await SwiftCodeLookup.countDocuments({ bic8: 'BPKOPLPW' })).toBe(0);
+    
+    const updated = await request(app).get('/v1/swift-codes/BPKOPLPWKRK');
+    expect(updated.body.address).toBe('WIELOPOLE 20 KRAKOW');
+  });
+  
+  it('pages the branches of a headquarter', async () => {
+    await SwiftCode.create({ ...branch, swiftCode: 'BPKOPLPWGDA', address: 'DLUGA 1, GDANSK' });
+    
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPWXXX?branchLimit=1&branchPage=2');
+    
+    expect(res.status).toBe(200);
+    expect(res.body.branches.map(b => b.swiftCode)).toEqual(['BPKOPLPWKRK']);
+    expect(res.body.branchPagination).toEqual({ page: 2, limit: 1, totalCount: 2, totalPages: 2 });
+  });
+  
+  it('returns a branch without a branches array', async () => {
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPWKRK');
+    
+    expect(res.status).toBe(200);
+    expect(res.body.isHeadquarter).toBe(false);
+    expect(res.body.branches).toBeUndefined();
+  });
+  
+  it('lists the active siblings of a branch in the same country', async () => {
+    await SwiftCode.create([
+      { ...branch, swiftCode: 'BPKOPLPWGDA', status: 'retired' },
+      { ...branch, swiftCode: 'BPKODEFFKRK', countryISO2: 'DE' }
+    ]);
+    
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPWKRK/siblings');
+    
+    expect(res.status).toBe(200);
+    expect(res.body.siblings.map(sibling => sibling.swiftCode)).toEqual(['BPKOPLPWXXX', 'BPKOPLPXABC']);
+  });
+  
+  it('matches codes case-insensitively', async () => {
+    const res = await request(app).get('/v1/swift-codes/bpkoplpwkrk');
+    
+    expect(res.status).toBe(200);
+    expect(res.body.swiftCode).toBe('BPKOPLPWKRK');
+  });
+  
+  it('returns 404 for an unknown code', async () => {
+    const res = await request(app).get('/v1/swift-codes/AAAAPLPWXXX');
+    
+    expect(res.status).toBe(404);
+    expect(res.body.message).toBe('SWIFT code not found');
+  });
+  
+  it('answers HEAD with the status alone', async () => {
+    const found = await request(app).head('/v1/swift-codes/bpkoplpwkrk');
+    const missing = await request(app).head('/v1/swift-codes/AAAAPLPWXXX');
+    
+    expect(found.status).toBe(200);
+    expect(found.text).toBeUndefined();
+    expect(missing.status).toBe(404);
+  });
+  
+  it('hides the fields of the caller\'s redaction profile', async () => {
+    const external = await request(app).get('/v1/swift-codes/BPKOPLPWXXX').set('X-API-Key', 'external-key');
+    const partner = await request(app).get('/v1/swift-codes/BPKOPLPWXXX').set('X-API-Key', 'partner-key');
+    
+    expect(external.status).toBe(200);
+    expect(external.body).not.toHaveProperty('address');
+    expect(external.body.branches[0]).not.toHaveProperty('address');
+    expect(external.body.bankName).toBe('PKO BANK POLSKI S.A.');
+    expect(partner.body.address).toBe('PULAWSKA 15 WARSZAWA');
+  });
+  
+  it('returns only the fields selected with ?fields=', async () => {
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPWXXX?fields=swiftCode,bankName');
+    const unknown = await request(app).get('/v1/swift-codes/BPKOPLPWXXX?fields=swiftCode,iban');
+    
+    expect(res.status).toBe(200);
+    expect(res.body).toMatchObject({ swiftCode: 'BPKOPLPWXXX', bankName: 'PKO BANK POLSKI S.A.' });
+    expect(res.body).not.toHaveProperty('address');
+    expect(res.body).not.toHaveProperty('countryName');
+    expect(res.body.branches).toEqual([{ swiftCode: 'BPKOPLPWKRK', bankName: 'PKO BANK POLSKI S.A.' }]);
+    expect(unknown.status).toBe(400);
+    expect(unknown.body.message).toMatch(/^Unknown fields: iban\./);
+  });
+  
+  it('serves headquarters and branches in the same shape on v2', async () => {
+    const res = await request(app).get('/v2/swift-codes/BPKOPLPWXXX');
+    
+    expect(res.status).toBe(200);
+    expect(res.body).toMatchObject({
+      swiftCode: 'BPKOPLPWXXX',
+      bic8: 'BPKOPLPW',
+      bankName: 'PKO BANK POLSKI S.A.',
+      countryISO2: 'PL',
+      countryName: 'POLAND',
+      isHeadquarter: true,
+      status: 'active',
+      connectivityStatus: 'connected'
+    });
+    expect(res.body.branches[0]).toMatchObject({
+      swiftCode: 'BPKOPLPWKRK',
+      bic8: 'BPKOPLPW',
+      address: 'WIELOPOLE 19 KRAKOW',
+      countryName: 'POLAND',
+      isHeadquarter: false,
+      status: 'active',
+      connectivityStatus: 'connected'
+    });
+  });
+});
+
+describe('GET /v1/swift-codes/count', () => {
+  it('counts the codes matching the filters, every status included', async () => {
+    await SwiftCode.create([headquarter, branch, { ...otherBank, status: 'inactive' }]);
+    
+    const all = await request(app).get('/v1/swift-codes/count');
+    const headquarters = await request(app).get('/v1/swift-codes/count?countryISO2=PL&isHeadquarter=true');
+    const active = await request(app).get('/v1/swift-codes/count?status=active');
+    const invalid = await request(app).get('/v1/swift-codes/count?isHeadquarter=yes');
+    
+    expect(all.body).toEqual({ count: 3 });
+    expect(headquarters.body).toEqual({ count: 1 });
+    expect(active.body).toEqual({ count: 2 });
+    expect(invalid.status).toBe(400);
+    expect(invalid.body.message).toBe('isHeadquarter must be true or false');
+  });
+});
+
+describe('GET /v1/swift-codes/country/:countryISO2', () => {
+  it('lists every code registered in the country', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    
+    const res = await request(app).get('/v1/swift-codes/country/pl');
+    
+    expect(res.status).toBe(200);
+    expect(res.body.countryISO2).toBe('PL');
+    expect(res.body.countryName).toBe('POLAND');
+    expect(res.body.swiftCodes).toHaveLength(2);
+  });
+  
+  it('filters the listing to headquarters with type=headquarter', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    
+    const res = await request(app).get('/v1/swift-codes/country/PL?type=headquarter');
+    
+    expect(res.status).toBe(200);
+    expect(res.body.swiftCodes.map(code => code.swiftCode)).toEqual(['BPKOPLPWXXX']);
+  });
+  
+  it('leaves deactivated codes out unless status is requested', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    await request(app).post('/v1/swift-codes/BPKOPLPWKRK/deactivate').send({ reason: 'Under investigation' });
+    
+    const active = await request(app).get('/v1/swift-codes/country/PL');
+    const inactive = await request(app).get('/v1/swift-codes/country/PL?status=inactive');
+    
+    expect(active.body.swiftCodes.map(code => code.swiftCode)).toEqual(['BPKOPLPWXXX']);
+    expect(inactive.body.swiftCodes.map(code => code.swiftCode)).toEqual(['BPKOPLPWKRK']);
+  });
+  
+  it('filters by connectivity derived from the location code', async () => {
+    const testCode = { ...branch, swiftCode: 'BPKOPLP0XXX', isHeadquarter: true };
+    await request(app).post('/v1/swift-codes').send(headquarter);
+    await request(app).post('/v1/swift-codes').send(testCode);
+    
+    const res = await request(app).get('/v1/swift-codes/country/PL?connectivity=test');
+    const details = await request(app).get('/v1/swift-codes/BPKOPLP0XXX');
+    
+    expect(res.body.swiftCodes.map(code => code.swiftCode)).toEqual(['BPKOPLP0XXX']);
+    expect(details.body.connectivityStatus).toBe('test');
+  });
+  
+  it('groups the listing by city with groupBy=city', async () => {
+    await SwiftCode.create([
+      { ...headquarter, city: 'WARSZAWA' },
+      { ...branch, city: 'KRAKOW' },
+      { ...otherBank, city: 'WARSZAWA' },
+      { ...branch, swiftCode: 'BPKOPLPWGDA' }
+    ]);
+    
+    const res = await request(app).get('/v1/swift-codes/country/PL?groupBy=city');
+    const withFields = await request(app).get('/v1/swift-codes/country/PL?groupBy=city&fields=swiftCode');
+    
+    expect(res.status).toBe(200);
+    expect(res.body).toMatchObject({ countryISO2: 'PL', countryName: 'POLAND' });
+    // Records without a city share the null bucket, which sorts first
+    expect(res.body.cities.map(({ city, swiftCodes }) => [city, swiftCodes.map(code => code.swiftCode)])).toEqual([
+      [null, ['BPKOPLPWGDA']],
+      ['KRAKOW', ['BPKOPLPWKRK']],
+      ['WARSZAWA', ['BPKOPLPWXXX', 'BPKOPLPXABC']]
+    ]);
+    expect(withFields.status).toBe(400);
+  });
+  
+  it('filters the city groups by connectivity', async () => {
+    await SwiftCode.create([
+      { ...headquarter, city: 'WARSZAWA' },
+      { ...branch, swiftCode: 'BPKOPLP0XXX', isHeadquarter: true, city: 'KRAKOW' }
+    ]);
+    
+    const res = await request(app).get('/v1/swift-codes/country/PL?groupBy=city&connectivity=test');
+    
+    expect(res.status).toBe(200);
+    expect(res.body.cities.map(({ city, swiftCodes }) => [city, swiftCodes.map(code => code.swiftCode)])).toEqual([['KRAKOW', ['BPKOPLP0XXX']]]);
+  });
+  
+  it('summarizes the whole listing in meta when paged', async () => {
+    await SwiftCode.create([headquarter, branch, otherBank]);
+    
+    const res = await request(app).get('/v1/swift-codes/country/PL?limit=2&page=2');
+    
+    expect(res.body.swiftCodes.map(code => code.swiftCode)).toEqual(['BPKOPLPXABC']);
+    expect(res.body.meta).toEqual({
+      page: 2,
+      limit: 2,
+      totalCount: 3,
+      totalPages: 2,
+      headquarterCount: 1,
+      branchCount: 2
+    });
+  });
+  
+  it('returns 404 for a country without codes', async () => {
+    const res = await request(app).get('/v1/swift-codes/country/DE');
+    
+    expect(res.status).toBe(404);
+  });
+});
+
+describe('POST /v1/swift-codes', () => {
+  it('creates a record with uppercased country fields', async () => {
+    const res = await request(app)
+      .post('/v1/swift-codes')
+      .send({ ...branch, countryISO2: 'pl', countryName: 'poland' });
+    
+    expect(res.status).toBe(201);
+    expect(res.headers.location).toBe('/v1/swift-codes/BPKOPLPWKRK');
+    expect(res.body.record.bic8).toBe('BPKOPLPW');
+    
+    const stored = await SwiftCode.findOne({ swiftCode: branch.swiftCode });
+    expect(stored.countryISO2).toBe('PL');
+    expect(stored.countryName).toBe('POLAND');
+  });
+  
+  it('derives countryName from countryISO2 and rejects unknown countries', async () => {
+    const created = await request(app)
+      .post('/v1/swift-codes')
+      .send({ ...branch, countryName: 'POLLAND' });
+    
+    expect(created.status).toBe(201);
+    expect(created.body.record.countryName).toBe('POLAND');
+    
+    const rejected = await request(app)
+      .post('/v1/swift-codes')
+      .send({ ...branch, swiftCode: 'BPKOUKPWKRK', countryISO2: 'UK', countryName: 'UNITED KINGOM' });
+    
+    expect(rejected.status).toBe(400);
+    expect(rejected.body.errors).toEqual([expect.objectContaining({ code: 'UNKNOWN_COUNTRY' })]);
+  });
+  
+  it('accepts a code/country mismatch with a warning under the standard profile', async () => {
+    const res = await request(app)
+      .post('/v1/swift-codes')
+      .send({ ...branch, countryISO2: 'DE', countryName: 'GERMANY' });
+    
+    expect(res.status).toBe(201);
+    expect(res.body.warnings).toEqual([
+      expect.objectContaining({ code: 'COUNTRY_MISMATCH', field: 'countryISO2' })
+    ]);
+  });
+  
+  it('derives isHeadquarter from the code and rejects contradictions under the strict profile', async () => {
+    const corrected = await request(app).post('/v1/swift-codes').send({ ...branch, isHeadquarter: true });
+    
+    expect(corrected.status).toBe(201);
+    expect(corrected.body.record.isHeadquarter).toBe(false);
+    expect(corrected.body.warnings).toEqual([expect.objectContaining({ code: 'HEADQUARTER_MISMATCH' })]);
+    
+    appConfig.validationStrictness = 'strict';
+    try {
+      const rejected = await request(app).post('/v1/swift-codes').send({ ...headquarter, isHeadquarter: false });
+      
+      expect(rejected.status).toBe(400);
+      expect(rejected.body.errors).toEqual([expect.objectContaining({ code: 'HEADQUARTER_MISMATCH' })]);
+    } finally {
+      appConfig.validationStrictness = 'standard';
+    }
+  });
+  
+  it('rejects duplicates with 409', async () => {
+    await SwiftCode.create(branch);
+    
+    const res = await request(app).post('/v1/swift-codes').send(branch);
+    
+    expect(res.status).toBe(409);
+  });
+  
+  it('rejects payloads with missing fields', async () => {
+    const { address, ...payload } = branch;
+    
+    const res = await request(app).post('/v1/swift-codes').send(payload);
+    
+    expect(res.status).toBe(400);
+    expect(res.body.message).toBe('Missing required field: address');
+  });
+  
+  it('reports every invalid field of a payload', async () => {
+    const { address, ...payload } = branch;
+    
+    const res = await request(app).post('/v1/swift-codes').send({ ...payload, bankName: 42, isTemporary: 'yes' });
+    
+    expect(res.status).toBe(400);
+    expect(res.body.errors).toEqual([
+      { location: 'body', field: 'bankName', message: 'Field bankName must be a string' },
+      { location: 'body', field: 'address', message: 'Missing required field: address' },
+      { location: 'body', field: 'isTemporary', message: 'Field isTemporary must be a boolean' }
+    ]);
+  });
+  
+  it('names the ISO 9362 violation of a malformed code', async () => {
+    const res = await request(app).post('/v1/swift-codes').send({ ...branch, swiftCode: 'BPK1PLPWKRK' });
+    
+    expect(res.status).toBe(400);
+    expect(res.body.message).toBe('Invalid SWIFT code: Bank code (characters 1-4) must be letters');
+  });
+});
+
+describe('GET /v1/swift-codes/lei/:lei', () => {
+  it('finds codes by LEI and refuses bad check digits on write', async () => {
+    await request(app).post('/v1/swift-codes').send({ ...headquarter, lei: 'P4GTT6GF1W40CVIMFR43' });
+    const invalid = await request(app).post('/v1/swift-codes').send({ ...branch, lei: 'P4GTT6GF1W40CVIMFR44' });
+    
+    const res = await request(app).get('/v1/swift-codes/lei/p4gtt6gf1w40cvimfr43');
+    
+    expect(invalid.statusCode).toBe(400);
+    expect(res.statusCode).toBe(200);
+    expect(res.body.swiftCodes.map(code => code.swiftCode)).toEqual(['BPKOPLPWXXX']);
+  });
+});
+
+describe('GET /v1/swift-codes/national/:scheme/:value', () => {
+  it('resolves a sort code written with separators', async () => {
+    await request(app).post('/v1/swift-codes').send({
+      ...branch,
+      nationalIds: [{ scheme: 'sort_code', value: '20-00-00' }]
+    });
+    
+    const res = await request(app).get('/v1/swift-codes/national/SORT_CODE/200000');
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body.swiftCodes.map(code => code.swiftCode)).toEqual(['BPKOPLPWKRK']);
+  });
+});
+
+describe('GET /v1/swift-codes/search', () => {
+  it('matches localized names added with PATCH', async () => {
+    await SwiftCode.create([headquarter, otherBank]);
+    
+    const patch = await request(app)
+      .patch('/v1/swift-codes/BPKOPLPWXXX')
+      .send({ localizedNames: { ja: 'ピーケーオー銀行' } });
+    const res = await request(app).get(`/v1/swift-codes/search?q=${encodeURIComponent('ピーケーオー')}`);
+    
+    expect(patch.statusCode).toBe(200);
+    expect(res.statusCode).toBe(200);
+    expect(res.body.swiftCodes.map(code => code.swiftCode)).toEqual(['BPKOPLPWXXX']);
+    expect(res.body.swiftCodes[0].localizedNames).toEqual({ ja: 'ピーケーオー銀行' });
+  });
+  
+  it('clamps the limit to the configured search page size and echoes it', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    const original = appConfig.pageSizes.search;
+    appConfig.pageSizes.search = { defaultLimit: 1, maxLimit: 1 };
+    
+    try {
+      const res = await request(app).get('/v1/swift-codes/search?q=pko&limit=10');
+      
+      expect(res.statusCode).toBe(200);
+      expect(res.body.swiftCodes).toHaveLength(1);
+      expect(res.body.pagination).toMatchObject({ limit: 1, totalCount: 2, defaultLimit: 1, maxLimit: 1 });
+    } finally {
+      appConfig.pageSizes.search = original;
+    }
+  });
+  
+  it('normalizes PATCH bodies, path parameters and search input', async () => {
+    await SwiftCode.create(headquarter);
+    
+    const patch = await request(app)
+      .patch('/v1/swift-codes/%20bpkoplpwxxx%20')
+      .send({ bankName: '  PKO   BANK  POLSKI ' });
+    const res = await request(app).get('/v1/swift-codes/search?q=%20pko%20%20bank%20&countryISO2=pl');
+    
+    expect(patch.statusCode).toBe(200);
+    expect((await SwiftCode.findOne({ swiftCode: 'BPKOPLPWXXX' })).bankName).toBe('PKO BANK POLSKI');
+    expect(res.body.swiftCodes.map(code => code.swiftCode)).toEqual(['BPKOPLPWXXX']);
+  });
+  
+  it('matches names by prefix only', async () => {
+    await SwiftCode.create(headquarter);
+    
+    const prefix = await request(app).get('/v1/swift-codes/search?q=pko%20bank');
+    const infix = await request(app).get('/v1/swift-codes/search?q=bank%20polski');
+    
+    expect(prefix.body.swiftCodes.map(code => code.swiftCode)).toEqual(['BPKOPLPWXXX']);
+    expect(infix.body.swiftCodes).toEqual([]);
+  });
+});
+
+describe('POST /v1/iban/resolve', () => {
+  it('resolves an IBAN through the national id of a record', async () => {
+    await SwiftCode.create({
+      ...headquarter,
+      swiftCode: 'COBADEFFXXX',
+      bankName: 'COMMERZBANK AG',
+      countryISO2: 'DE',
+      nationalIds: [{ scheme: 'BLZ', value: '37040044' }]
+    });
+    
+    const res = await request(app).post('/v1/iban/resolve').send({ iban: 'DE89 3704 0044 0532 0130 00' });
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body).toMatchObject({ countryISO2: 'DE', bankCode: '37040044', resolvedBy: 'national-id' });
+    expect(res.body.record.swiftCode).toBe('COBADEFFXXX');
+  });
+  
+  it('falls back to the bank code mapping table', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    
+    const res = await request(app).post('/v1/iban/resolve').send({ iban: 'PL60102010260000042270201111' });
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body.resolvedBy).toBe('mapping-table');
+    expect(res.body.record.swiftCode).toBe('BPKOPLPWXXX');
+  });
+  
+  it('rejects IBANs failing the mod-97 check', async () => {
+    const res = await request(app).post('/v1/iban/resolve').send({ iban: 'DE88370400440532013000' });
+    
+    expect(res.statusCode).toBe(400);
+    expect(res.body.message).toBe('IBAN check digits do not match');
+    expect(res.body.errors).toEqual([{ location: 'body', field: 'iban', message: 'IBAN check digits do not match' }]);
+  });
+  
+  it('prefers the headquarter among records sharing a national id', async () => {
+    const commerzbank = { ...headquarter, bankName: 'COMMERZBANK AG', countryISO2: 'DE', nationalIds: [{ scheme: 'BLZ', value: '37040044' }] };
+    await SwiftCode.create([
+      { ...commerzbank, swiftCode: 'COBADEFFAAA' },
+      { ...commerzbank, swiftCode: 'COBADEFFXXX' }
+    ]);
+    
+    const res = await request(app).post('/v1/iban/resolve').send({ iban: 'DE89 3704 0044 0532 0130 00' });
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body.record.swiftCode).toBe('COBADEFFXXX');
+  });
+});
+
+describe('GET /v1/swift-codes/near', () => {
+  it('finds codes within the radius, nearest first', async () => {
+    await request(app).post('/v1/swift-codes').send({ ...headquarter, latitude: 52.2008, longitude: 21.0147 });
+    await request(app).post('/v1/swift-codes').send({ ...branch, latitude: 50.0617, longitude: 19.9455 });
+    
+    const res = await request(app).get('/v1/swift-codes/near?lat=52.2297&lng=21.0122&radiusKm=10');
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body.swiftCodes.map(code => code.swiftCode)).toEqual(['BPKOPLPWXXX']);
+    expect(res.body.swiftCodes[0].distanceKm).toBeGreaterThan(3);
+  });
+  
+  it('rejects out-of-range coordinates', async () => {
+    const res = await request(app).get('/v1/swift-codes/near?lat=95&lng=21');
+    
+    expect(res.statusCode).toBe(400);
+  });
+});
+
+describe('address normalization', () => {
+  it('normalizes the address on write and keeps the original', async () => {
+    const res = await request(app)
+      .post('/v1/swift-codes')
+      .send({ ...branch, address: 'ul. wielopole  19,  31072 krakow' });
+    
+    expect(res.statusCode).toBe(201);
+    expect(res.body.record.address).toBe('ULICA WIELOPOLE 19, 31-072 KRAKOW');
+    expect(res.body.record.originalAddress).toBe('ul. wielopole  19,  31072 krakow');
+  });
+});
+
+describe('POST /v1/swift-codes/lookup', () => {
+  it('resolves every code in one call and flags the unknown ones', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    
+    const res = await request(app).post('/v1/swift-codes/lookup').send({ swiftCodes: ['bpkoplpwxxx', 'AAAAPLPWXXX'] });
+    const invalid = await request(app).post('/v1/swift-codes/lookup').send({ swiftCodes: 'BPKOPLPWXXX' });
+    
+    expect(res.status).toBe(207);
+    expect(res.body.results.BPKOPLPWXXX).toMatchObject({ found: true, statusCode: 200, bankName: 'PKO BANK POLSKI S.A.' });
+    expect(res.body.results.AAAAPLPWXXX).toMatchObject({ found: false, statusCode: 404, error: { code: 'NOT_FOUND' } });
+    expect(invalid.status).toBe(400);
+  });
+});
+
+describe('POST /v1/swift-codes/validate', () => {
+  it('checks the format of a code and whether it is listed', async () => {
+    await SwiftCode.create(headquarter);
+    
+    const listed = await request(app).post('/v1/swift-codes/validate').send({ swiftCode: 'BPKOPLPWXXX' });
+    const unlisted = await request(app).post('/v1/swift-codes/validate').send({ swiftCode: 'BPKOPLPWGDA' });
+    const malformed = await request(app).post('/v1/swift-codes/validate').send({ swiftCode: 'BPKO' });
+    const missing = await request(app).post('/v1/swift-codes/validate').send({});
+    
+    expect(listed.body).toMatchObject({ swiftCode: 'BPKOPLPWXXX', valid: true, errors: [], exists: true });
+    expect(unlisted.body).toMatchObject({ valid: true, exists: false });
+    expect(malformed.status).toBe(200);
+    expect(malformed.body.valid).toBe(false);
+    expect(malformed.body.errors.length).toBeGreaterThan(0);
+    expect(missing.status).toBe(400);
+  });
+  
+  it('answers a list of codes with one verdict each', async () => {
+    await SwiftCode.create(headquarter);
+    
+    const res = await request(app).post('/v1/swift-codes/validate').send({ swiftCodes: ['BPKOPLPWXXX', 'BPKO'] });
+    
+    expect(res.status).toBe(207);
+    expect(res.body.results.map(({ index, valid, exists, statusCode }) => ({ index, valid, exists, statusCode }))).toEqual([
+      { index: 0, valid: true, exists: true, statusCode: 200 },
+      { index: 1, valid: false, exists: false, statusCode: 400 }
+    ]);
+    expect(res.body.results[1].error.code).toBe('INVALID_FORMAT');
+  });
+});
+
+describe('GET /v1/stats', () => {
+  it('summarizes the dataset', async () => {
+    await SwiftCode.create([headquarter, branch, otherBank, { ...headquarter, swiftCode: 'BPKODEFFXXX', countryISO2: 'DE', countryName: 'GERMANY' }]);
+    
+    const res = await request(app).get('/v1/stats');
+    
+    expect(res.status).toBe(200);
+    expect(res.body).toEqual({ totalCodes: 4, headquarters: 2, branches: 2, countries: 2, banks: 3, lastImportAt: null });
+  });
+});
+
+describe('temporaryCodeExpiry', () => {
+  it('retires temporary codes once they expire', async () => {
+    const now = Date.now();
+    await SwiftCode.create([
+      { ...branch, isTemporary: true, expiresAt: new Date(now - 1000) },
+      { ...otherBank, isTemporary: true, expiresAt: new Date(now + 60 * 60 * 1000) }
+    ]);
+    
+    expect(await temporaryCodeExpiry.run()).toBe(1);
+    
+    const expired = await SwiftCode.findOne({ swiftCode: 'BPKOPLPWKRK' });
+    expect(expired).toMatchObject({ status: 'retired', statusReason: 'Temporary code expired' });
+    expect(expired.deprecatedAt).toBeInstanceOf(Date);
+    expect((await SwiftCode.findOne({ swiftCode: 'BPKOPLPXABC' })).status).toBe('active');
+    
+    const history = await request(app).get('/v1/swift-codes/BPKOPLPWKRK/history');
+    expect(history.body.revisions[0]).toMatchObject({ action: 'expire', actor: 'system:temporary-code-expiry' });
+    expect(await temporaryCodeExpiry.run()).toBe(0);
+  });
+});
+
+describe('POST /v1/swift-codes/bulk', () => {
+  it('reports created, duplicate and invalid rows', async () => {
+    await SwiftCode.create(headquarter);
+    
+    const res = await request(app)
+      .post('/v1/swift-codes/bulk')
+      .send([branch, headquarter, { swiftCode: 'BROKENXX' }]);
+    
+    expect(res.status).toBe(207);
+    expect(res.body.results.map(r => r.status)).toEqual(['created', 'duplicate', 'invalid']);
+    expect(res.body.results.map(r => r.error && r.error.code)).toEqual([undefined, 'DUPLICATE_SWIFT_CODE', 'INVALID_RECORD']);
+  });
+  
+  it('derives isHeadquarter when the records leave it out', async () => {
+    // undefined values are dropped from the JSON body
+    const res = await request(app)
+      .post('/v1/swift-codes/bulk')
+      .send([{ ...headquarter, isHeadquarter: undefined }, { ...branch, isHeadquarter: undefined }]);
+    
+    expect(res.status).toBe(200);
+    expect(res.body.results.map(r => r.status)).toEqual(['created', 'created']);
+    expect((await SwiftCode.findOne({ swiftCode: 'BPKOPLPWXXX' })).isHeadquarter).toBe(true);
+    expect((await SwiftCode.findOne({ swiftCode: 'BPKOPLPWKRK' })).isHeadquarter).toBe(false);
+  });
+});
+
+describe('DELETE /v1/swift-codes/bulk', () => {
+  it('deletes by filter for admins only', async () => {
+    await SwiftCode.create([headquarter, branch, otherBank]);
+    const body = { filter: { countryISO2: 'PL' }, confirm: true };
+    
+    const anonymous = await request(app).delete('/v1/swift-codes/bulk').send(body);
+    const partner = await request(app).delete('/v1/swift-codes/bulk').set('X-API-Key', 'partner-key').send(body);
+    
+    expect(anonymous.statusCode).toBe(401);
+    expect(partner.statusCode).toBe(403);
+    expect(await SwiftCode.countDocuments()).toBe(3);
+    
+    const res = await request(app).delete('/v1/swift-codes/bulk').set('X-API-Key', 'admin-key').send(body);
+    
+    expect(res.statusCode).toBe(200);
+    expect(await SwiftCode.countDocuments()).toBe(0);
+  });
+});
+
+describe('/v1/swift-codes/:swiftCode/lock', () => {
+  const steward = (req, name) => req.set('X-API-Key', 'admin-key').set('X-Actor', name);
+  
+  it('holds writes of other stewards back until released', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    
+    const anonymous = await request(app).post('/v1/swift-codes/BPKOPLPWKRK/lock');
+    const acquired = await steward(request(app).post('/v1/swift-codes/BPKOPLPWKRK/lock'), 'steward-1');
+    const contested = await steward(request(app).post('/v1/swift-codes/BPKOPLPWKRK/lock'), 'steward-2');
+    const heartbeat = await steward(request(app).put('/v1/swift-codes/BPKOPLPWKRK/lock'), 'steward-1');
+    
+    expect(anonymous.statusCode).toBe(401);
+    expect(acquired.statusCode).toBe(200);
+    expect(acquired.body).toMatchObject({ swiftCode: 'BPKOPLPWKRK', lockedBy: 'steward-1' });
+    expect(contested.statusCode).toBe(423);
+    expect(contested.body.lockedBy).toBe('steward-1');
+    expect(heartbeat.statusCode).toBe(200);
+    expect(new Date(heartbeat.body.expiresAt).getTime()).toBeGreaterThanOrEqual(new Date(acquired.body.expiresAt).getTime());
+    
+    const patch = await steward(request(app).patch('/v1/swift-codes/BPKOPLPWKRK'), 'steward-2').send({ bankName: 'PKO BP' });
+    const bulk = await steward(request(app).delete('/v1/swift-codes/bulk'), 'steward-2').send({ filter: { countryISO2: 'PL' }, confirm: true });
+    const rename = await steward(request(app).patch('/v1/banks/BPKOPLPW'), 'steward-2').send({ name: 'PKO BP' });
+    
+    expect(patch.statusCode).toBe(423);
+    expect(bulk.statusCode).toBe(423);
+    expect(bulk.body).toMatchObject({ message: 'Records are locked by steward-1', locks: [{ swiftCode: 'BPKOPLPWKRK' }] });
+    expect(rename.statusCode).toBe(423);
+    expect(await SwiftCode.countDocuments()).toBe(2);
+    
+    const foreignRelease = await steward(request(app).delete('/v1/swift-codes/BPKOPLPWKRK/lock'), 'steward-2');
+    const release = await steward(request(app).delete('/v1/swift-codes/BPKOPLPWKRK/lock'), 'steward-1');
+    const retried = await steward(request(app).patch('/v1/swift-codes/BPKOPLPWKRK'), 'steward-2').send({ bankName: 'PKO BP' });
+    
+    expect(foreignRelease.statusCode).toBe(404);
+    expect(release.statusCode).toBe(200);
+    expect(retried.statusCode).toBe(200);
+  });
+});
+
+describe('DELETE /v1/swift-codes/:swiftCode', () => {
+  it('deletes an existing record', async () => {
+    await SwiftCode.create(branch);
+    
+    const res = await request(app).delete('/v1/swift-codes/BPKOPLPWKRK');
+    
+    expect(res.status).toBe(200);
+    expect(await SwiftCode.countDocuments()).toBe(0);
+  });
+  
+  it('returns 404 for an unknown code', async () => {
+    const res = await request(app).delete('/v1/swift-codes/BPKOPLPWKRK');
+    
+    expect(res.status).toBe(404);
+  });
+  
+  it('answers 204 to a repeated delete with idempotent=true', async () => {
+    await SwiftCode.create(branch);
+    await request(app).delete('/v1/swift-codes/BPKOPLPWKRK');
+    
+    const res = await request(app).delete('/v1/swift-codes/BPKOPLPWKRK?idempotent=true');
+    
+    expect(res.status).toBe(204);
+  });
+  
+  it('records the deletion in the history of the code', async () => {
+    await SwiftCode.create(branch);
+    await request(app).delete('/v1/swift-codes/BPKOPLPWKRK').set('X-API-Key', 'admin-key').set('X-Actor', 'steward-1');
+    
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPWKRK/history');
+    
+    expect(res.status).toBe(200);
+    expect(res.body.revisions).toHaveLength(1);
+    expect(res.body.revisions[0]).toMatchObject({ version: 1, action: 'delete', actor: 'steward-1' });
+    expect(res.body.revisions[0].previous.deletedAt).toBeNull();
+  });
+  
+  it('serves an unlimited history as a single page', async () => {
+    await SwiftCode.create(branch);
+    await request(app).delete('/v1/swift-codes/BPKOPLPWKRK');
+    const original = appConfig.pageSizes.default;
+    appConfig.pageSizes.default = { defaultLimit: 0, maxLimit: 0 };
+    
+    try {
+      const first = await request(app).get('/v1/swift-codes/BPKOPLPWKRK/history');
+      const second = await request(app).get('/v1/swift-codes/BPKOPLPWKRK/history?page=2');
+      
+      expect(first.body.revisions).toHaveLength(1);
+      expect(first.body.pagination).toMatchObject({ page: 1, limit: 0, totalCount: 1, totalPages: 1 });
+      expect(second.body.revisions).toEqual([]);
+      expect(second.body.pagination).toMatchObject({ page: 2, totalPages: 1 });
+    } finally {
+      appConfig.pageSizes.default = original;
+    }
+  });
+  
+  it('ignores X-Actor from callers that can not act on behalf of others', async () => {
+    await SwiftCode.create([branch, otherBank]);
+    await request(app).delete('/v1/swift-codes/BPKOPLPWKRK').set('X-Actor', 'steward-1');
+    await request(app).delete('/v1/swift-codes/BPKOPLPXABC').set('X-API-Key', 'partner-key').set('X-Actor', 'steward-1');
+    
+    const [anonymous, partner] = await Promise.all(
+      ['BPKOPLPWKRK', 'BPKOPLPXABC'].map(code => request(app).get(`/v1/swift-codes/${code}/history`))
+    );
+    
+    expect(anonymous.body.revisions[0].actor).toBe('anonymous');
+    expect(partner.body.revisions[0].actor).toBe('partner-x');
+  });
+  
+  it('diffs two versions of a record field by field', async () => {
+    await SwiftCode.create(branch);
+    await request(app).delete('/v1/swift-codes/BPKOPLPWKRK');
+    await request(app).post('/v1/swift-codes/BPKOPLPWKRK/restore');
+    
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPWKRK/history/diff?from=0&to=1');
+    
+    expect(res.status).toBe(200);
+    expect(res.body.changes).toHaveLength(1);
+    expect(res.body.changes[0]).toMatchObject({ field: 'deletedAt', from: null });
+    
+    const unchanged = await request(app).get('/v1/swift-codes/BPKOPLPWKRK/history/diff?from=0&to=2');
+    expect(unchanged.body.changes).toEqual([]);
+    
+    const missing = await request(app).get('/v1/swift-codes/BPKOPLPWKRK/history/diff?from=1&to=3');
+    expect(missing.status).toBe(404);
+  });
+  
+  it('keeps deleted records restorable', async () => {
+    await SwiftCode.create(branch);
+    await request(app).delete('/v1/swift-codes/BPKOPLPWKRK');
+    
+    expect((await request(app).get('/v1/swift-codes/BPKOPLPWKRK')).status).toBe(404);
+    
+    const res = await request(app).post('/v1/swift-codes/BPKOPLPWKRK/restore');
+    
+    expect(res.status).toBe(200);
+    expect((await request(app).get('/v1/swift-codes/BPKOPLPWKRK')).status).toBe(200);
+  });
+});
+
+describe('/v1/banks', () => {
+  it('keeps the bank entity in sync with its codes', async () => {
+    await request(app).post('/v1/swift-codes').send(headquarter);
+    await request(app).post('/v1/swift-codes').send(branch);
+    
+    const res = await request(app).get('/v1/banks/BPKOPLPW');
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body).toMatchObject({
+      bic8: 'BPKOPLPW',
+      name: 'PKO BANK POLSKI S.A.',
+      headquarter: 'BPKOPLPWXXX',
+      branches: ['BPKOPLPWKRK']
+    });
+    
+    await request(app).delete('/v1/swift-codes/BPKOPLPWKRK');
+    const afterDelete = await request(app).get('/v1/banks/BPKOPLPW');
+    expect(afterDelete.body.branches).toEqual([]);
+  });
+  
+  it('aggregates an institution by its 4-letter bank code', async () => {
+    await SwiftCode.create([
+      headquarter,
+      branch,
+      { ...headquarter, swiftCode: 'BPKODEFFXXX', countryISO2: 'DE', countryName: 'GERMANY' }
+    ]);
+    
+    const res = await request(app).get('/v1/banks/bpko');
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body.countryCount).toBe(2);
+    expect(res.body.codeCount).toBe(3);
+    expect(res.body.countries.map(country => country.countryISO2)).toEqual(['DE', 'PL']);
+  });
+  
+  it('renames every code of the bank', async () => {
+    await request(app).post('/v1/swift-codes/bulk').send([headquarter, branch]);
+    
+    const partner = await request(app).patch('/v1/banks/BPKOPLPW').set('X-API-Key', 'partner-key').send({ name: 'PKO BP' });
+    const res = await request(app).patch('/v1/banks/BPKOPLPW').set('X-API-Key', 'admin-key').send({ name: 'PKO BP' });
+    
+    expect(partner.statusCode).toBe(403);
+    
+    expect(res.statusCode).toBe(200);
+    const branchRes = await request(app).get('/v1/swift-codes/BPKOPLPWKRK');
+    expect(branchRes.body.bankName).toBe('PKO BP');
+  });
+  
+  it('rebuilds the lookup entries of a renamed bank', async () => {
+    await request(app).post('/v1/swift-codes/bulk').send([headquarter, branch]);
+    await request(app).get('/v1/swift-codes/BPKOPLPWKRK');
+    
+    await request(app).patch('/v1/banks/BPKOPLPW').set('X-API-Key', 'admin-key').send({ name: 'PKO BP' });
+    
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPWKRK');
+    expect(res.body.bankName).toBe('PKO BP');
+    expect(await SwiftCodeLookup.countDocuments({ 'record.bankName': 'PKO BANK POLSKI S.A.' })).toBe(0);
+  });
+  
+  it('drops cached branch lists of a renamed bank', async () => {
+    appConfig.lookupReadModel = false;
+    try {
+      await request(app).post('/v1/swift-codes/bulk').send([headquarter, branch]);
+      await request(app).get('/v1/swift-codes/BPKOPLPWXXX');
+      
+      await request(app).patch('/v1/banks/BPKOPLPW').set('X-API-Key', 'admin-key').send({ name: 'PKO BP' });
+      
+      const res = await request(app).get('/v1/swift-codes/BPKOPLPWXXX');
+      expect(res.body.branches[0].bankName).toBe('PKO BP');
+    } finally {
+      appConfig.lookupReadModel = true;
+    }
+  });
+});
+
+describe('auth providers', () => {
+  beforeEach(() => {
+    appConfig.authProviders = ['api-key', 'jwt'];
+    appConfig.jwtSecret = 'test-secret';
+  });
+  
+  afterEach(() => {
+    appConfig.authProviders = ['api-key'];
+    appConfig.jwtSecret = '';
+  });
+  
+  it('accepts bearer tokens carrying the role claim', async () => {
+    const token = jwt.sign({ sub: 'ops-sso', role: 'admin' }, 'test-secret');
+    
+    const res = await request(app).get('/v1/admin/audit-log').set('Authorization', `Bearer ${token}`);
+    
+    expect(res.statusCode).toBe(200);
+  });
+  
+  it('maps the client certificate subject to a consumer', async () => {
+    appConfig.authProviders = ['mtls'];
+    appConfig.mtlsSubjectHeader = 'X-Client-Subject';
+    appConfig.mtlsConsumers = { 'CN=ops.bank-x.com': { name: 'ops-gateway', role: 'admin' } };
+    
+    try {
+      const mapped = await request(app)
+        .get('/v1/admin/audit-log')
+        .set('X-Client-Subject', 'CN=ops.bank-x.com,O=Bank X,C=PL');
+      const unmapped = await request(app)
+        .get('/v1/admin/audit-log')
+        .set('X-Client-Subject', 'CN=unknown.example.com');
+      
+      expect(mapped.statusCode).toBe(200);
+      expect(unmapped.statusCode).toBe(401);
+      expect(unmapped.body.message).toBe('Client certificate is not mapped to a consumer');
+    } finally {
+      appConfig.mtlsSubjectHeader = '';
+      appConfig.mtlsConsumers = {};
+    }
+  });
+  
+  it('rejects tokens with a bad signature', async () => {
+    const token = jwt.sign({ sub: 'ops-sso', role: 'admin' }, 'other-secret');
+    
+    const res = await request(app).get('/v1/admin/audit-log').set('Authorization', `Bearer ${token}`);
+    
+    expect(res.statusCode).toBe(401);
+    expect(res.body.message).toBe('Invalid bearer token');
+  });
+});
+
+describe('/v1/keys', () => {
+  it('rotates a key with a grace period and revokes the replacement', async () => {
+    const created = await request(app).post('/v1/keys').set('X-API-Key', 'partner-key').send({ label: 'ci' });
+    const rotated = await request(app).post(`/v1/keys/${created.body.id}/rotate`).set('X-API-Key', created.body.apiKey);
+    
+    expect(created.statusCode).toBe(201);
+    expect(rotated.statusCode).toBe(201);
+    
+    const withOldKey = await request(app).get('/v1/keys').set('X-API-Key', created.body.apiKey);
+    expect(withOldKey.statusCode).toBe(200);
+    expect(withOldKey.body.keys).toHaveLength(2);
+    
+    await request(app).delete(`/v1/keys/${rotated.body.id}`).set('X-API-Key', 'partner-key');
+    const withRevokedKey = await request(app).get('/v1/keys').set('X-API-Key', rotated.body.apiKey);
+    expect(withRevokedKey.statusCode).toBe(401);
+  });
+  
+  it('stops accepting keys whose parent was removed from API_KEYS', async () => {
+    const created = await request(app).post('/v1/keys').set('X-API-Key', 'partner-key').send({ label: 'ci' });
+    const rotated = await request(app).post(`/v1/keys/${created.body.id}/rotate`).set('X-API-Key', created.body.apiKey);
+    const configured = appConfig.apiKeys;
+    
+    expect((await request(app).get('/v1/keys').set('X-API-Key', rotated.body.apiKey)).statusCode).toBe(200);
+    
+    appConfig.apiKeys = { 'admin-key': configured['admin-key'] };
+    try {
+      const withCreatedKey = await request(app).get('/v1/keys').set('X-API-Key', created.body.apiKey);
+      const withRotatedKey = await request(app).get('/v1/keys').set('X-API-Key', rotated.body.apiKey);
+      
+      expect(withCreatedKey.statusCode).toBe(401);
+      expect(withRotatedKey.statusCode).toBe(401);
+    } finally {
+      appConfig.apiKeys = configured;
+    }
+  });
+});
+
+describe('GET /v1/admin/reports/orphan-branches', () => {
+  it('lists branches without a headquarter record', async () => {
+    await SwiftCode.create([headquarter, branch, otherBank]);
+    
+    const res = await request(app).get('/v1/admin/reports/orphan-branches').set('X-API-Key', 'admin-key');
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body.missingHeadquarterCount).toBe(1);
+    expect(res.body.branches).toEqual([
+      { swiftCode: 'BPKOPLPXABC', bankName: 'OTHER BANK', countryISO2: 'PL', expectedHeadquarter: 'BPKOPLPXXXX' }
+    ]);
+  });
+});
+
+describe('GET /v1/swift-codes/export', () => {
+  it('pages the export and reports the page in headers', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    
+    const paged = await request(app).get('/v1/swift-codes/export?format=csv&page=2&limit=1');
+    
+    expect(paged.statusCode).toBe(200);
+    expect(paged.headers['x-page']).toBe('2');
+    expect(paged.headers['x-page-limit']).toBe('1');
+    expect(paged.headers['x-page-max-limit']).toBeUndefined();
+    expect(paged.text.trim().split('\n')).toHaveLength(2);
+    
+    const original = appConfig.pageSizes.export;
+    appConfig.pageSizes.export = appConfig.buildConfig({ PAGE_SIZE_EXPORT_DEFAULT: '0', PAGE_SIZE_EXPORT_MAX: '1' }).pageSizes.export;
+    
+    try {
+      const clamped = await request(app).get('/v1/swift-codes/export?format=csv');
+      
+      expect(clamped.headers['x-page']).toBe('1');
+      expect(clamped.headers['x-page-limit']).toBe('1');
+      expect(clamped.headers['x-page-max-limit']).toBe('1');
+    } finally {
+      appConfig.pageSizes.export = original;
+    }
+  });
+  
+  it('leaves out the fields hidden by the redaction profile', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    
+    const csv = await request(app).get('/v1/swift-codes/export?format=csv').set('X-API-Key', 'external-key');
+    const nested = await request(app).get('/v1/swift-codes/export?format=json&layout=nested').set('X-API-Key', 'external-key');
+    
+    expect(csv.statusCode).toBe(200);
+    expect(csv.text.split('\n')[0]).toBe('swiftCode,bankName,countryISO2,countryName,isHeadquarter');
+    expect(csv.text).not.toContain('WARSZAWA');
+    expect(nested.body[0].headquarter).not.toHaveProperty('address');
+    expect(nested.body[0].branches[0]).not.toHaveProperty('address');
+  });
+  
+  it('exports a workbook with a header row and typed cells', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    
+    const res = await request(app)
+      .get('/v1/swift-codes/export?format=xlsx')
+      .buffer(true)
+      .parse((response, callback) => {
+        const chunks = [];
+        response.on('data', chunk => chunks.push(chunk));
+        response.on('end', () => callback(null, Buffer.concat(chunks)));
+      });
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.headers['content-type']).toMatch(/spreadsheetml/);
+    
+    const workbook = new ExcelJS.Workbook();
+    await workbook.xlsx.load(res.body);
+    const sheet = workbook.getWorksheet('SWIFT codes');
+    
+    expect(sheet.getRow(1).values.slice(1)).toEqual(['swiftCode', 'bankName', 'address', 'countryISO2', 'countryName', 'isHeadquarter']);
+    expect(sheet.getRow(2).values.slice(1)).toEqual(['BPKOPLPWKRK', 'PKO BANK POLSKI S.A.', 'WIELOPOLE 19 KRAKOW', 'PL', 'POLAND', false]);
+    expect(sheet.getRow(3).getCell(6).value).toBe(true);
+  });
+  
+  it('bundles a zip whose manifest matches the archived files', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    
+    const res = await request(app)
+      .get('/v1/swift-codes/export?format=zip')
+      .buffer(true)
+      .parse((response, callback) => {
+        const chunks = [];
+        response.on('data', chunk => chunks.push(chunk));
+        response.on('end', () => callback(null, Buffer.concat(chunks)));
+      });
+    
+    expect(res.statusCode).toBe(200);
+    
+    const zip = new AdmZip(res.body);
+    const manifest = JSON.parse(zip.readAsText('manifest.json'));
+    
+    expect(manifest.recordCount).toBe(2);
+    expect(manifest.files.map(file => file.name)).toEqual(['swift-codes.csv', 'swift-codes.ndjson']);
+    manifest.files.forEach(file => {
+      const content = zip.getEntry(file.name).getData();
+      expect(file.records).toBe(2);
+      expect(file.bytes).toBe(content.length);
+      expect(file.sha256).toBe(crypto.createHash('sha256').update(content).digest('hex'));
+    });
+  });
+});
+
+describe('GET /v1/admin/dashboard', () => {
+  it('summarizes pending work, recent changes and quality offenders', async () => {
+    await SwiftCode.create([headquarter, branch, { ...otherBank, status: 'pending' }]);
+    // Written around the model so the contradicting values survive
+    await SwiftCode.collection.insertOne({ ...branch, swiftCode: 'BPKOPLPWGDA', bic8: 'BPKOPLPW', countryISO2: 'DE', isHeadquarter: true, deletedAt: null });
+    await request(app).delete('/v1/swift-codes/BPKOPLPWKRK').set('X-API-Key', 'admin-key');
+    
+    const [changes, pending, imports, offenders] = await Promise.all(
+      ['recent-changes', 'pending-approvals', 'failing-imports', 'quality-offenders?limit=1']
+        .map(panel => request(app).get(`/v1/admin/dashboard/${panel}`).set('X-API-Key', 'admin-key'))
+    );
+    
+    expect(changes.statusCode).toBe(200);
+    expect(changes.body.changes[0]).toMatchObject({ swiftCode: 'BPKOPLPWKRK', action: 'delete' });
+    expect(pending.body).toMatchObject({ pendingRecordCount: 1, pendingRecords: [{ swiftCode: 'BPKOPLPXABC' }], openRejectCount: 0 });
+    expect(imports.body).toEqual({ scheduledImport: null, imports: [] });
+    expect(offenders.body.offenders).toEqual([{
+      bic8: 'BPKOPLPW',
+      bankName: 'PKO BANK POLSKI S.A.',
+      codeCount: 1,
+      issueCount: 2,
+      issues: { COUNTRY_MISMATCH: 1, HEADQUARTER_MISMATCH: 1, UNKNOWN_COUNTRY: 0 }
+    }]);
+  });
+});
+
+describe('GET /v1/admin/siem/events', () => {
+  it('streams audit entries and record changes oldest first in one layout', async () => {
+    await AuditLog.create({ at: new Date(Date.now() - 60 * 1000), actor: 'ops', action: 'set-country', method: 'PUT', path: '/v1/admin/countries/PL', statusCode: 200, countries: ['PL'] });
+    await SwiftCode.create(branch);
+    await request(app).delete('/v1/swift-codes/BPKOPLPWKRK').set('X-API-Key', 'admin-key').set('X-Actor', 'steward-1');
+    
+    const res = await request(app).get('/v1/admin/siem/events').set('X-API-Key', 'admin-key');
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.headers['content-type']).toMatch('application/x-ndjson');
+    const events = res.text.trim().split('\n').map(line => JSON.parse(line));
+    expect(events[0]).toMatchObject({
+      schemaVersion: 1,
+      eventType: 'audit',
+      actor: 'ops',
+      action: 'set-country',
+      http: { method: 'PUT', path: '/v1/admin/countries/PL', statusCode: 200, clientIp: null },
+      target: { swiftCodes: [], countries: ['PL'] },
+      change: null
+    });
+    
+    const change = events.find(event => event.eventType === 'change');
+    expect(change).toMatchObject({ actor: 'steward-1', action: 'delete', http: null, target: { swiftCodes: ['BPKOPLPWKRK'], countries: ['PL'] } });
+    expect(change.change.changes.map(({ field }) => field)).toEqual(['deletedAt']);
+  });
+  
+  it('rejects unknown event types', async () => {
+    const res = await request(app).get('/v1/admin/siem/events?types=audit,login').set('X-API-Key', 'admin-key');
+    
+    expect(res.statusCode).toBe(400);
+    expect(res.body.message).toBe('Unknown event types login, expected: audit, change');
+  });
+  
+  it('rejects dates that are not ISO 8601', async () => {
+    const res = await request(app).get('/v1/admin/siem/events?from=yesterday').set('X-API-Key', 'admin-key');
+    
+    expect(res.statusCode).toBe(400);
+    expect(res.body.errors).toEqual([{ location: 'query', field: 'from', message: 'from must be an ISO 8601 date' }]);
+  });
+});
+
+describe('/v1/events/mergers', () => {
+  it('deprecates the absorbed codes and lists the event', async () => {
+    await SwiftCode.create([headquarter, branch, otherBank]);
+    
+    const res = await request(app)
+      .post('/v1/events/mergers')
+      .set('X-API-Key', 'admin-key')
+      .send({ effectiveDate: '2024-01-01', absorbingBic: 'BPKOPLPW', absorbedBics: ['BPKOPLPX'], notes: 'Consolidation' });
+    
+    expect(res.statusCode).toBe(201);
+    expect(res.body.event).toMatchObject({ absorbingBic: 'BPKOPLPW', affectedCodes: ['BPKOPLPXABC'] });
+    
+    const absorbed = await request(app).get('/v2/swift-codes/BPKOPLPXABC');
+    expect(absorbed.body).toMatchObject({ status: 'retired', successor: 'BPKOPLPWXXX', deprecated: true });
+    
+    const timeline = await request(app).get('/v1/events/mergers?bic=BPKOPLPXABC');
+    expect(timeline.body.events).toHaveLength(1);
+  });
+  
+  it('refuses events naming unknown BICs', async () => {
+    await SwiftCode.create(headquarter);
+    
+    const res = await request(app)
+      .post('/v1/events/mergers')
+      .set('X-API-Key', 'admin-key')
+      .send({ effectiveDate: '2024-01-01', absorbingBic: 'BPKOPLPW', absorbedBics: ['AAAAPLPW'] });
+    
+    expect(res.statusCode).toBe(404);
+  });
+  
+  it('refuses events that take effect in the future', async () => {
+    await SwiftCode.create([headquarter, otherBank]);
+    
+    const res = await request(app)
+      .post('/v1/events/mergers')
+      .set('X-API-Key', 'admin-key')
+      .send({ effectiveDate: '2999-01-01', absorbingBic: 'BPKOPLPW', absorbedBics: ['BPKOPLPX'] });
+    
+    expect(res.statusCode).toBe(400);
+    expect((await SwiftCode.findOne({ swiftCode: otherBank.swiftCode })).status).toBe('active');
+  });
+});
+
+describe('/v1/admin/countries', () => {
+  it('renames a country on its SWIFT codes', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    
+    const res = await request(app).put('/v1/admin/countries/PL').set('X-API-Key', 'admin-key').send({ name: 'Republic of Poland' });
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body).toEqual({ countryISO2: 'PL', name: 'REPUBLIC OF POLAND', builtIn: true, withdrawn: false });
+    expect((await SwiftCode.findOne({ swiftCode: 'BPKOPLPWXXX' })).countryName).toBe('REPUBLIC OF POLAND');
+    
+    const history = await request(app).get('/v1/swift-codes/BPKOPLPWKRK/history');
+    expect(history.body.revisions[0]).toMatchObject({ action: 'rename-country', actor: 'ops' });
+    expect(history.body.revisions[0].current.countryName).toBe('REPUBLIC OF POLAND');
+  });
+  
+  it('adds newly assigned codes and withdraws old ones', async () => {
+    const added = await request(app).put('/v1/admin/countries/XZ').set('X-API-Key', 'admin-key').send({ name: 'New Country' });
+    expect(added.statusCode).toBe(201);
+    
+    const created = await request(app).post('/v1/swift-codes').send({ ...headquarter, swiftCode: 'NEWBXZ22XXX', countryISO2: 'XZ', countryName: 'NEW COUNTRY' });
+    expect(created.statusCode).toBe(201);
+    
+    await request(app).put('/v1/admin/countries/XZ').set('X-API-Key', 'admin-key').send({ withdrawn: true });
+    
+    const refused = await request(app).post('/v1/swift-codes').send({ ...headquarter, swiftCode: 'NEWCXZ22XXX', countryISO2: 'XZ', countryName: 'NEW COUNTRY' });
+    expect(refused.statusCode).toBe(400);
+    
+    const reset = await request(app).delete('/v1/admin/countries/XZ').set('X-API-Key', 'admin-key');
+    expect(reset.statusCode).toBe(409);
+  });
+});
+
+describe('POST /v1/admin/config/reload', () => {
+  it('applies reloadable settings from the config file', async () => {
+    const configFile = path.join(os.tmpdir(), `swift-config-${Date.now()}.env`);
+    const { configFile: originalFile, bulkMaxItems, jsonBodyLimit } = appConfig;
+    fs.writeFileSync(configFile, 'BULK_MAX_ITEMS=5\nJSON_BODY_LIMIT=10mb\n');
+    appConfig.configFile = configFile;
+    
+    try {
+      const res = await request(app).post('/v1/admin/config/reload').set('X-API-Key', 'admin-key');
+      
+      expect(res.statusCode).toBe(200);
+      expect(res.body.changed).toEqual(['bulkMaxItems']);
+      expect(appConfig.bulkMaxItems).toBe(5);
+      expect(appConfig.jsonBodyLimit).toBe(jsonBodyLimit);
+    } finally {
+      appConfig.configFile = originalFile;
+      appConfig.bulkMaxItems = bulkMaxItems;
+      fs.unlinkSync(configFile);
+    }
+  });
+  
+  it('reloads on SIGHUP', () => {
+    const configFile = path.join(os.tmpdir(), `swift-config-${Date.now()}.env`);
+    const { configFile: originalFile, bulkMaxItems } = appConfig;
+    fs.writeFileSync(configFile, 'BULK_MAX_ITEMS=7\n');
+    appConfig.configFile = configFile;
+    
+    try {
+      configReload.watchSignals();
+      process.emit('SIGHUP');
+      
+      expect(appConfig.bulkMaxItems).toBe(7);
+    } finally {
+      appConfig.configFile = originalFile;
+      appConfig.bulkMaxItems = bulkMaxItems;
+      fs.unlinkSync(configFile);
+    }
+  });
+});
+
+describe('POST /v1/admin/maintenance/normalize', () => {
+  it('rewrites records stored before normalization existed', async () => {
+    await SwiftCode.create(branch);
+    // Written around the model, as older releases stored it
+    await SwiftCode.collection.insertOne({
+      ...headquarter,
+      bankName: ' PKO BANK  POLSKI S.A.',
+      address: 'UL.  PULAWSKA 15,   02515 WARSZAWA',
+      countryISO2: 'pl',
+      countryName: 'Poland',
+      bic8: 'BPKOPLPW',
+      deletedAt: null
+    });
+    
+    const res = await request(app).post('/v1/admin/maintenance/normalize').set('X-API-Key', 'admin-key');
+    
+    expect(res.statusCode).toBe(202);
+    expect(res.body).toMatchObject({ status: 'running', actor: 'ops' });
+    
+    let state = res.body;
+    while (state.status === 'running') {
+      await new Promise(resolve => setTimeout(resolve, 10));
+      state = (await request(app).get('/v1/admin/maintenance/normalize').set('X-API-Key', 'admin-key')).body;
+    }
+    expect(state).toMatchObject({ status: 'completed', scanned: 2, corrected: 1, conflicts: [] });
+    
+    const stored = await SwiftCode.findOne({ swiftCode: 'BPKOPLPWXXX' }).lean();
+    expect(stored).toMatchObject({
+      bankName: 'PKO BANK POLSKI S.A.',
+      address: 'ULICA PULAWSKA 15, 02-515 WARSZAWA',
+      originalAddress: 'UL. PULAWSKA 15, 02515 WARSZAWA',
+      countryISO2: 'PL',
+      countryName: 'POLAND'
+    });
+  });
+});
+
+describe('POST /v1/admin/maintenance/smoke-test', () => {
+  it('exercises the read paths with sampled codes', async () => {
+    await SwiftCode.create([headquarter, branch, otherBank]);
+    
+    const res = await request(app).post('/v1/admin/maintenance/smoke-test').set('X-API-Key', 'admin-key').send({ samples: 3 });
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body.passed).toBe(true);
+    expect(res.body.sampledCodes).toBe(3);
+    expect(res.body.paths.map(path => path.path)).toEqual(['details', 'bic8', 'country', 'search']);
+    expect(res.body.paths.find(path => path.path === 'country').samples).toBe(1);
+  });
+});
+
+describe('POST /v1/admin/imports/preview', () => {
+  it('maps the first rows with the chosen columns and reports issues', async () => {
+    const csvContent = [
+      'BIC,NAME,STREET,ISO,COUNTRY',
+      'BPKOPLPWXXX,PKO BANK POLSKI S.A.,PULAWSKA 15 WARSZAWA,PL,POLAND',
+      'BPKOPLPWKRK,PKO BANK POLSKI S.A.,,PL,POLAND'
+    ].join('\n');
+    
+    const res = await request(app)
+      .post('/v1/admin/imports/preview')
+      .set('X-API-Key', 'admin-key')
+      .field('mapping', JSON.stringify({
+        swiftCode: 'BIC',
+        bankName: 'NAME',
+        address: 'STREET',
+        countryISO2: 'ISO',
+        countryName: 'COUNTRY'
+      }))
+      .attach('file', Buffer.from(csvContent), 'directory.csv');
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body.records[0]).toMatchObject({ line: 2, record: { swiftCode: 'BPKOPLPWXXX', bic8: 'BPKOPLPW' }, issues: [] });
+    expect(res.body.records[1].issues.map(issue => issue.field)).toEqual(['address']);
+    expect(await SwiftCode.countDocuments()).toBe(0);
+  });
+});
+
+describe('SWIFT code reassignment', () => {
+  it('answers historical queries with the institution holding the code then', async () => {
+    await SwiftCode.create(otherBank);
+    
+    const res = await request(app)
+      .post('/v1/swift-codes/BPKOPLPXABC/reassign')
+      .send({ effectiveDate: '2022-06-01', bankName: 'NEW HOLDER BANK', address: 'UL. NOWY SWIAT 2 WARSZAWA' });
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body.record.bankName).toBe('NEW HOLDER BANK');
+    expect(res.body.record.address).toBe('ULICA NOWY SWIAT 2 WARSZAWA');
+    expect(res.body.assignments.map(assignment => assignment.holder.bankName)).toEqual(['OTHER BANK', 'NEW HOLDER BANK']);
+    
+    const before = await request(app).get('/v1/swift-codes/BPKOPLPXABC/assignments?at=2021-01-01');
+    expect(before.body.assignment).toMatchObject({ holder: { bankName: 'OTHER BANK' }, validFrom: null });
+    
+    const after = await request(app).get('/v1/swift-codes/BPKOPLPXABC/assignments?at=2023-01-01');
+    expect(after.body.assignment.holder.bankName).toBe('NEW HOLDER BANK');
+    
+    const overlapping = await request(app)
+      .post('/v1/swift-codes/BPKOPLPXABC/reassign')
+      .send({ effectiveDate: '2022-01-01', bankName: 'THIRD BANK', address: 'ZLOTA 3 WARSZAWA' });
+    expect(overlapping.statusCode).toBe(409);
+  });
+});
+
+describe('POST /v1/swift-codes/validate/stream', () => {
+  it('answers every line of the stream with a verdict', async () => {
+    await SwiftCode.create(headquarter);
+    
+    const res = await request(app)
+      .post('/v1/swift-codes/validate/stream')
+      .set('Content-Type', 'application/x-ndjson')
+      .send('BPKOPLPWXXX\n{"swiftCode":"INVALID","id":"payment-7"}\nnot json {\n');
+    
+    expect(res.statusCode).toBe(200);
+    const verdicts = res.text.trim().split('\n').map(line => JSON.parse(line));
+    expect(verdicts.map(({ index, valid, exists }) => ({ index, valid, exists }))).toEqual([
+      { index: 0, valid: true, exists: true },
+      { index: 1, valid: false, exists: false },
+      { index: 2, valid: false, exists: false }
+    ]);
+    expect(verdicts[1].id).toBe('payment-7');
+  });
+  
+  it('answers each line while the request is still open', async () => {
+    await SwiftCode.create(headquarter);
+    const server = app.listen(0);
+    
+    try {
+      const verdicts = await new Promise((resolve, reject) => {
+        const req = http.request({
+          port: server.address().port,
+          method: 'POST',
+          path: '/v1/swift-codes/validate/stream',
+          headers: { 'Content-Type': 'application/x-ndjson' }
+        });
+        req.on('error', reject);
+        req.on('response', (res) => {
+          const received = [];
+          const lines = readline.createInterface({ input: res });
+          lines.on('line', (line) => {
+            received.push(JSON.parse(line));
+            // The second code is only sent once the first verdict is back
+            if (received.length === 1) {
+              req.end('INVALID\n');
+            }
+          });
+          lines.on('close', () => resolve(received));
+        });
+        req.write('BPKOPLPWXXX\n');
+      });
+      
+      expect(verdicts.map(({ swiftCode, valid }) => ({ swiftCode, valid }))).toEqual([
+        { swiftCode: 'BPKOPLPWXXX', valid: true },
+        { swiftCode: 'INVALID', valid: false }
+      ]);
+    } finally {
+      server.close();
+    }
+  });
+  
+  // Verdicts received until the server closes the stream, whether it ends or is cut
+  const streamUntilClosed = (server, body, { end = true } = {}) => new Promise((resolve) => {
+    const req = http.request({
+      port: server.address().port,
+      method: 'POST',
+      path: '/v1/swift-codes/validate/stream',
+      headers: { 'Content-Type': 'application/x-ndjson' }
+    });
+    req.on('error', () => {});
+    req.on('response', (res) => {
+      const received = [];
+      res.on('error', () => {});
+      readline.createInterface({ input: res }).on('line', line => received.push(JSON.parse(line)));
+      res.on('close', () => resolve({ received, complete: res.complete }));
+    });
+    req.write(body);
+    if (end) {
+      req.end();
+    }
+  });
+  
+  it('cuts streams that exceed the line limit or go idle', async () => {
+    const server = app.listen(0);
+    const { validationStreamMaxLines, validationStreamIdleTimeoutMs } = appConfig;
+    appConfig.validationStreamMaxLines = 2;
+    appConfig.validationStreamIdleTimeoutMs = 100;
+    
+    try {
+      const tooLong = await streamUntilClosed(server, 'BPKOPLPWXXX\nBPKOPLPWKRK\nBPKOPLPXABC\n');
+      const idle = await streamUntilClosed(server, 'BPKOPLPWXXX\n', { end: false });
+      
+      expect(tooLong.received.map(verdict => verdict.index)).toEqual([0, 1]);
+      expect(tooLong.complete).toBe(false);
+      expect(idle.received.map(verdict => verdict.index)).toEqual([0]);
+      expect(idle.complete).toBe(false);
+    } finally {
+      appConfig.validationStreamMaxLines = validationStreamMaxLines;
+      appConfig.validationStreamIdleTimeoutMs = validationStreamIdleTimeoutMs;
+      server.close();
+    }
+  });
+  
+  it('refuses JSON bodies with 415', async () => {
+    const res = await request(app).post('/v1/swift-codes/validate/stream').send({ swiftCode: 'BPKOPLPWXXX' });
+    
+    expect(res.statusCode).toBe(415);
+  });
+});
+
+describe('POST /v1/admin/imports', () => {
+  const csvContent = [
+    'SWIFT,BANK_NAME,ADDRESS,COUNTRY_ISO,COUNTRY_NAME',
+    'BPKOPLPWXXX,PKO BANK POLSKI S.A.,PULAWSKA 15 WARSZAWA,pl,poland'
+  ].join('\n');
+  
+  it('imports an uploaded file', async () => {
+    await SwiftCode.create(otherBank);
+    
+    const res = await request(app)
+      .post('/v1/admin/imports')
+      .set('X-API-Key', 'admin-key')
+      .field('mode', 'upsert')
+      .attach('file', Buffer.from(csvContent), 'directory.csv');
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body).toMatchObject({ mode: 'upsert', imported: 1, insertedCount: 1 });
+    expect(await SwiftCode.countDocuments()).toBe(2);
+  });
+  
+  it('only reports what a dry run would do', async () => {
+    const res = await request(app)
+      .post('/v1/admin/imports')
+      .set('X-API-Key', 'admin-key')
+      .field('dryRun', 'true')
+      .attach('file', Buffer.from(csvContent), 'directory.csv');
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body).toMatchObject({ source: 'upload:directory.csv', wouldInsert: 1 });
+    expect(await SwiftCode.countDocuments()).toBe(0);
+  });
+  
+  it('refuses to start while another process holds the import lease', async () => {
+    const lease = await leaseService.acquire('import');
+    
+    try {
+      const res = await request(app)
+        .post('/v1/admin/imports')
+        .set('X-API-Key', 'admin-key')
+        .attach('file', Buffer.from(csvContent), 'directory.csv');
+      
+      expect(res.statusCode).toBe(409);
+      expect(res.body.message).toBe('An import is already running');
+    } finally {
+      await lease.release();
+    }
+  });
+});
+
+describe('importSwiftCodes', () => {
+  let filePath;
+  
+  beforeEach(() => {
+    filePath = path.join(os.tmpdir(), `swift-codes-${Date.now()}.csv`);
+    fs.writeFileSync(filePath, [
+      'SWIFT,BANK_NAME,ADDRESS,COUNTRY_ISO,COUNTRY_NAME',
+      'BPKOPLPWXXX,PKO BANK POLSKI S.A.,PULAWSKA 15 WARSZAWA,pl,poland',
+      'BPKOPLPWKRK,PKO BANK POLSKI S.A.,WIELOPOLE 19 KRAKOW,pl,poland'
+    ].join('\n'));
+  });
+  
+  afterEach(() => {
+    fs.unlinkSync(filePath);
+  });
+  
+  it('replaces the collection and derives headquarter flags', async () => {
+    await SwiftCode.create(otherBank);
+    
+    const summary = await importSwiftCodes(filePath);
+    
+    expect(summary.imported).toBe(2);
+    expect(await SwiftCode.countDocuments()).toBe(2);
+    
+    const res = await request(app).get('/v1/swift-codes/BPKOPLPWXXX');
+    expect(res.body.isHeadquarter).toBe(true);
+    expect(res.body.countryISO2).toBe('PL');
+    expect(res.body.branches).toHaveLength(1);
+  });
+  
+  it('streams the file in batches', async () => {
+    fs.appendFileSync(filePath, '\nBPKOPLPWGDA,PKO BANK POLSKI S.A.,DLUGA 1 GDANSK,pl,poland');
+    
+    const summary = await importSwiftCodes(filePath, { batchSize: 2 });
+    
+    expect(summary.imported).toBe(3);
+    expect(await SwiftCode.countDocuments()).toBe(3);
+  });
+  
+  it('merges the file into the collection in upsert mode', async () => {
+    await SwiftCode.create([
+      { ...headquarter, bankName: 'PKO BP', localizedNames: { ja: 'ピーケーオー銀行' } },
+      { ...otherBank, externalIds: { CORE_BANKING: 'CB-1' }, importRun: new mongoose.Types.ObjectId() },
+      { ...otherBank, swiftCode: 'BPKOPLPXDEF' }
+    ]);
+    
+    const summary = await importSwiftCodes(filePath, { mode: 'upsert', deactivateMissing: true });
+    
+    expect(summary).toMatchObject({ mode: 'upsert', imported: 2, insertedCount: 1, updatedCount: 1, deactivatedCount: 1 });
+    const updated = await SwiftCode.findOne({ swiftCode: 'BPKOPLPWXXX' });
+    expect(updated.bankName).toBe('PKO BANK POLSKI S.A.');
+    expect([...updated.searchNames]).toEqual(['PKO BANK POLSKI S.A.', 'ピーケーオー銀行']);
+    // The new bank name hands the code over from the start of the import
+    const assignments = await request(app).get('/v1/swift-codes/BPKOPLPWXXX/assignments');
+    expect(assignments.body.assignments.map(assignment => assignment.holder.bankName)).toEqual(['PKO BP', 'PKO BANK POLSKI S.A.']);
+    
+    const missing = await SwiftCode.findOne({ swiftCode: 'BPKOPLPXABC' });
+    expect(missing.status).toBe('inactive');
+    expect(missing.externalIds.get('CORE_BANKING')).toBe('CB-1');
+    // Created through the API, so never part of a file
+    expect((await SwiftCode.findOne({ swiftCode: 'BPKOPLPXDEF' })).status).toBe('active');
+  });
+  
+  it('keeps codes whose row was rejected active in upsert mode', async () => {
+    await SwiftCode.create({ ...otherBank, importRun: new mongoose.Types.ObjectId() });
+    fs.appendFileSync(filePath, '\nBPKOPLPXABC,,MARSZALKOWSKA 1 WARSZAWA,pl,poland');
+    
+    const report = await importSwiftCodes(filePath, { mode: 'upsert', deactivateMissing: true, dryRun: true });
+    const summary = await importSwiftCodes(filePath, { mode: 'upsert', deactivateMissing: true });
+    
+    expect(report).toMatchObject({ wouldDeactivate: 0, rejectedCount: 1 });
+    expect(summary).toMatchObject({ imported: 2, rejectedCount: 1, deactivatedCount: 0 });
+    expect((await SwiftCode.findOne({ swiftCode: 'BPKOPLPXABC' })).status).toBe('active');
+  });
+  
+  it('applies only the differences in delta mode', async () => {
+    await SwiftCode.create([
+      { ...headquarter, bankName: 'PKO BP', localizedNames: { ja: 'ピーケーオー銀行' } },
+      { ...otherBank, importRun: new mongoose.Types.ObjectId() },
+      { ...otherBank, swiftCode: 'BPKOPLPXDEF' }
+    ]);
+    
+    const summary = await importSwiftCodes(filePath, { mode: 'delta' });
+    
+    expect(summary).toMatchObject({ mode: 'delta', addedCount: 1, modifiedCount: 1, removedCount: 1 });
+    expect(summary.changeset.added).toEqual(['BPKOPLPWKRK']);
+    expect(summary.changeset.removed).toEqual(['BPKOPLPXABC']);
+    expect(summary.changeset.modified[0]).toMatchObject({
+      swiftCode: 'BPKOPLPWXXX',
+      changes: { bankName: { from: 'PKO BP', to: 'PKO BANK POLSKI S.A.' } }
+    });
+    expect([...(await SwiftCode.findOne({ swiftCode: 'BPKOPLPWXXX' })).searchNames]).toContain('ピーケーオー銀行');
+    expect(await SwiftCode.findOne({ swiftCode: 'BPKOPLPXABC' })).toBeNull();
+    expect(await SwiftCode.findOne({ swiftCode: 'BPKOPLPXDEF' })).not.toBeNull();
+    
+    const stored = await request(app).get(`/v1/admin/imports/${summary.importRunId}/changeset`).set('X-API-Key', 'admin-key');
+    expect(stored.statusCode).toBe(200);
+    expect(stored.body.changeset.removed).toEqual(['BPKOPLPXABC']);
+    
+    const rerun = await importSwiftCodes(filePath, { mode: 'delta' });
+    expect(rerun).toMatchObject({ addedCount: 0, modifiedCount: 0, removedCount: 0, unchangedCount: 2 });
+  });
+  
+  it('reports what an import would do without writing', async () => {
+    await SwiftCode.create(headquarter);
+    fs.appendFileSync(filePath, '\nBPKOPLPWKRK,PKO BANK POLSKI S.A.,WIELOPOLE 20 KRAKOW,pl,poland\n,NO CODE BANK,SOMEWHERE 1,pl,poland');
+    
+    const report = await importSwiftCodes(filePath, { mode: 'upsert', dedupe: false, dryRun: true });
+    
+    expect(report).toMatchObject({ mode: 'upsert', rows: 4, wouldInsert: 1, wouldUpdate: 1, rejectedCount: 2 });
+    expect(report.rejects.map(reject => [reject.line, reject.reasons[0].code])).toEqual([[4, 'DUPLICATE_IN_FILE'], [5, 'MISSING_FIELD']]);
+    expect(await SwiftCode.countDocuments()).toBe(1);
+    expect(await mongoose.connection.collection('importrejects').countDocuments()).toBe(0);
+    expect(await mongoose.connection.collection('importruns').countDocuments()).toBe(0);
+  });
+  
+  it('reads semicolon-separated latin1 files', async () => {
+    fs.writeFileSync(filePath, Buffer.from('SWIFT;BANK_NAME;ADDRESS;COUNTRY_ISO;COUNTRY_NAME\nBPKOPLPWXXX;BANK MÜNCHEN;ULICA 1;pl;poland', 'latin1'));
+    
+    const { options } = parseCliOptions([filePath, '--delimiter', ';', '--encoding', 'latin1']);
+    const summary = await importSwiftCodes(filePath, options);
+    
+    expect(summary.imported).toBe(1);
+    expect((await SwiftCode.findOne({ swiftCode: 'BPKOPLPWXXX' })).bankName).toBe('BANK MÜNCHEN');
+    expect(parseCliOptions([])).toEqual({ help: true });
+    expect(() => parseCliOptions([filePath, '--batch-size', 'ten'])).toThrow('--batch-size');
+  });
+  
+  it('imports NDJSON lines keyed by record field names', async () => {
+    const ndjsonPath = `${filePath}.ndjson`;
+    fs.writeFileSync(ndjsonPath, [
+      JSON.stringify({ swiftCode: 'BPKOPLPWXXX', bankName: 'PKO BANK POLSKI S.A.', address: 'PULAWSKA 15 WARSZAWA', countryISO2: 'PL', countryName: 'POLAND', isHeadquarter: true }),
+      '',
+      '{"swiftCode": "BPKOPLPWKRK",',
+      JSON.stringify({ swiftCode: 'BPKOPLPWGDA', bankName: 'PKO BANK POLSKI S.A.', address: 'DLUGA 1 GDANSK', countryISO2: 'PL', latitude: 54.35, longitude: 18.65 })
+    ].join('\n'));
+    
+    try {
+      const summary = await importSwiftCodes(ndjsonPath);
+      
+      expect(summary).toMatchObject({ imported: 2, rejectedCount: 1, rejectReasonCounts: { INVALID_RECORD: 1 } });
+      expect((await SwiftCode.findOne({ swiftCode: 'BPKOPLPWGDA' })).location.coordinates).toEqual([18.65, 54.35]);
+      
+      const rejects = await request(app).get(`/v1/admin/imports/${summary.importRunId}/rejects`).set('X-API-Key', 'admin-key');
+      expect(rejects.body.rejects[0]).toMatchObject({ line: 3, reasons: [{ code: 'INVALID_RECORD', field: 'line' }] });
+    } finally {
+      fs.unlinkSync(ndjsonPath);
+    }
+  });
+  
+  it('skips scheduled runs while the source is unchanged', async () => {
+    expect(await scheduledImport.run(filePath)).toMatchObject({ status: 'imported', imported: 2 });
+    expect(await scheduledImport.run(filePath)).toMatchObject({ status: 'skipped' });
+    
+    fs.appendFileSync(filePath, '\nBPKOPLPWGDA,PKO BANK POLSKI S.A.,DLUGA 1 GDANSK,pl,poland');
+    expect(await scheduledImport.run(filePath)).toMatchObject({ status: 'imported', imported: 3 });
+  });
+  
+  it('skips a scheduled run while another import holds the lease', async () => {
+    const lease = await leaseService.acquire('import');
+    
+    try {
+      expect(await scheduledImport.run(filePath)).toMatchObject({ status: 'skipped', reason: 'An import is already running' });
+      expect(await SwiftCode.countDocuments()).toBe(0);
+    } finally {
+      await lease.release();
+    }
+  });
+  
+  it('keeps failed imports for the dashboard', async () => {
+    const missingPath = `${filePath}.missing`;
+    await expect(importSwiftCodes(missingPath)).rejects.toThrow();
+    expect(await scheduledImport.run(missingPath)).toMatchObject({ status: 'failed' });
+    
+    const res = await request(app).get('/v1/admin/dashboard/failing-imports').set('X-API-Key', 'admin-key');
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body.scheduledImport).toMatchObject({ status: 'failed', source: missingPath, error: expect.any(String) });
+    expect(res.body.imports).toHaveLength(2);
+    expect(res.body.imports.map(run => [run.status, run.trigger, run.source])).toEqual(expect.arrayContaining([
+      ['failed', 'cli', missingPath],
+      ['failed', 'schedule', missingPath]
+    ]));
+  });
+  
+  it('gives up on a download source that stops responding', async () => {
+    // Never answers; https.get is pointed at it over plain HTTP so no certificate is needed
+    const server = http.createServer(() => {});
+    await new Promise(resolve => server.listen(0, resolve));
+    const get = jest.spyOn(https, 'get')
+      .mockImplementation((url, options, callback) => http.get(url.replace(/^https:/, 'http:'), options, callback));
+    const timeout = appConfig.importDownloadTimeoutMs;
+    appConfig.importDownloadTimeoutMs = 50;
+    
+    try {
+      await expect(openStream(`https://localhost:${server.address().port}/swift_codes.csv`)).rejects.toThrow('timed out after 50 ms');
+    } finally {
+      appConfig.importDownloadTimeoutMs = timeout;
+      get.mockRestore();
+      server.closeAllConnections();
+      await new Promise(resolve => server.close(resolve));
+    }
+  });
+  
+  it('leaves the collection alone when the file is missing', async () => {
+    await SwiftCode.create(otherBank);
+    
+    await expect(importSwiftCodes(`${filePath}.missing`)).rejects.toThrow();
+    await expect(importSwiftCodes('ftp://example.com/swift_codes.csv')).rejects.toThrow('Unsupported import source');
+    expect(await SwiftCode.countDocuments()).toBe(1);
+    expect(parseCliOptions(['https://example.com/swift_codes.csv']).filePath).toBe('https://example.com/swift_codes.csv');
+  });
+  
+  it('fails a replace import without a valid row and keeps the collection', async () => {
+    await SwiftCode.create(otherBank);
+    
+    fs.writeFileSync(filePath, 'SWIFT,BANK_NAME,ADDRESS,COUNTRY_ISO,COUNTRY_NAME\n');
+    await expect(importSwiftCodes(filePath)).rejects.toThrow('found no valid rows (0 rejected)');
+    
+    // Wrong delimiter: every row lands in a single column and is rejected
+    fs.writeFileSync(filePath, 'SWIFT;BANK_NAME;ADDRESS;COUNTRY_ISO;COUNTRY_NAME\nBPKOPLPWXXX;PKO BANK POLSKI S.A.;PULAWSKA 15;PL;POLAND\n');
+    await expect(importSwiftCodes(filePath)).rejects.toThrow('found no valid rows (1 rejected)');
+    
+    expect(await SwiftCode.countDocuments()).toBe(1);
+    expect(await mongoose.connection.collection('importruns').countDocuments({ status: 'failed' })).toBe(2);
+  });
+  
+  it('reads the directory sheet of an Excel workbook below its title block', async () => {
+    const workbookPath = path.join(os.tmpdir(), `swift-codes-${Date.now()}.xlsx`);
+    const workbook = new ExcelJS.Workbook();
+    workbook.addWorksheet('Notes').addRow(['Generated for testing']);
+    const sheet = workbook.addWorksheet('Directory');
+    sheet.addRow(['BIC directory extract']);
+    sheet.addRow([]);
+    sheet.addRow(['SWIFT', 'BANK_NAME', 'ADDRESS', 'COUNTRY_ISO', 'COUNTRY_NAME']);
+    sheet.addRow(['BPKOPLPWXXX', 'PKO BANK POLSKI S.A.', 'PULAWSKA 15 WARSZAWA', 'PL', 'POLAND']);
+    sheet.addRow(['BPKOPLPWKRK', '', 'WIELOPOLE 19 KRAKOW', 'PL', 'POLAND']);
+    await workbook.xlsx.writeFile(workbookPath);
+    
+    try {
+      const summary = await importSwiftCodes(workbookPath);
+      
+      expect(summary.imported).toBe(1);
+      expect(summary.rejectedCount).toBe(1);
+      expect((await SwiftCode.findOne({ swiftCode: 'BPKOPLPWXXX' })).bankName).toBe('PKO BANK POLSKI S.A.');
+      
+      const rejects = await request(app).get(`/v1/admin/imports/${summary.importRunId}/rejects`).set('X-API-Key', 'admin-key');
+      expect(rejects.body.rejects[0].line).toBe(5);
+    } finally {
+      fs.unlinkSync(workbookPath);
+    }
+  });
+  
+  it('writes rejected rows with their line and reasons to the rejects file', async () => {
+    const rejectsFile = `${filePath}.rejects.ndjson`;
+    fs.appendFileSync(filePath, '\nBPKOPLPWGDA,,DLUGA 1 GDANSK,pl,poland\n,NO CODE BANK,SOMEWHERE 1,pl,poland');
+    
+    try {
+      const summary = await importSwiftCodes(filePath, parseCliOptions([filePath, '--rejects', rejectsFile]).options);
+      
+      expect(summary).toMatchObject({ imported: 2, rejectedCount: 2, rejectsFile });
+      expect(summary.rejectReasonCounts.MISSING_FIELD).toBeGreaterThanOrEqual(2);
+      
+      const lines = fs.readFileSync(rejectsFile, 'utf8').trim().split('\n').map(line => JSON.parse(line));
+      expect(lines.map(reject => [reject.line, reject.swiftCode])).toEqual([[4, 'BPKOPLPWGDA'], [5, null]]);
+      expect(lines[0].reasons).toEqual([{ code: 'MISSING_FIELD', field: 'bankName', message: expect.any(String) }]);
+    } finally {
+      fs.rmSync(rejectsFile, { force: true });
+    }
+  });
+  
+  it('keeps rejected rows until they are corrected and resubmitted', async () => {
+    fs.appendFileSync(filePath, '\nBPKOPLPWGDA,,DLUGA 1 GDANSK,pl,poland');
+    
+    const summary = await importSwiftCodes(filePath);
+    expect(summary.rejectedCount).toBe(1);
+    
+    const rejects = await request(app)
+      .get(`/v1/admin/imports/${summary.importRunId}/rejects?reason=MISSING_FIELD`)
+      .set('X-API-Key', 'admin-key');
+    
+    expect(rejects.statusCode).toBe(200);
+    expect(rejects.body.reasonCounts).toEqual([{ code: 'MISSING_FIELD', count: 1 }]);
+    expect(rejects.body.rejects).toHaveLength(1);
+    expect(rejects.body.rejects[0]).toMatchObject({ line: 4, status: 'open', reasons: [{ code: 'MISSING_FIELD', field: 'bankName' }] });
+    
+    const resubmitPath = `/v1/admin/imports/${summary.importRunId}/rejects/${rejects.body.rejects[0].id}/resubmit`;
+    const unknownCountry = await request(app)
+      .post(resubmitPath)
+      .set('X-API-Key', 'admin-key')
+      .send({ bankName: 'PKO BANK POLSKI S.A.', countryISO2: 'XX' });
+    
+    expect(unknownCountry.statusCode).toBe(400);
+    expect(unknownCountry.body.errors).toEqual([expect.objectContaining({ code: 'UNKNOWN_COUNTRY', field: 'countryISO2' })]);
+    
+    const resubmitted = await request(app)
+      .post(resubmitPath)
+      .set('X-API-Key', 'admin-key')
+      .send({ bankName: 'PKO BANK POLSKI S.A.' });
+    
+    expect(resubmitted.statusCode).toBe(201);
+    expect(resubmitted.body.reject.status).toBe('resubmitted');
+    expect(resubmitted.body.record.swiftCode).toBe('BPKOPLPWGDA');
+    expect(await SwiftCode.countDocuments()).toBe(3);
+    
+    const again = await request(app).post(resubmitPath).set('X-API-Key', 'admin-key').send({});
+    expect(again.statusCode).toBe(409);
+  });
+});
+
+describe('GET /v1/meta/error-codes', () => {
+  it('lists the import reject reasons with the other error codes', async () => {
+    const res = await request(app).get('/v1/meta/error-codes');
+    
+    expect(res.statusCode).toBe(200);
+    expect(res.body.errorCodes.map(entry => entry.code)).toEqual(expect.arrayContaining(['MISSING_FIELD', 'DUPLICATE_IN_FILE']));
+  });
+});
+
+describe('GET /ready', () => {
+  it('stays ready when only non-critical invariants are violated', async () => {
+    // Written around the model so the contradicting flag survives
+    await SwiftCode.collection.insertOne({ ...branch, isHeadquarter: true, deletedAt: null });
+    
+    const state = await integrityCheck.run();
+    expect(state.status).toBe('passed');
+    expect(state.violations).toEqual([{ invariant: 'headquarter-suffix', critical: false, count: 1 }]);
+    
+    const res = await request(app).get('/ready');
+    expect(res.statusCode).toBe(200);
+    expect(res.body.status).toBe('ready');
+  });
+});
+
+describe('snapshotPublisher', () => {
+  it('publishes the dataset under a dated prefix with a manifest and checksums', async () => {
+    await SwiftCode.create([headquarter, branch]);
+    const objects = {};
+    const storage = {
+      put: async (key, body) => {
+        const chunks = [];
+        for await (const chunk of Readable.from(body)) {
+          chunks.push(chunk);
+        }
+        objects[key] = Buffer.concat(chunks).toString();
+      }
+    };
+    
+    const result = await snapshotPublisher.run(storage, new Date('2024-03-01T02:00:00Z'));
+    
+    expect(result).toEqual({ version: '2024-03-01', recordCount: 2 });
+    expect(Object.keys(objects)).toEqual([
+      '2024-03-01/swift-codes.ndjson',
+      '2024-03-01/manifest.json',
+      '2024-03-01/SHA256SUMS'
+    ]);
+    expect(objects['2024-03-01/swift-codes.ndjson'].trim().split('\n')).toHaveLength(2);
+    expect(JSON.parse(objects['2024-03-01/manifest.json']).recordCount).toBe(2);
+    expect(objects['2024-03-01/SHA256SUMS']).toMatch(/^[0-9a-f]{64} {2}swift-codes\.ndjson\n[0-9a-f]{64} {2}manifest\.json\n$/);
+  });
+});
\ No newline at end of file